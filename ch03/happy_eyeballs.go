@@ -0,0 +1,261 @@
+package ch03
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// RFC 8305 권장 값
+const (
+	defaultResolutionDelay = 50 * time.Millisecond
+	defaultAttemptDelay    = 250 * time.Millisecond
+)
+
+var ErrNoAddress = errors.New("no addresses to dial")
+
+// net.Resolver가 구현하는 메서드. 테스트에서는 가짜 resolver로 대체 가능.
+type Resolver interface {
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+}
+
+// Happy Eyeballs (RFC 8305)
+// dual-stack 호스트에서 한 주소 체계(IPv4/IPv6)가 blackhole 상태일 때
+// 해당 주소로의 연결 시도가 timeout 될 때까지 기다리지 않도록,
+// 주소 체계를 번갈아 가며 시차를 두고 연결을 시도하고 가장 먼저 성공한 연결을 사용한다.
+//
+// dial fanout 예제와 같이 나머지 연결 시도는 context 취소를 통해 중단하고,
+// 이미 연결된 경우 연결을 종료한다.
+type Dialer struct {
+	Resolver        Resolver      // nil인 경우 net.DefaultResolver
+	Dialer          *net.Dialer   // 각 주소로의 연결에 사용할 dialer. nil인 경우 zero value
+	ResolutionDelay time.Duration // A 응답이 먼저 왔을 때 AAAA 응답을 기다리는 시간
+	AttemptDelay    time.Duration // 다음 주소로 연결 시도를 시작하기까지의 시차
+}
+
+func (d *Dialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+func (d *Dialer) DialContext(
+	ctx context.Context, network, address string,
+) (net.Conn, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, &net.OpError{
+			Op: "dial", Net: network, Err: net.UnknownNetworkError(network),
+		}
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	answers, lookups := d.resolve(ctx, network, host)
+	return d.race(ctx, network, port, answers, lookups)
+}
+
+// 주소 조회 결과
+type answer struct {
+	v6  bool
+	ips []net.IP
+	err error
+}
+
+// IPv6(AAAA), IPv4(A) 주소를 동시에 조회. 응답이 오는 대로 채널로 전달하며 조회 수를 함께 반환
+func (d *Dialer) resolve(
+	ctx context.Context, network, host string,
+) (<-chan answer, int) {
+	// IP 주소 그대로 주어진 경우 조회 없이 사용
+	if ip := net.ParseIP(host); ip != nil {
+		answers := make(chan answer, 1)
+		answers <- answer{v6: ip.To4() == nil, ips: []net.IP{ip}}
+		return answers, 1
+	}
+
+	var resolver Resolver = net.DefaultResolver
+	if d.Resolver != nil {
+		resolver = d.Resolver
+	}
+
+	families := []string{"ip6", "ip4"}
+	switch network {
+	case "tcp4":
+		families = []string{"ip4"}
+	case "tcp6":
+		families = []string{"ip6"}
+	}
+
+	answers := make(chan answer, len(families))
+	for _, family := range families {
+		go func(family string) {
+			ips, err := resolver.LookupIP(ctx, family, host)
+			answers <- answer{v6: family == "ip6", ips: ips, err: err}
+		}(family)
+	}
+
+	return answers, len(families)
+}
+
+// 두 주소 체계를 번갈아가며 하나의 목록으로 합침
+func interleave[T any](first, second []T) []T {
+	ips := make([]T, 0, len(first)+len(second))
+	for i := 0; i < len(first) || i < len(second); i++ {
+		if i < len(first) {
+			ips = append(ips, first[i])
+		}
+		if i < len(second) {
+			ips = append(ips, second[i])
+		}
+	}
+	return ips
+}
+
+func (d *Dialer) resolutionDelay() time.Duration {
+	if d.ResolutionDelay <= 0 {
+		return defaultResolutionDelay
+	}
+	return d.ResolutionDelay
+}
+
+func (d *Dialer) attemptDelay() time.Duration {
+	if d.AttemptDelay <= 0 {
+		return defaultAttemptDelay
+	}
+	return d.AttemptDelay
+}
+
+// 조회된 주소로 AttemptDelay 간격을 두고 연결을 시도하여 가장 먼저 성공한 연결을 반환.
+// 이전 시도가 실패하면 시차를 기다리지 않고 바로 다음 주소로 연결을 시도한다.
+//
+// RFC 8305와 같이 AAAA 응답이 오면 A 응답을 기다리지 않고 바로 연결을 시작하고,
+// A 응답이 먼저 오면 ResolutionDelay 동안만 AAAA 응답을 기다린다.
+// 늦게 도착한 주소는 아직 시도하지 않은 주소와 번갈아 가며 시도한다.
+func (d *Dialer) race(
+	ctx context.Context, network, port string, answers <-chan answer, lookups int,
+) (net.Conn, error) {
+	dialer := d.Dialer
+	if dialer == nil {
+		dialer = new(net.Dialer)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+
+	results := make(chan result)
+
+	var (
+		addrs      []string // 아직 연결을 시도하지 않은 주소
+		ready      bool     // 연결 시도를 시작할 수 있음
+		resolution <-chan time.Time
+		lastStart  time.Time
+		pending    int
+		firstErr   error
+	)
+
+	start := func() {
+		go func(addr string) {
+			c, err := dialer.DialContext(ctx, network, addr)
+			results <- result{conn: c, err: err}
+		}(addrs[0])
+		addrs = addrs[1:]
+		pending++
+		lastStart = time.Now()
+	}
+
+	// 승리한 연결 이외에 뒤늦게 성공한 연결 종료
+	closeLosers := func(n int) {
+		go func() {
+			for i := 0; i < n; i++ {
+				if r := <-results; r.conn != nil {
+					_ = r.conn.Close()
+				}
+			}
+		}()
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	for lookups > 0 || pending > 0 || (ready && len(addrs) > 0) {
+		var delay <-chan time.Time
+		if ready && len(addrs) > 0 {
+			// 진행 중인 시도가 없거나 시차가 지났으면 바로 다음 주소로 시도
+			wait := d.attemptDelay() - time.Since(lastStart)
+			if pending == 0 || wait <= 0 {
+				start()
+				continue
+			}
+			timer.Reset(wait)
+			delay = timer.C
+		}
+
+		select {
+		case <-delay:
+			continue
+		case a := <-answers:
+			lookups--
+			if a.err != nil {
+				if firstErr == nil {
+					firstErr = a.err
+				}
+				break
+			}
+
+			// 지금까지 시도한 주소와 다른 주소 체계가 다음 차례가 되도록 앞에 배치
+			ips := make([]string, 0, len(a.ips))
+			for _, ip := range a.ips {
+				ips = append(ips, net.JoinHostPort(ip.String(), port))
+			}
+			addrs = interleave(ips, addrs)
+
+			switch {
+			case ready:
+			case a.v6 || lookups == 0:
+				ready = true
+			case len(a.ips) > 0:
+				// A 응답이 먼저 온 경우 ResolutionDelay 동안 AAAA 응답을 기다림
+				t := time.NewTimer(d.resolutionDelay())
+				defer t.Stop()
+				resolution = t.C
+			}
+		case <-resolution:
+			// AAAA 응답이 없으면 A 주소로 먼저 시작하고, AAAA 주소는 도착하면 추가
+			resolution = nil
+			ready = true
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				cancel() // 나머지 연결 시도 중단
+				closeLosers(pending)
+				return r.conn, nil
+			}
+
+			if firstErr == nil {
+				firstErr = r.err
+			}
+		case <-ctx.Done():
+			closeLosers(pending)
+			return nil, ctx.Err()
+		}
+
+		// select에서 timer가 만료되지 않았으므로 다음 Reset 전 정지
+		if delay != nil && !timer.Stop() {
+			<-timer.C
+		}
+	}
+
+	if firstErr == nil {
+		firstErr = ErrNoAddress
+	}
+	return nil, firstErr
+}
@@ -0,0 +1,214 @@
+package ch03
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// 주소 체계별로 고정된 응답을 반환하는 가짜 resolver
+type fakeResolver struct {
+	v4, v6         []net.IP
+	delay4, delay6 time.Duration // A, AAAA 응답 지연
+}
+
+func (r fakeResolver) LookupIP(
+	ctx context.Context, network, _ string,
+) ([]net.IP, error) {
+	ips, delay := r.v6, r.delay6
+	if network == "ip4" {
+		ips, delay = r.v4, r.delay4
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(delay):
+	}
+	return ips, nil
+}
+
+func TestHappyEyeballsFallback(t *testing.T) {
+	// IPv4 loopback에서만 listen -> IPv6 연결은 거절됨
+	listener, err := net.Listen("tcp4", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	d := &Dialer{
+		Resolver: fakeResolver{
+			v4: []net.IP{net.ParseIP("127.0.0.1")},
+			v6: []net.IP{net.ParseIP("::1")},
+		},
+		AttemptDelay: time.Second,
+	}
+
+	// IPv6 연결 시도가 바로 실패하므로 AttemptDelay를 기다리지 않고 IPv4 연결 시도
+	begin := time.Now()
+	conn, err := d.Dial("tcp", net.JoinHostPort("example.test", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if elapsed := time.Since(begin); elapsed >= time.Second {
+		t.Errorf("expected immediate fallback; actual: %s", elapsed)
+	}
+
+	if ip := conn.RemoteAddr().(*net.TCPAddr).IP; ip.To4() == nil {
+		t.Errorf("expected IPv4 connection; actual: %s", ip)
+	}
+}
+
+func TestHappyEyeballsBlackhole(t *testing.T) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	blackholed := make(chan struct{}, 1)
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	d := &Dialer{
+		Resolver: fakeResolver{
+			v4: []net.IP{net.ParseIP("127.0.0.1")},
+			v6: []net.IP{net.ParseIP("::1")},
+		},
+		Dialer: &net.Dialer{
+			// IPv6 연결 시도가 응답 없이 멈춰 있는 상황을 mocking
+			Control: func(network, _ string, _ syscall.RawConn) error {
+				if network == "tcp6" {
+					blackholed <- struct{}{}
+					time.Sleep(time.Second)
+					return errors.New("blackholed")
+				}
+				return nil
+			},
+		},
+		AttemptDelay: 100 * time.Millisecond,
+	}
+
+	begin := time.Now()
+	conn, err := d.Dial("tcp", net.JoinHostPort("example.test", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	elapsed := time.Since(begin)
+	t.Logf("connected to %s in %s", conn.RemoteAddr(), elapsed)
+
+	select {
+	case <-blackholed:
+	default:
+		t.Error("expected IPv6 attempt first")
+	}
+
+	if elapsed < 100*time.Millisecond || elapsed >= time.Second {
+		t.Errorf("expected connection after attempt delay; actual: %s", elapsed)
+	}
+}
+
+// 모든 연결 시도를 실패시키고 시도한 주소와 시작 시각을 순서대로 반환
+func dialAttempts(t *testing.T, d *Dialer) ([]string, []time.Duration) {
+	t.Helper()
+
+	var (
+		mu    sync.Mutex
+		addrs []string
+		times []time.Duration
+	)
+	begin := time.Now()
+	d.Dialer = &net.Dialer{
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, _ := net.SplitHostPort(address)
+			mu.Lock()
+			addrs = append(addrs, host)
+			times = append(times, time.Since(begin))
+			mu.Unlock()
+			return errors.New("refused")
+		},
+	}
+
+	if _, err := d.Dial("tcp", "example.test:80"); err == nil {
+		t.Fatal("expected dial error")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return addrs, times
+}
+
+func TestHappyEyeballsResolutionDelay(t *testing.T) {
+	r := fakeResolver{
+		v4:     []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("127.0.0.2")},
+		v6:     []net.IP{net.ParseIP("::1")},
+		delay6: 10 * time.Millisecond,
+	}
+
+	// AAAA 응답이 ResolutionDelay 안에 도착하면 IPv6 주소를 먼저 시도
+	addrs, _ := dialAttempts(t, &Dialer{Resolver: r, ResolutionDelay: time.Second})
+	expected := []string{"::1", "127.0.0.1", "127.0.0.2"}
+	if !slices.Equal(expected, addrs) {
+		t.Errorf("expected %v; actual %v", expected, addrs)
+	}
+
+	// AAAA 응답이 늦으면 A 주소로 먼저 시작하고, AAAA 주소는 도착한 후 시도
+	r.delay6 = 200 * time.Millisecond
+	addrs, times := dialAttempts(t, &Dialer{Resolver: r, ResolutionDelay: 10 * time.Millisecond})
+	expected = []string{"127.0.0.1", "127.0.0.2", "::1"}
+	if !slices.Equal(expected, addrs) {
+		t.Fatalf("expected %v; actual %v", expected, addrs)
+	}
+	if times[0] >= r.delay6 {
+		t.Errorf("expected IPv4 attempt before AAAA answer; actual %s", times[0])
+	}
+}
+
+// A 응답이 늦어도 AAAA 응답이 오면 바로 IPv6 연결을 시작
+func TestHappyEyeballsSlowA(t *testing.T) {
+	r := fakeResolver{
+		v4:     []net.IP{net.ParseIP("127.0.0.1")},
+		v6:     []net.IP{net.ParseIP("::1")},
+		delay4: 200 * time.Millisecond,
+	}
+
+	addrs, times := dialAttempts(t, &Dialer{Resolver: r})
+	expected := []string{"::1", "127.0.0.1"}
+	if !slices.Equal(expected, addrs) {
+		t.Fatalf("expected %v; actual %v", expected, addrs)
+	}
+	if times[0] >= r.delay4 {
+		t.Errorf("expected IPv6 attempt before A answer; actual %s", times[0])
+	}
+	if times[1] < r.delay4 {
+		t.Errorf("expected IPv4 attempt after A answer; actual %s", times[1])
+	}
+}
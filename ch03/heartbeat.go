@@ -0,0 +1,280 @@
+package ch03
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPongTimeout = 5 * time.Second
+	defaultMaxMisses   = 3
+
+	heartbeatFrameSize = 8 // 4byte 메시지("ping"/"pong") + 4byte sequence 번호
+)
+
+var (
+	pingMsg = [4]byte{'p', 'i', 'n', 'g'}
+	pongMsg = [4]byte{'p', 'o', 'n', 'g'}
+
+	ErrPeerDead     = errors.New("peer is dead: too many missed pongs")
+	ErrInvalidFrame = errors.New("invalid heartbeat frame")
+	ErrPingWrite    = errors.New("failed to write ping")
+)
+
+// Pinger는 ping을 보내기만 하고 응답은 확인하지 않는다.
+// Heartbeat는 ping을 보낸 뒤 pong을 기다려 왕복 시간(RTT)을 측정하고,
+// 연속으로 pong을 받지 못한 횟수가 MaxMisses에 도달하면 원격지를 죽은 것으로 판단한다.
+//
+// pong을 받을 때마다 연결의 데드라인을 뒤로 연장하여
+// 애플리케이션 계층에서 긴 유휴 시간을 가지는 연결을 유지한다.
+type Heartbeat struct {
+	Interval  time.Duration // ping 간격 (기본 30초)
+	Timeout   time.Duration // pong 대기 시간 (기본 5초)
+	MaxMisses int           // 원격지를 죽은 것으로 판단할 연속 miss 횟수 (기본 3)
+
+	OnRTT  func(rtt time.Duration) // pong을 받을 때마다 호출
+	OnDead func(err error)         // 원격지가 죽었다고 판단될 때 한 번 호출
+
+	mu      sync.Mutex
+	seq     uint32
+	misses  int
+	rtt     time.Duration
+	pending []byte // 타임아웃으로 인해 일부만 읽힌 frame. mu로 보호
+}
+
+func (h *Heartbeat) interval() time.Duration {
+	if h.Interval <= 0 {
+		return defaultPingInterval
+	}
+	return h.Interval
+}
+
+func (h *Heartbeat) timeout() time.Duration {
+	if h.Timeout <= 0 {
+		return defaultPongTimeout
+	}
+	return h.Timeout
+}
+
+func (h *Heartbeat) maxMisses() int {
+	if h.MaxMisses <= 0 {
+		return defaultMaxMisses
+	}
+	return h.MaxMisses
+}
+
+// 연속으로 pong을 받지 못한 횟수
+func (h *Heartbeat) Misses() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.misses
+}
+
+// 마지막으로 측정한 왕복 시간
+func (h *Heartbeat) RTT() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.rtt
+}
+
+// Run은 context가 취소되거나 원격지가 죽었다고 판단될 때까지
+// Interval마다 Probe를 반복한다.
+// 원격지가 죽은 경우 OnDead를 호출하고 ErrPeerDead를 반환한다.
+func (h *Heartbeat) Run(ctx context.Context, conn net.Conn) error {
+	// context가 취소되면 대기 중인 read를 즉시 깨움
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	timer := time.NewTimer(h.interval())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		_, err := h.Probe(conn)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			// ping을 보내지 못했거나 timeout이 아닌 에러 (연결 종료 등)는 바로 원격지가 죽은 것으로 처리
+			if nErr, ok := err.(net.Error); !ok || !nErr.Timeout() {
+				h.dead(err)
+				return err
+			}
+
+			if h.Misses() >= h.maxMisses() {
+				h.dead(ErrPeerDead)
+				return ErrPeerDead
+			}
+		}
+
+		timer.Reset(h.interval())
+	}
+}
+
+func (h *Heartbeat) dead(err error) {
+	if h.OnDead != nil {
+		h.OnDead(err)
+	}
+}
+
+// Probe는 ping을 한 번 보내고 Timeout 동안 그에 대한 pong을 기다린다.
+// pong을 받으면 miss 횟수를 초기화하고 연결의 데드라인을 연장한 뒤 RTT를 반환한다.
+// 시간 내에 pong을 받지 못하면 miss 횟수를 증가시키고 timeout 에러를 반환한다.
+// ping을 보내지 못한 경우 frame의 일부만 전송되었을 수 있어 이후 frame을 구분할 수 없으므로,
+// 연결을 닫고 ErrPingWrite를 반환한다.
+func (h *Heartbeat) Probe(conn net.Conn) (time.Duration, error) {
+	h.mu.Lock()
+	h.seq++
+	seq := h.seq
+	h.mu.Unlock()
+
+	timeout := h.timeout()
+	start := time.Now()
+
+	err := conn.SetWriteDeadline(start.Add(timeout))
+	if err != nil {
+		return 0, err
+	}
+	err = writeHeartbeatFrame(conn, pingMsg, seq)
+	if err != nil {
+		_ = conn.Close()
+		return 0, fmt.Errorf("%w: %w", ErrPingWrite, err)
+	}
+
+	err = conn.SetReadDeadline(start.Add(timeout))
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		msg, pongSeq, err := h.readFrame(conn)
+		if err != nil {
+			return 0, h.miss(err)
+		}
+		// 이전에 타임아웃 처리된 ping에 대한 늦은 pong은 무시
+		if msg != pongMsg || pongSeq != seq {
+			continue
+		}
+		break
+	}
+
+	rtt := time.Since(start)
+
+	h.mu.Lock()
+	h.misses = 0
+	h.rtt = rtt
+	h.mu.Unlock()
+
+	// pong을 받았으므로 원격지는 살아있음 -> 데드라인 연장
+	err = conn.SetDeadline(time.Now().Add(h.interval() + timeout))
+	if err != nil {
+		return rtt, err
+	}
+
+	if h.OnRTT != nil {
+		h.OnRTT(rtt)
+	}
+
+	return rtt, nil
+}
+
+func (h *Heartbeat) miss(err error) error {
+	if nErr, ok := err.(net.Error); ok && nErr.Timeout() {
+		h.mu.Lock()
+		h.misses++
+		h.mu.Unlock()
+	}
+	return err
+}
+
+// 타임아웃으로 frame이 중간에 끊겨도 다음 read에서 이어 읽을 수 있도록
+// 읽은 byte를 pending에 보관한다.
+// read 동안 mu를 잡고 있으면 Misses, RTT가 Timeout만큼 막히므로 pending을 꺼내서 읽고 되돌린다.
+func (h *Heartbeat) readFrame(conn net.Conn) ([4]byte, uint32, error) {
+	var msg [4]byte
+
+	h.mu.Lock()
+	frame := h.pending
+	h.pending = nil
+	h.mu.Unlock()
+
+	buf := make([]byte, heartbeatFrameSize)
+	for len(frame) < heartbeatFrameSize {
+		n, err := conn.Read(buf[:heartbeatFrameSize-len(frame)])
+		frame = append(frame, buf[:n]...)
+		if err != nil {
+			h.mu.Lock()
+			h.pending = frame
+			h.mu.Unlock()
+			return msg, 0, err
+		}
+	}
+
+	copy(msg[:], frame[:4])
+	seq := binary.BigEndian.Uint32(frame[4:heartbeatFrameSize])
+
+	return msg, seq, nil
+}
+
+func writeHeartbeatFrame(conn net.Conn, msg [4]byte, seq uint32) error {
+	var frame [heartbeatFrameSize]byte
+	copy(frame[:4], msg[:])
+	binary.BigEndian.PutUint32(frame[4:], seq)
+
+	_, err := conn.Write(frame[:])
+	return err
+}
+
+// Ponger는 Heartbeat의 상대편으로, context가 취소되거나 연결이 끊길 때까지
+// 받은 ping마다 같은 sequence 번호로 pong을 응답한다.
+func Ponger(ctx context.Context, conn net.Conn) error {
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	frame := make([]byte, heartbeatFrameSize)
+	for {
+		_, err := io.ReadFull(conn, frame)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return err
+		}
+
+		var msg [4]byte
+		copy(msg[:], frame[:4])
+		if msg != pingMsg {
+			return ErrInvalidFrame
+		}
+
+		err = writeHeartbeatFrame(conn, pongMsg, binary.BigEndian.Uint32(frame[4:]))
+		if err != nil {
+			return err
+		}
+	}
+}
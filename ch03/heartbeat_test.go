@@ -0,0 +1,149 @@
+package ch03
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatPong(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 서버는 ping을 받을 때마다 pong으로 응답
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = Ponger(ctx, conn)
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	rtts := make(chan time.Duration, 10)
+	hb := &Heartbeat{
+		Interval:  50 * time.Millisecond,
+		Timeout:   time.Second,
+		MaxMisses: 2,
+		OnRTT:     func(rtt time.Duration) { rtts <- rtt },
+		OnDead:    func(err error) { t.Errorf("unexpected dead peer: %v", err) },
+	}
+
+	done := make(chan error)
+	go func() { done <- hb.Run(ctx, conn) }()
+
+	// 3번의 왕복 동안 RTT가 측정되어야 함
+	for i := 0; i < 3; i++ {
+		select {
+		case rtt := <-rtts:
+			t.Logf("rtt: %s", rtt)
+			if rtt <= 0 {
+				t.Errorf("expected positive rtt; actual: %s", rtt)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for pong")
+		}
+	}
+
+	if m := hb.Misses(); m != 0 {
+		t.Errorf("expected 0 misses; actual: %d", m)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected context canceled; actual: %v", err)
+	}
+}
+
+func TestHeartbeatDeadPeer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// 서버는 ping을 읽기만 하고 pong으로 응답하지 않음
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(io.Discard, conn)
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	dead := make(chan error, 1)
+	hb := &Heartbeat{
+		Interval:  10 * time.Millisecond,
+		Timeout:   50 * time.Millisecond,
+		MaxMisses: 3,
+		OnDead:    func(err error) { dead <- err },
+	}
+
+	begin := time.Now()
+	err = hb.Run(context.Background(), conn)
+	if err != ErrPeerDead {
+		t.Fatalf("expected ErrPeerDead; actual: %v", err)
+	}
+	t.Logf("peer declared dead after %s", time.Since(begin))
+
+	if err := <-dead; err != ErrPeerDead {
+		t.Errorf("expected OnDead with ErrPeerDead; actual: %v", err)
+	}
+
+	if m := hb.Misses(); m != 3 {
+		t.Errorf("expected 3 misses; actual: %d", m)
+	}
+}
+
+// ping을 보내지 못하면 frame이 일부만 전송되었을 수 있으므로 miss가 아닌 죽은 원격지로 처리
+func TestHeartbeatWriteTimeout(t *testing.T) {
+	// 원격지가 읽지 않아 ping 전송이 timeout됨
+	conn, peer := net.Pipe()
+	defer peer.Close()
+
+	dead := make(chan error, 1)
+	hb := &Heartbeat{
+		Interval:  10 * time.Millisecond,
+		Timeout:   50 * time.Millisecond,
+		MaxMisses: 3,
+		OnDead:    func(err error) { dead <- err },
+	}
+
+	err := hb.Run(context.Background(), conn)
+	if !errors.Is(err, ErrPingWrite) {
+		t.Fatalf("expected ErrPingWrite; actual: %v", err)
+	}
+	if err := <-dead; !errors.Is(err, ErrPingWrite) {
+		t.Errorf("expected OnDead with ErrPingWrite; actual: %v", err)
+	}
+	if m := hb.Misses(); m != 0 {
+		t.Errorf("expected 0 misses; actual: %d", m)
+	}
+
+	// 연결이 닫혀 이후 frame을 보낼 수 없음
+	if _, err := conn.Write([]byte("ping")); err != io.ErrClosedPipe {
+		t.Errorf("expected closed conn; actual: %v", err)
+	}
+}
@@ -48,7 +48,7 @@ func Pinger(ctx context.Context, w io.Writer, reset <-chan time.Duration) {
 			}
 		case <-timer.C: // timer 만료 -> ping을 보냄
 			if _, err := w.Write([]byte("ping")); err != nil {
-				// 여기서 연속으로 발생하는 타임아웃을 추적하고 처리.
+				// 연속으로 발생하는 타임아웃의 추적은 Heartbeat 참고
 				return
 			}
 		}
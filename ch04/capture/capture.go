@@ -0,0 +1,239 @@
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/huGgW/network-study-with-go/ch04"
+)
+
+// 데이터가 흐른 방향
+type Direction uint8
+
+const (
+	ClientToServer Direction = iota
+	ServerToClient
+)
+
+func (d Direction) String() string {
+	switch d {
+	case ClientToServer:
+		return "client->server"
+	case ServerToClient:
+		return "server->client"
+	}
+	return fmt.Sprintf("Direction(%d)", uint8(d))
+}
+
+const version = 1
+
+var ErrInvalidCapture = errors.New("invalid capture")
+
+// 캡처한 연결의 정보
+type Header struct {
+	Client string
+	Server string
+	Start  time.Time
+}
+
+// 한 방향으로 흐른 데이터 혹은 half-close(EOF)
+type Event struct {
+	Offset time.Duration // 연결 시작으로부터의 시간
+	Dir    Direction
+	Data   []byte
+	EOF    bool
+}
+
+// 캡처 파일 전체
+type Capture struct {
+	Header
+	Events []Event
+}
+
+// 캡처 파일은 ch04 TLV Map frame의 연속이다. 첫 frame은 헤더이고 나머지는 Event이다.
+//
+//	{"version": Uvarint, "client": String, "server": String, "start": Timestamp}
+//	{"offset": Uvarint(ns), "dir": Uvarint, "data": Binary} 또는 {..., "eof": Bool}
+//
+// 따라서 tlvdump로도 내용을 확인할 수 있다.
+
+// 캡처 파일을 기록. 여러 고루틴에서 동시에 사용 가능
+type Writer struct {
+	mu    sync.Mutex
+	w     io.Writer
+	start time.Time
+	err   error
+}
+
+func NewWriter(w io.Writer, h Header) (*Writer, error) {
+	if h.Start.IsZero() {
+		h.Start = time.Now()
+	}
+
+	header := ch04.Map{
+		"version": ptr(ch04.Uvarint(version)),
+		"client":  ptr(ch04.String(h.Client)),
+		"server":  ptr(ch04.String(h.Server)),
+		"start":   ptr(ch04.Timestamp(h.Start)),
+	}
+	_, err := header.WriteTo(w)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Writer{w: w, start: h.Start}, nil
+}
+
+// 현재 시각으로 dir 방향의 데이터 기록
+func (w *Writer) Write(dir Direction, data []byte) error {
+	return w.record(dir, ptr(ch04.Binary(data)), "data")
+}
+
+// 현재 시각으로 dir 방향의 half-close 기록
+func (w *Writer) WriteEOF(dir Direction) error {
+	return w.record(dir, ptr(ch04.Bool(true)), "eof")
+}
+
+func (w *Writer) record(dir Direction, v ch04.Payload, key string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err != nil {
+		return w.err
+	}
+
+	event := ch04.Map{
+		"offset": ptr(ch04.Uvarint(time.Since(w.start))),
+		"dir":    ptr(ch04.Uvarint(dir)),
+		key:      v,
+	}
+	_, w.err = event.WriteTo(w.w)
+
+	return w.err
+}
+
+// 기록 중 처음 발생한 에러
+func (w *Writer) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// 캡처 파일을 순서대로 읽음
+type Reader struct {
+	Header
+	dec *ch04.Decoder
+}
+
+func NewReader(r io.Reader) (*Reader, error) {
+	dec := ch04.NewDecoder(r)
+
+	m, err := next(dec)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := m["version"].(*ch04.Uvarint); !ok || *v != version {
+		return nil, fmt.Errorf("%w: unsupported version", ErrInvalidCapture)
+	}
+
+	var h Header
+	client, ok1 := m["client"].(*ch04.String)
+	server, ok2 := m["server"].(*ch04.String)
+	start, ok3 := m["start"].(*ch04.Timestamp)
+	if !ok1 || !ok2 || !ok3 {
+		return nil, fmt.Errorf("%w: invalid header", ErrInvalidCapture)
+	}
+	h.Client, h.Server, h.Start = string(*client), string(*server), start.Time()
+
+	return &Reader{Header: h, dec: dec}, nil
+}
+
+// 다음 Event 반환. 끝에 도달하면 io.EOF
+func (r *Reader) Next() (Event, error) {
+	m, err := next(r.dec)
+	if err != nil {
+		return Event{}, err
+	}
+
+	offset, ok1 := m["offset"].(*ch04.Uvarint)
+	dir, ok2 := m["dir"].(*ch04.Uvarint)
+	if !ok1 || !ok2 || Direction(*dir) > ServerToClient {
+		return Event{}, fmt.Errorf("%w: invalid event", ErrInvalidCapture)
+	}
+	e := Event{Offset: time.Duration(*offset), Dir: Direction(*dir)}
+
+	switch v := m["data"].(type) {
+	case *ch04.Binary:
+		e.Data = *v
+	case nil:
+		eof, ok := m["eof"].(*ch04.Bool)
+		if !ok || !bool(*eof) {
+			return Event{}, fmt.Errorf("%w: event without data", ErrInvalidCapture)
+		}
+		e.EOF = true
+	default:
+		return Event{}, fmt.Errorf("%w: invalid data type %T", ErrInvalidCapture, v)
+	}
+
+	return e, nil
+}
+
+func next(dec *ch04.Decoder) (ch04.Map, error) {
+	p, err := dec.Decode()
+	if err != nil {
+		return nil, err
+	}
+	m, ok := p.(*ch04.Map)
+	if !ok {
+		return nil, fmt.Errorf("%w: unexpected %T frame", ErrInvalidCapture, p)
+	}
+	return *m, nil
+}
+
+// r의 캡처 전체를 읽음
+func Read(r io.Reader) (*Capture, error) {
+	cr, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Capture{Header: cr.Header}
+	for {
+		e, err := cr.Next()
+		if err == io.EOF {
+			return c, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.Events = append(c.Events, e)
+	}
+}
+
+// 캡처 파일을 읽음
+func Load(path string) (*Capture, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Read(f)
+}
+
+// dir 방향으로 흐른 데이터 전체
+func (c *Capture) Data(dir Direction) []byte {
+	var b []byte
+	for _, e := range c.Events {
+		if e.Dir == dir {
+			b = append(b, e.Data...)
+		}
+	}
+	return b
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,236 @@
+package capture
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// 받은 데이터를 transform하여 돌려주고, 상대가 닫으면 연결을 닫는 서버
+func transformServer(t *testing.T, transform func([]byte) []byte) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 1024)
+				for {
+					n, err := conn.Read(buf)
+					if err != nil {
+						return
+					}
+					_, _ = conn.Write(transform(buf[:n]))
+				}
+			}()
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+// 요청마다 응답을 받은 뒤 다음 요청을 보내고, 마지막에 write 방향을 닫는 client
+func converse(t *testing.T, conn net.Conn, gap time.Duration, reqs ...string) string {
+	t.Helper()
+
+	var resp []byte
+	buf := make([]byte, 1024)
+	for _, req := range reqs {
+		time.Sleep(gap)
+		_, err := conn.Write([]byte(req))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := io.ReadAtLeast(conn, buf, len(req))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp = append(resp, buf[:n]...)
+	}
+	_ = conn.(*net.TCPConn).CloseWrite()
+
+	rest, _ := io.ReadAll(conn)
+	return string(append(resp, rest...))
+}
+
+// client <-> Recorder <-> server 한 번의 대화를 캡처
+func record(t *testing.T, server string, gap time.Duration, reqs ...string) *Capture {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	rec := &Recorder{Dir: t.TempDir()}
+	path := make(chan string, 1)
+	go func() {
+		client, err := l.Accept()
+		if err != nil {
+			t.Error(err)
+			path <- ""
+			return
+		}
+		defer client.Close()
+		upstream, err := net.Dial("tcp", server)
+		if err != nil {
+			t.Error(err)
+			path <- ""
+			return
+		}
+		defer upstream.Close()
+
+		p, _, err := rec.Proxy(client, upstream)
+		if err != nil {
+			t.Error(err)
+		}
+		path <- p
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	converse(t, conn, gap, reqs...)
+
+	c, err := Load(<-path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestRecord(t *testing.T) {
+	server := transformServer(t, bytes.ToUpper)
+	c := record(t, server, 0, "hello", "bye")
+
+	if c.Server != server {
+		t.Errorf("expected server %s; actual %s", server, c.Server)
+	}
+	if actual := string(c.Data(ClientToServer)); actual != "hellobye" {
+		t.Errorf("expected %q; actual %q", "hellobye", actual)
+	}
+	if actual := string(c.Data(ServerToClient)); actual != "HELLOBYE" {
+		t.Errorf("expected %q; actual %q", "HELLOBYE", actual)
+	}
+
+	// 양쪽의 half-close도 기록되며, 시각은 증가하는 순서
+	eofs := 0
+	for i, e := range c.Events {
+		t.Logf("%10s %s %q eof=%t", e.Offset, e.Dir, e.Data, e.EOF)
+		if e.EOF {
+			eofs++
+		}
+		if i > 0 && e.Offset < c.Events[i-1].Offset {
+			t.Errorf("event %d: offset went backwards", i)
+		}
+	}
+	if eofs != 2 {
+		t.Errorf("expected 2 EOF events; actual %d", eofs)
+	}
+}
+
+func TestReplayClient(t *testing.T) {
+	c := record(t, transformServer(t, bytes.ToUpper), 0, "hello", "bye")
+	r := &Replayer{Scale: 1, Timeout: time.Second}
+
+	replay := func(server string) error {
+		conn, err := net.Dial("tcp", server)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		return r.Client(conn, c)
+	}
+
+	if err := replay(transformServer(t, bytes.ToUpper)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 응답이 달라지면 회귀로 보고
+	err := replay(transformServer(t, bytes.ToLower))
+	if !errors.Is(err, ErrMismatch) {
+		t.Fatalf("expected ErrMismatch; actual: %v", err)
+	}
+	t.Log(err)
+}
+
+func TestReplayServer(t *testing.T) {
+	c := record(t, transformServer(t, bytes.ToUpper), 0, "hello", "bye")
+
+	// 캡처를 재생하는 가짜 서버
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			done <- err
+			return
+		}
+		defer conn.Close()
+		done <- (&Replayer{Scale: 1}).Server(conn, c)
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if resp := converse(t, conn, 0, "hello", "bye"); resp != "HELLOBYE" {
+		t.Errorf("expected %q; actual %q", "HELLOBYE", resp)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplayTiming(t *testing.T) {
+	const gap = 100 * time.Millisecond
+	c := record(t, transformServer(t, bytes.ToUpper), gap, "a", "b", "c")
+	server := transformServer(t, bytes.ToUpper)
+
+	for _, scale := range []float64{1, 0} {
+		conn, err := net.Dial("tcp", server)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		start := time.Now()
+		err = (&Replayer{Scale: scale}).Client(conn, c)
+		elapsed := time.Since(start)
+		_ = conn.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Logf("scale %.1f: %s", scale, elapsed)
+		if scale == 1 && elapsed < 3*gap {
+			t.Errorf("expected at least %s; actual %s", 3*gap, elapsed)
+		}
+		if scale == 0 && elapsed > gap {
+			t.Errorf("expected less than %s; actual %s", gap, elapsed)
+		}
+	}
+}
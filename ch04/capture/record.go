@@ -0,0 +1,85 @@
+package capture
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/huGgW/network-study-with-go/ch04"
+)
+
+// proxy를 거치는 연결마다 캡처 파일을 기록
+//
+// ch04.Monitor와 달리 데이터마다 시각과 방향을 기록하여 Replayer로 재생할 수 있다.
+type Recorder struct {
+	Dir string // 캡처 파일을 저장할 디렉토리
+
+	seq atomic.Uint64
+}
+
+// client와 server 사이의 데이터를 ch04.Proxy로 전달하면서 캡처 파일에 기록하고 파일 경로 반환
+// 기록을 위해 연결을 감싸므로 splice는 사용되지 않는다.
+// 기록에 실패해도 proxy는 계속 동작하며, 기록 에러는 proxy 에러가 없을 때 반환한다.
+func (r *Recorder) Proxy(client, server net.Conn) (string, ch04.ProxyStats, error) {
+	start := time.Now()
+	name := fmt.Sprintf("%s-%d.cap", start.UTC().Format("20060102T150405.000000"), r.seq.Add(1))
+	path := filepath.Join(r.Dir, name)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", ch04.ProxyStats{}, err
+	}
+	defer f.Close()
+
+	buf := bufio.NewWriter(f)
+	w, err := NewWriter(buf, Header{
+		Client: client.RemoteAddr().String(),
+		Server: server.RemoteAddr().String(),
+		Start:  start,
+	})
+	if err != nil {
+		return path, ch04.ProxyStats{}, err
+	}
+
+	stats, err := ch04.Proxy(
+		&recordConn{Conn: client, w: w, dir: ClientToServer},
+		&recordConn{Conn: server, w: w, dir: ServerToClient},
+	)
+	if err == nil {
+		err = w.Err()
+	}
+	if fErr := buf.Flush(); err == nil {
+		err = fErr
+	}
+
+	return path, stats, err
+}
+
+// 읽은 데이터를 dir 방향으로 기록하는 연결
+type recordConn struct {
+	net.Conn
+	w   *Writer
+	dir Direction
+}
+
+func (c *recordConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	// 기록 에러는 Writer.Err로 확인하며, proxy에는 영향을 주지 않음
+	if n > 0 {
+		_ = c.w.Write(c.dir, p[:n])
+	}
+	if err == io.EOF {
+		_ = c.w.WriteEOF(c.dir)
+	}
+	return n, err
+}
+
+// half-close 전파를 위해 원래 연결의 CloseWrite 호출
+func (c *recordConn) CloseWrite() error {
+	return ch04.CloseWrite(c.Conn)
+}
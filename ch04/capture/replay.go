@@ -0,0 +1,188 @@
+package capture
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const defaultReplayTimeout = 5 * time.Second
+
+var (
+	ErrMismatch = errors.New("replayed data mismatch")
+	ErrTimeout  = errors.New("timed out waiting for peer")
+)
+
+// 캡처를 재생
+//
+// 자신의 방향 Event는 캡처한 시각에 맞추어 전송하고, 상대 방향 Event는 받은 데이터와 비교한다.
+// 상대가 보낸 데이터가 캡처보다 늦으면, 캡처에서 그 데이터 이후에 보낸 데이터는 상대의 데이터를
+// 모두 받을 때까지 보내지 않는다. 따라서 요청-응답 순서가 캡처와 같게 유지된다.
+type Replayer struct {
+	// 캡처 시각에 곱할 배율. 1이면 원래 속도, 0.5면 두 배 빠르게, 0이면 지연 없이 재생
+	Scale float64
+
+	// 상대의 데이터를 기다리는 최대 시간 (기본 5초)
+	Timeout time.Duration
+}
+
+// client 역할로 server에 캡처의 client 데이터를 보내고, server 응답을 캡처와 비교
+func (r *Replayer) Client(server net.Conn, c *Capture) error {
+	return r.play(server, c, ClientToServer)
+}
+
+// server 역할로 client에 캡처의 server 데이터를 보내고, client 요청을 캡처와 비교
+func (r *Replayer) Server(client net.Conn, c *Capture) error {
+	return r.play(client, c, ServerToClient)
+}
+
+func (r *Replayer) timeout() time.Duration {
+	if r.Timeout <= 0 {
+		return defaultReplayTimeout
+	}
+	return r.Timeout
+}
+
+func (r *Replayer) play(conn net.Conn, c *Capture, self Direction) error {
+	peer := newPeerReader(conn)
+	start := time.Now()
+
+	var (
+		expected []byte // 지금까지 캡처에서 상대가 보낸 데이터
+		peerEOF  bool
+	)
+	for _, e := range c.Events {
+		if e.Dir != self {
+			expected = append(expected, e.Data...)
+			peerEOF = peerEOF || e.EOF
+			continue
+		}
+
+		// 캡처에서 이 Event 이전에 상대가 보낸 데이터를 먼저 받음
+		err := peer.wait(len(expected), false, r.timeout())
+		if err != nil {
+			return err
+		}
+
+		if d := time.Until(start.Add(time.Duration(float64(e.Offset) * r.Scale))); d > 0 {
+			time.Sleep(d)
+		}
+
+		if e.EOF {
+			err = closeWrite(conn)
+		} else {
+			_, err = conn.Write(e.Data)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// 상대가 연결을 닫은 캡처라면 EOF까지 받아 남는 데이터가 없는지도 확인
+	err := peer.wait(len(expected), peerEOF, r.timeout())
+	if err != nil {
+		return err
+	}
+
+	return compare(expected, peer.bytes(), peerEOF)
+}
+
+func closeWrite(conn net.Conn) error {
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return nil
+}
+
+func compare(expected, actual []byte, exact bool) error {
+	n := min(len(expected), len(actual))
+	for i := 0; i < n; i++ {
+		if expected[i] != actual[i] {
+			return fmt.Errorf("%w at byte %d: expected %q; actual %q",
+				ErrMismatch, i, excerpt(expected, i), excerpt(actual, i))
+		}
+	}
+	if len(actual) < len(expected) || (exact && len(actual) != len(expected)) {
+		return fmt.Errorf("%w: expected %d bytes; actual %d bytes",
+			ErrMismatch, len(expected), len(actual))
+	}
+	return nil
+}
+
+func excerpt(b []byte, i int) []byte {
+	return b[i:min(len(b), i+16)]
+}
+
+// 상대가 보낸 데이터를 백그라운드에서 계속 읽음
+type peerReader struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	err    error // 읽기가 끝난 이유. 정상 종료는 io.EOF
+	notify chan struct{}
+}
+
+func newPeerReader(conn net.Conn) *peerReader {
+	p := &peerReader{notify: make(chan struct{})}
+
+	go func() {
+		b := make([]byte, 32<<10)
+		for {
+			n, err := conn.Read(b)
+
+			p.mu.Lock()
+			p.buf.Write(b[:n])
+			if err != nil {
+				p.err = err
+			}
+			close(p.notify)
+			p.notify = make(chan struct{})
+			p.mu.Unlock()
+
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return p
+}
+
+// n byte 이상을 받을 때까지 대기. eof가 true면 연결이 닫힐 때까지 대기
+func (p *peerReader) wait(n int, eof bool, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		p.mu.Lock()
+		received, err, notify := p.buf.Len(), p.err, p.notify
+		p.mu.Unlock()
+
+		if err != nil && err != io.EOF {
+			return err
+		}
+		done := err == io.EOF
+		if received >= n && (!eof || done) {
+			return nil
+		}
+		if done {
+			// 더 받을 데이터가 없으므로 비교에서 차이를 보고
+			return nil
+		}
+
+		select {
+		case <-notify:
+		case <-timer.C:
+			return fmt.Errorf("%w: received %d of %d bytes", ErrTimeout, received, n)
+		}
+	}
+}
+
+func (p *peerReader) bytes() []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return bytes.Clone(p.buf.Bytes())
+}
@@ -0,0 +1,76 @@
+package chaos
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// 실행 중에 Toxics를 조회, 변경하는 HTTP API
+//
+//	GET    /toxics              양방향 설정 조회
+//	GET    /toxics/{direction}  한 방향 설정 조회 (upstream, downstream)
+//	PUT    /toxics/{direction}  JSON으로 설정 교체
+//	DELETE /toxics/{direction}  설정 제거
+//	GET    /stats               연결 수 조회
+func (p *Proxy) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /toxics", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, map[string]Toxics{
+			Upstream.String():   p.Toxics(Upstream),
+			Downstream.String(): p.Toxics(Downstream),
+		})
+	})
+
+	mux.HandleFunc("GET /toxics/{direction}", func(w http.ResponseWriter, r *http.Request) {
+		d, err := ParseDirection(r.PathValue("direction"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, p.Toxics(d))
+	})
+
+	mux.HandleFunc("PUT /toxics/{direction}", func(w http.ResponseWriter, r *http.Request) {
+		d, err := ParseDirection(r.PathValue("direction"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		var t Toxics
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&t)
+		if err == nil {
+			err = p.SetToxics(d, t)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, t)
+	})
+
+	mux.HandleFunc("DELETE /toxics/{direction}", func(w http.ResponseWriter, r *http.Request) {
+		d, err := ParseDirection(r.PathValue("direction"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		_ = p.SetToxics(d, Toxics{})
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /stats", func(w http.ResponseWriter, _ *http.Request) {
+		active, total := p.Stats()
+		writeJSON(w, map[string]any{"active": active, "total": total})
+	})
+
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
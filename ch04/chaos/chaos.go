@@ -0,0 +1,303 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/huGgW/network-study-with-go/ch04"
+	"github.com/huGgW/network-study-with-go/ch04/sockopt"
+)
+
+var ErrCut = errors.New("connection cut by toxic")
+
+// client와 upstream 사이에서 방향별 장애(Toxics)를 주입하는 TCP proxy
+//
+// 받은 연결마다 upstream에 연결한 뒤 ch04.Proxy로 양방향 데이터를 전달하며,
+// 각 방향의 write에 현재 설정된 Toxics를 적용한다.
+// 타임아웃, 재시도 처리를 로컬에서 재현 가능하게 테스트하기 위한 용도이다.
+type Proxy struct {
+	Upstream    string
+	DialTimeout time.Duration // upstream 연결 timeout (기본 3초)
+	ErrorLog    *log.Logger   // nil이면 log 패키지의 기본 logger
+
+	SocketOptions sockopt.Options // ListenAndServe의 listen 소켓 옵션
+
+	mu      sync.Mutex
+	toxics  [2]Toxics
+	changed chan struct{} // 설정이 바뀔 때마다 닫고 새로 생성
+
+	active atomic.Int64
+	total  atomic.Uint64
+}
+
+func New(upstream string) *Proxy {
+	return &Proxy{Upstream: upstream, changed: make(chan struct{})}
+}
+
+// 현재 설정과 설정 변경시 닫히는 채널 반환
+func (p *Proxy) current(d Direction) (Toxics, <-chan struct{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.changed == nil {
+		p.changed = make(chan struct{})
+	}
+	return p.toxics[d], p.changed
+}
+
+func (p *Proxy) Toxics(d Direction) Toxics {
+	t, _ := p.current(d)
+	return t
+}
+
+// d 방향의 설정을 교체. 이미 연결된 연결에도 바로 적용됨
+func (p *Proxy) SetToxics(d Direction, t Toxics) error {
+	if d != Upstream && d != Downstream {
+		return fmt.Errorf("invalid direction %d", d)
+	}
+	err := t.Validate()
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.toxics[d] = t
+	// Stall 중인 연결이 새 설정을 확인하도록 깨움
+	if p.changed != nil {
+		close(p.changed)
+	}
+	p.changed = make(chan struct{})
+
+	return nil
+}
+
+// 현재 전달 중인 연결 수와 지금까지 받은 연결 수
+func (p *Proxy) Stats() (active int64, total uint64) {
+	return p.active.Load(), p.total.Load()
+}
+
+func (p *Proxy) ListenAndServe(ctx context.Context, addr string) error {
+	l, err := p.SocketOptions.Listen(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("binding to tcp %s: %w", addr, err)
+	}
+
+	return p.Serve(ctx, l)
+}
+
+// l에서 연결을 받아 Upstream으로 전달. ctx가 취소되면 모든 연결을 닫고 nil 반환
+func (p *Proxy) Serve(ctx context.Context, l net.Listener) error {
+	sctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		<-sctx.Done()
+		_ = l.Close()
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.handle(sctx, conn)
+		}()
+	}
+}
+
+func (p *Proxy) handle(ctx context.Context, client net.Conn) {
+	defer func() { _ = client.Close() }()
+
+	timeout := p.DialTimeout
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+	server, err := net.DialTimeout("tcp", p.Upstream, timeout)
+	if err != nil {
+		p.logf("%s: %v", client.RemoteAddr(), err)
+		return
+	}
+	defer func() { _ = server.Close() }()
+
+	p.active.Add(1)
+	p.total.Add(1)
+	defer p.active.Add(-1)
+
+	l := &link{proxy: p, client: client, server: server, closed: make(chan struct{})}
+
+	// 종료시 지연 중인 write도 바로 끝나도록 연결을 닫음
+	stop := context.AfterFunc(ctx, l.cut)
+	defer stop()
+
+	// 감싼 연결은 splice를 사용할 수 없지만, write마다 장애를 주입하기 위해 필요
+	_, err = ch04.Proxy(
+		&toxicConn{Conn: client, w: &toxicWriter{link: l, dir: Downstream, dst: client}},
+		&toxicConn{Conn: server, w: &toxicWriter{link: l, dir: Upstream, dst: server}},
+	)
+	if err != nil && !errors.Is(err, ErrCut) {
+		p.logf("%s <-> %s: %v", client.RemoteAddr(), p.Upstream, err)
+	}
+}
+
+func (p *Proxy) logf(format string, v ...any) {
+	if p.ErrorLog != nil {
+		p.ErrorLog.Printf(format, v...)
+		return
+	}
+	log.Printf(format, v...)
+}
+
+// proxy 중인 연결 한 쌍
+type link struct {
+	proxy          *Proxy
+	client, server net.Conn
+	closed         chan struct{} // 연결을 끊으면 닫힘
+	once           sync.Once
+	reset          atomic.Bool
+}
+
+// 양쪽 연결을 끊고 대기 중인 write를 깨움. reset이 설정되어 있으면 RST 전송
+func (l *link) cut() {
+	l.once.Do(func() {
+		close(l.closed)
+		for _, c := range []net.Conn{l.client, l.server} {
+			if tcp, ok := c.(*net.TCPConn); ok && l.reset.Load() {
+				_ = tcp.SetLinger(0)
+			}
+			_ = c.Close()
+		}
+	})
+}
+
+// d만큼 대기. 연결이 끝나면 false 반환
+func (l *link) sleep(d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-l.closed:
+		return false
+	}
+}
+
+type toxicConn struct {
+	net.Conn
+	w *toxicWriter
+}
+
+func (c *toxicConn) Write(p []byte) (int, error) { return c.w.Write(p) }
+
+// ch04.Proxy가 에러로 연결을 닫을 때 Stall, 지연 중인 반대 방향도 끝나도록 함
+func (c *toxicConn) Close() error {
+	c.w.cut()
+	return nil
+}
+
+// half-close 전파를 위해 원래 연결의 CloseWrite 호출
+func (c *toxicConn) CloseWrite() error {
+	return ch04.CloseWrite(c.Conn)
+}
+
+// 한 방향의 write에 장애를 주입
+type toxicWriter struct {
+	*link
+	dir     Direction
+	dst     net.Conn
+	written int64
+}
+
+func (w *toxicWriter) Write(b []byte) (int, error) {
+	total := 0
+	first := true
+
+	for len(b) > 0 {
+		t, changed := w.proxy.current(w.dir)
+
+		if t.Stall {
+			select {
+			case <-changed:
+				continue
+			case <-w.closed:
+				return total, net.ErrClosed
+			}
+		}
+
+		var delay time.Duration
+		if first {
+			delay = jitter(time.Duration(t.Latency), time.Duration(t.Jitter))
+		} else if t.SliceSize > 0 {
+			delay = time.Duration(t.SliceDelay)
+		}
+		if !w.sleep(delay) {
+			return total, net.ErrClosed
+		}
+
+		n := len(b)
+		if t.SliceSize > 0 {
+			n = min(n, t.SliceSize)
+		}
+		if t.Bandwidth > 0 {
+			// 100ms 단위로 나누어 전송하여 속도가 고르게 유지되도록 함
+			n = min(n, int(max(t.Bandwidth/10, 1)))
+		}
+		if t.LimitBytes > 0 {
+			n = int(min(int64(n), max(t.LimitBytes-w.written, 0)))
+		}
+
+		// 전송할 byte 수에 해당하는 시간만큼 먼저 대기
+		if t.Bandwidth > 0 && !w.sleep(time.Duration(n)*time.Second/time.Duration(t.Bandwidth)) {
+			return total, net.ErrClosed
+		}
+
+		m, err := w.dst.Write(b[:n])
+		w.written += int64(m)
+		total += m
+		b = b[m:]
+		if err != nil {
+			return total, err
+		}
+		first = false
+
+		// 설정한 byte 수를 전달하면 바로 연결을 끊음
+		if t.LimitBytes > 0 && w.written >= t.LimitBytes {
+			w.reset.Store(t.Reset)
+			w.cut()
+			return total, ErrCut
+		}
+	}
+
+	return total, nil
+}
+
+// base ± j 범위의 무작위 지연
+func jitter(base, j time.Duration) time.Duration {
+	if j > 0 {
+		base += time.Duration(rand.Int64N(int64(2*j+1))) - j
+	}
+	return max(base, 0)
+}
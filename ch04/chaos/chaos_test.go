@@ -0,0 +1,265 @@
+package chaos
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// 받은 데이터를 server 채널로 전달하고, 그대로 돌려주는 upstream
+func echoServer(t *testing.T) (string, <-chan []byte) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	reads := make(chan []byte, 1024)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 1024)
+				for {
+					n, err := conn.Read(buf)
+					if err != nil {
+						close(reads)
+						return
+					}
+					reads <- bytes.Clone(buf[:n])
+					_, _ = conn.Write(buf[:n])
+				}
+			}()
+		}
+	}()
+
+	return l.Addr().String(), reads
+}
+
+// proxy를 실행하고 연결된 client 반환
+func dialProxy(t *testing.T, p *Proxy) net.Conn {
+	t.Helper()
+
+	p.ErrorLog = log.New(io.Discard, "", 0)
+
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- p.Serve(ctx, l) }()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+
+	return conn
+}
+
+func roundTrip(t *testing.T, conn net.Conn, msg string) time.Duration {
+	t.Helper()
+
+	start := time.Now()
+	_, err := conn.Write([]byte(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, len(msg))
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = io.ReadFull(conn, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != msg {
+		t.Errorf("expected %q; actual %q", msg, buf)
+	}
+
+	return time.Since(start)
+}
+
+func TestLatency(t *testing.T) {
+	addr, _ := echoServer(t)
+	p := New(addr)
+	conn := dialProxy(t, p)
+
+	if d := roundTrip(t, conn, "fast"); d > 50*time.Millisecond {
+		t.Errorf("expected fast round trip; actual %s", d)
+	}
+
+	err := p.SetToxics(Downstream, Toxics{
+		Latency: Duration(100 * time.Millisecond),
+		Jitter:  Duration(20 * time.Millisecond),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 이미 연결된 연결에도 적용
+	d := roundTrip(t, conn, "slow")
+	t.Logf("round trip: %s", d)
+	if d < 80*time.Millisecond {
+		t.Errorf("expected at least 80ms; actual %s", d)
+	}
+}
+
+func TestBandwidth(t *testing.T) {
+	addr, _ := echoServer(t)
+	p := New(addr)
+	_ = p.SetToxics(Upstream, Toxics{Bandwidth: 20 << 10}) // 20KB/s
+	conn := dialProxy(t, p)
+
+	d := roundTrip(t, conn, strings.Repeat("x", 4<<10))
+	t.Logf("4KB at 20KB/s: %s", d)
+	if d < 150*time.Millisecond {
+		t.Errorf("expected at least 150ms; actual %s", d)
+	}
+}
+
+func TestSlice(t *testing.T) {
+	addr, reads := echoServer(t)
+	p := New(addr)
+	_ = p.SetToxics(Upstream, Toxics{
+		SliceSize:  3,
+		SliceDelay: Duration(5 * time.Millisecond),
+	})
+	conn := dialProxy(t, p)
+
+	msg := "sliced into tiny segments"
+	roundTrip(t, conn, msg)
+
+	// upstream은 최대 3byte씩 나뉘어 수신
+	received := ""
+	for len(received) < len(msg) {
+		b := <-reads
+		if len(b) > 3 {
+			t.Errorf("expected at most 3 bytes per read; actual %q", b)
+		}
+		received += string(b)
+	}
+}
+
+func TestLimitBytes(t *testing.T) {
+	addr, reads := echoServer(t)
+	p := New(addr)
+	_ = p.SetToxics(Upstream, Toxics{LimitBytes: 10, Reset: true})
+	conn := dialProxy(t, p)
+
+	_, err := conn.Write([]byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// upstream은 정확히 10byte만 수신
+	received := ""
+	for b := range reads {
+		received += string(b)
+	}
+	if received != "0123456789" {
+		t.Errorf("expected %q; actual %q", "0123456789", received)
+	}
+
+	// client는 RST를 받음
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = io.ReadAll(conn)
+	if !errors.Is(err, syscall.ECONNRESET) {
+		t.Errorf("expected connection reset; actual: %v", err)
+	}
+}
+
+func TestStallAdmin(t *testing.T) {
+	addr, reads := echoServer(t)
+	p := New(addr)
+	conn := dialProxy(t, p)
+
+	admin := httptest.NewServer(p.Handler())
+	defer admin.Close()
+
+	do := func(method, path, body string) int {
+		req, _ := http.NewRequest(method, admin.URL+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := do(http.MethodPut, "/toxics/upstream", `{"stall": true}`); code != http.StatusOK {
+		t.Fatalf("expected 200; actual %d", code)
+	}
+	for _, c := range []struct {
+		path, body string
+		code       int
+	}{
+		{"/toxics/sideways", `{}`, http.StatusNotFound},
+		{"/toxics/upstream", `{"latency": "-1s"}`, http.StatusBadRequest},
+		{"/toxics/upstream", `{"unknown": 1}`, http.StatusBadRequest},
+	} {
+		if code := do(http.MethodPut, c.path, c.body); code != c.code {
+			t.Errorf("%s %s: expected %d; actual %d", c.path, c.body, c.code, code)
+		}
+	}
+
+	_, err := conn.Write([]byte("stalled"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case b := <-reads:
+		t.Fatalf("expected no data while stalled; actual %q", b)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// 설정을 제거하면 멈춰 있던 데이터 전달
+	if code := do(http.MethodDelete, "/toxics/upstream", ""); code != http.StatusNoContent {
+		t.Fatalf("expected 204; actual %d", code)
+	}
+	select {
+	case b := <-reads:
+		if string(b) != "stalled" {
+			t.Errorf("expected %q; actual %q", "stalled", b)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("data not delivered after stall was cleared")
+	}
+}
+
+func TestStallShutdown(t *testing.T) {
+	addr, _ := echoServer(t)
+	p := New(addr)
+	_ = p.SetToxics(Downstream, Toxics{Stall: true})
+	conn := dialProxy(t, p)
+
+	// Stall 중인 연결이 있어도 proxy는 종료되어야 함 (Cleanup에서 확인)
+	_, err := conn.Write([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+}
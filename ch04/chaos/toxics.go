@@ -0,0 +1,102 @@
+package chaos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 데이터가 흐르는 방향
+type Direction int
+
+const (
+	Upstream   Direction = iota // client -> server
+	Downstream                  // server -> client
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Upstream:
+		return "upstream"
+	case Downstream:
+		return "downstream"
+	}
+	return fmt.Sprintf("Direction(%d)", int(d))
+}
+
+func ParseDirection(s string) (Direction, error) {
+	switch s {
+	case "upstream":
+		return Upstream, nil
+	case "downstream":
+		return Downstream, nil
+	}
+	return 0, fmt.Errorf("unknown direction %q", s)
+}
+
+// 한 방향에 적용할 장애 설정. zero value는 아무 장애도 주지 않음
+//
+// 각 설정은 데이터를 전달할 때마다 다시 읽으므로 이미 연결된 연결에도 바로 적용된다.
+// 적용 순서는 Stall, Latency/Jitter, Slice, Bandwidth, Limit 순이다.
+type Toxics struct {
+	// 데이터 전달을 멈춤. 전달하지 않는 동안 원본 연결에서도 읽지 않으므로
+	// 수신 버퍼가 가득 차 송신측은 zero window 상태가 된다. (ch04.ZeroWindowErr 참고)
+	Stall bool `json:"stall,omitempty"`
+
+	Latency Duration `json:"latency,omitempty"` // 매 write마다 추가할 지연
+	Jitter  Duration `json:"jitter,omitempty"`  // Latency에 더하거나 뺄 최대 무작위 지연
+
+	SliceSize  int      `json:"slice_size,omitempty"`  // write를 나눌 최대 byte 수
+	SliceDelay Duration `json:"slice_delay,omitempty"` // 나눈 조각 사이의 지연
+
+	Bandwidth int64 `json:"bandwidth,omitempty"` // 초당 최대 byte 수
+
+	LimitBytes int64 `json:"limit_bytes,omitempty"` // 이 방향으로 N byte를 전달한 뒤 연결을 끊음
+	Reset      bool  `json:"reset,omitempty"`       // 연결을 끊을 때 FIN 대신 RST 전송
+}
+
+func (t Toxics) Validate() error {
+	switch {
+	case t.Latency < 0, t.Jitter < 0, t.SliceDelay < 0:
+		return errors.New("durations must not be negative")
+	case t.SliceSize < 0:
+		return errors.New("slice_size must not be negative")
+	case t.Bandwidth < 0:
+		return errors.New("bandwidth must not be negative")
+	case t.LimitBytes < 0:
+		return errors.New("limit_bytes must not be negative")
+	}
+	return nil
+}
+
+// JSON에서 "100ms"와 같은 문자열로 표현하는 time.Duration
+// 숫자인 경우 nanosecond로 해석
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v any
+	err := json.Unmarshal(b, &v)
+	if err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case float64:
+		*d = Duration(v)
+	case string:
+		dur, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = Duration(dur)
+	default:
+		return fmt.Errorf("invalid duration %s", b)
+	}
+
+	return nil
+}
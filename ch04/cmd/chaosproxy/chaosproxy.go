@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/huGgW/network-study-with-go/ch04/chaos"
+	"github.com/huGgW/network-study-with-go/ch04/sockopt"
+)
+
+var (
+	listen     string
+	upstream   string
+	admin      string
+	upToxics   string
+	downToxics string
+	sockOpts   sockopt.Options
+)
+
+func init() {
+	flag.StringVar(&listen, "listen", "127.0.0.1:8080", "listen address")
+	flag.StringVar(&upstream, "upstream", "", "upstream host:port")
+	flag.StringVar(&admin, "admin", "127.0.0.1:8474", "admin API address")
+	flag.StringVar(&upToxics, "up", "", "initial upstream toxics in JSON")
+	flag.StringVar(&downToxics, "down", "", "initial downstream toxics in JSON")
+	sockOpts.RegisterFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(),
+			`
+Usage: %s [flags] -upstream host:port
+    proxy TCP connections to upstream, injecting faults per direction
+    toxics: {"stall": bool, "latency": "100ms", "jitter": "10ms",
+             "slice_size": n, "slice_delay": "1ms", "bandwidth": bytes/sec,
+             "limit_bytes": n, "reset": bool}
+Admin API:
+    GET    /toxics
+    GET    /toxics/{upstream|downstream}
+    PUT    /toxics/{upstream|downstream}   (JSON toxics)
+    DELETE /toxics/{upstream|downstream}
+    GET    /stats
+Flags:
+`,
+			filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	if upstream == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	p := chaos.New(upstream)
+	p.SocketOptions = sockOpts
+	for d, s := range map[chaos.Direction]string{chaos.Upstream: upToxics, chaos.Downstream: downToxics} {
+		if s == "" {
+			continue
+		}
+		var t chaos.Toxics
+		err := json.Unmarshal([]byte(s), &t)
+		if err == nil {
+			err = p.SetToxics(d, t)
+		}
+		if err != nil {
+			log.Fatalf("%s toxics: %v", d, err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: admin, Handler: p.Handler()}
+	go func() {
+		err := srv.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+
+	log.Printf("proxying %s -> %s, admin API on %s", listen, upstream, admin)
+	err := p.ListenAndServe(ctx, listen)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/netip"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/huGgW/network-study-with-go/ch04/scan"
+)
+
+var (
+	ports       string
+	concurrency int
+	rate        float64
+	timeout     time.Duration
+	banner      bool
+	jsonOutput  bool
+	all         bool
+)
+
+func init() {
+	flag.StringVar(&ports, "p", "1-1024", "ports to scan: e.g. 22,80,8000-8100")
+	flag.IntVar(&concurrency, "c", 100, "maximum concurrent connection attempts")
+	flag.Float64Var(&rate, "rate", 0, "maximum connection attempts per second: 0 means unlimited")
+	flag.DurationVar(&timeout, "W", time.Second, "handshake timeout")
+	flag.BoolVar(&banner, "banner", false, "read a banner from open ports")
+	flag.BoolVar(&jsonOutput, "json", false, "print results as JSON lines as they complete")
+	flag.BoolVar(&all, "all", false, "show closed and filtered ports as well")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(),
+			`
+Usage: %s [flags] target [target ...]
+    TCP connect scan of hosts, IPs or CIDRs (e.g. 192.168.0.0/24)
+    open: handshake succeeded
+    closed: connection refused
+    filtered: no response within the timeout, or unreachable
+Flags:
+`,
+			filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	hosts, err := scan.ParseTargets(flag.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+	portList, err := scan.ParsePorts(ports)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s := scan.Scanner{Concurrency: concurrency, Rate: rate, Timeout: timeout, Banner: banner}
+
+	// CTRL+C로 중단하면 그때까지의 결과를 출력
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	enc := json.NewEncoder(os.Stdout)
+	var results []scan.Result
+	for r := range s.Scan(ctx, hosts, portList) {
+		if !all && r.State != scan.Open {
+			continue
+		}
+		if jsonOutput {
+			v := map[string]any{
+				"host":   r.Host,
+				"port":   r.Port,
+				"state":  r.State.String(),
+				"rtt_ms": float64(r.RTT) / float64(time.Millisecond),
+			}
+			if r.Banner != "" {
+				v["banner"] = r.Banner
+			}
+			if r.Err != nil {
+				v["error"] = r.Err.Error()
+			}
+			_ = enc.Encode(v)
+			continue
+		}
+		results = append(results, r)
+	}
+
+	if !jsonOutput {
+		printTable(results)
+	}
+}
+
+// 호스트(IP는 주소 순), 포트 순으로 정렬하여 표로 출력
+func printTable(results []scan.Result) {
+	slices.SortFunc(results, func(a, b scan.Result) int {
+		if c := compareHost(a.Host, b.Host); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Port, b.Port)
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "HOST\tPORT\tSTATE\tRTT\tBANNER")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t", r.Host, r.Port, r.State, r.RTT.Round(time.Microsecond))
+		if r.Banner != "" {
+			fmt.Fprintf(w, "%q", r.Banner)
+		}
+		fmt.Fprintln(w)
+	}
+	_ = w.Flush()
+}
+
+func compareHost(a, b string) int {
+	aAddr, aErr := netip.ParseAddr(a)
+	bAddr, bErr := netip.ParseAddr(b)
+	if aErr == nil && bErr == nil {
+		return aAddr.Compare(bAddr)
+	}
+	return cmp.Compare(a, b)
+}
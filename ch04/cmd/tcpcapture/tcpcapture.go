@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/huGgW/network-study-with-go/ch04/capture"
+	"github.com/huGgW/network-study-with-go/ch04/sockopt"
+)
+
+var (
+	listen   string
+	upstream string
+	dir      string
+	scale    float64
+	timeout  time.Duration
+	sockOpts sockopt.Options
+)
+
+func init() {
+	flag.StringVar(&listen, "listen", "127.0.0.1:8080", "listen address for record and serve")
+	flag.StringVar(&upstream, "upstream", "", "server host:port for record and replay")
+	flag.StringVar(&dir, "dir", ".", "directory to write captures to")
+	flag.Float64Var(&scale, "scale", 1, "timing scale: 1 is original, 0.5 is twice as fast, 0 is no delay")
+	flag.DurationVar(&timeout, "timeout", 5*time.Second, "time to wait for peer data while replaying")
+	sockOpts.RegisterFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(),
+			`
+Usage: %s [flags] [record|replay file|serve file]
+    record: proxy -listen to -upstream, writing a capture per connection to -dir
+    replay: play the client side of a capture against -upstream
+    serve: act as the server of a capture for clients connecting to -listen
+Flags:
+`,
+			filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var err error
+	switch strings.ToLower(flag.Arg(0)) {
+	case "record":
+		err = record(ctx)
+	case "replay":
+		err = replay()
+	case "serve":
+		err = serve(ctx)
+	default:
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func listenContext(ctx context.Context) (net.Listener, error) {
+	l, err := sockOpts.Listen(ctx, "tcp", listen)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		_ = l.Close()
+	}()
+	return l, nil
+}
+
+func record(ctx context.Context) error {
+	if upstream == "" {
+		return fmt.Errorf("-upstream is required")
+	}
+
+	l, err := listenContext(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("recording %s -> %s into %s", l.Addr(), upstream, dir)
+
+	rec := &capture.Recorder{Dir: dir}
+	for {
+		client, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		go func() {
+			defer client.Close()
+
+			server, err := net.Dial("tcp", upstream)
+			if err != nil {
+				log.Printf("%s: %v", client.RemoteAddr(), err)
+				return
+			}
+			defer server.Close()
+
+			path, stats, err := rec.Proxy(client, server)
+			if err != nil {
+				log.Printf("%s: %v", client.RemoteAddr(), err)
+			}
+			log.Printf("%s: %d bytes up, %d bytes down", path, stats.ClientToServer, stats.ServerToClient)
+		}()
+	}
+}
+
+func load() (*capture.Capture, error) {
+	if flag.NArg() < 2 {
+		return nil, fmt.Errorf("capture file is required")
+	}
+	return capture.Load(flag.Arg(1))
+}
+
+func replay() error {
+	c, err := load()
+	if err != nil {
+		return err
+	}
+	if upstream == "" {
+		upstream = c.Server
+	}
+
+	conn, err := net.Dial("tcp", upstream)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	r := &capture.Replayer{Scale: scale, Timeout: timeout}
+	err = r.Client(conn, c)
+	if err != nil {
+		return err
+	}
+	log.Printf("replayed %d events against %s: OK", len(c.Events), upstream)
+
+	return nil
+}
+
+func serve(ctx context.Context) error {
+	c, err := load()
+	if err != nil {
+		return err
+	}
+
+	l, err := listenContext(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("serving %s on %s", flag.Arg(1), l.Addr())
+
+	r := &capture.Replayer{Scale: scale, Timeout: timeout}
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		go func() {
+			defer conn.Close()
+
+			err := r.Server(conn, c)
+			if err != nil {
+				log.Printf("%s: %v", conn.RemoteAddr(), err)
+				return
+			}
+			log.Printf("%s: OK", conn.RemoteAddr())
+		}()
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/huGgW/network-study-with-go/ch04/lb"
+	"github.com/huGgW/network-study-with-go/ch04/sockopt"
+)
+
+var (
+	listen         string
+	policy         string
+	healthInterval time.Duration
+	healthTimeout  time.Duration
+	maxFails       int
+	drainTimeout   time.Duration
+	sockOpts       sockopt.Options
+)
+
+func init() {
+	flag.StringVar(&listen, "listen", "127.0.0.1:8080", "listen address")
+	flag.StringVar(&policy, "policy", "rr", "balancing policy: rr, leastconn or hash")
+	flag.DurationVar(&healthInterval, "health-interval", 5*time.Second, "health check interval: < 0 disables health checks")
+	flag.DurationVar(&healthTimeout, "health-timeout", time.Second, "health check handshake timeout")
+	flag.IntVar(&maxFails, "max-fails", 2, "consecutive failures before an upstream is marked down")
+	flag.DurationVar(&drainTimeout, "drain", 30*time.Second, "time to wait for connections on shutdown")
+	sockOpts.RegisterFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(),
+			`
+Usage: %s [flags] upstream [upstream ...]
+    forward TCP connections to upstream host:port addresses
+    rr: round-robin
+    leastconn: upstream with the fewest active connections
+    hash: consistent hashing on client IP
+Flags:
+`,
+			filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	s := lb.New(flag.Args()...)
+	s.HealthInterval = healthInterval
+	s.HealthTimeout = healthTimeout
+	s.MaxFails = maxFails
+	s.DrainTimeout = drainTimeout
+	s.SocketOptions = sockOpts
+
+	switch policy {
+	case "rr":
+		s.Balancer = &lb.RoundRobin{}
+	case "leastconn":
+		s.Balancer = &lb.LeastConn{}
+	case "hash":
+		s.Balancer = lb.ConsistentHash{}
+	default:
+		log.Fatalf("unknown policy %q", policy)
+	}
+
+	// SIGINT, SIGTERM을 받으면 새 연결을 받지 않고 기존 연결이 끝나기를 기다림
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("balancing %s -> %v (%s)", listen, flag.Args(), policy)
+	err := s.ListenAndServe(ctx, listen)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Print("all connections drained")
+}
@@ -0,0 +1,7 @@
+package main
+
+import "github.com/huGgW/network-study-with-go/ch04"
+
+func main() {
+	ch04.Ping()
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/huGgW/network-study-with-go/ch04"
+)
+
+// 디코딩한 frame 하나의 정보
+type frame struct {
+	Conn   string `json:"conn,omitempty"`
+	Offset int64  `json:"offset"`
+	Code   uint8  `json:"code"`   // 헤더의 type 코드. Flagged인 경우 내부 payload와 다름
+	Length uint32 `json:"length"` // 헤더를 제외한 값의 길이. Stream은 전체 데이터 길이
+	element
+}
+
+// 여러 연결의 frame을 섞이지 않게 출력
+type printer struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+func newPrinter(w io.Writer) *printer {
+	return &printer{w: w, enc: json.NewEncoder(w)}
+}
+
+func (p *printer) print(f frame) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if jsonOutput {
+		return p.enc.Encode(f)
+	}
+
+	prefix := ""
+	if f.Conn != "" {
+		prefix = "[" + f.Conn + "] "
+	}
+	name := f.Type
+	if f.Code == ch04.FlaggedType {
+		name += "*"
+	}
+	_, err := fmt.Fprintf(p.w, "%s%08x  %-10s %8d  %s\n",
+		prefix, f.Offset, name, f.Length, previewOf(f.payload))
+
+	return err
+}
+
+// r의 frame을 끝까지 읽어 출력
+func (p *printer) dump(conn string, r io.Reader) error {
+	d := newDumper(r)
+	for {
+		f, err := d.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("offset %d: %w", d.r.n, err)
+		}
+
+		f.Conn = conn
+		err = p.print(f)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+type dumper struct {
+	br  *bufio.Reader
+	r   *countingReader
+	dec *ch04.Decoder
+}
+
+func newDumper(r io.Reader) *dumper {
+	br := bufio.NewReader(r)
+	cr := &countingReader{r: br}
+	return &dumper{br: br, r: cr, dec: ch04.NewDecoder(cr)}
+}
+
+func (d *dumper) next() (frame, error) {
+	// 헤더는 소비하지 않고 미리 확인
+	header, err := d.br.Peek(5)
+	if err != nil {
+		if err == io.EOF && len(header) > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return frame{}, err
+	}
+
+	f := frame{
+		Offset: d.r.n,
+		Code:   header[0],
+		Length: binary.BigEndian.Uint32(header[1:]),
+	}
+
+	var p ch04.Payload
+	if _, err := ch04.DefaultRegistry.New(f.Code); err != nil {
+		// 알 수 없는 타입은 헤더의 길이만큼 건너뛰어 다음 frame을 계속 출력
+		p, err = readRaw(d.r, f.Code, f.Length)
+		if err != nil {
+			return frame{}, err
+		}
+	} else {
+		p, err = d.dec.Decode()
+		if err != nil {
+			return frame{}, err
+		}
+
+		if s, ok := p.(*ch04.Stream); ok {
+			// 다음 frame을 읽기 위해 stream을 끝까지 읽음
+			f.Length = uint32(len(s.Bytes()))
+		}
+	}
+
+	f.element, err = elementOf(p)
+	return f, err
+}
+
+func readRaw(r io.Reader, code uint8, size uint32) (*raw, error) {
+	if size > ch04.MaxPayloadSize {
+		return nil, ch04.ErrMaxPayloadSize
+	}
+
+	buf := make([]byte, 5+int(size))
+	_, err := io.ReadFull(r, buf)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &raw{code: code, value: buf[5:]}, nil
+}
+
+// 사람이 읽을 수 있는 값의 앞부분
+// 데이터 타입은 UTF-8 문자열로 보이면 문자열, 아니면 hex로 표시
+func previewOf(p ch04.Payload) string {
+	var s string
+	switch p.(type) {
+	case *ch04.Binary, *ch04.Stream, *raw:
+		b := p.Bytes()
+		if printable(b) {
+			s = fmt.Sprintf("%q", truncate(b))
+		} else {
+			s = hex.EncodeToString(truncate(b))
+		}
+		if len(b) > preview {
+			s += "..."
+		}
+	default:
+		s = p.String()
+		if len(s) > preview {
+			s = string(truncate([]byte(s))) + "..."
+		}
+	}
+
+	return s
+}
+
+func printable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// preview 길이로 자르되 UTF-8 문자 중간에서 자르지 않음
+func truncate(b []byte) []byte {
+	if len(b) <= preview {
+		return b
+	}
+	n := preview
+	for n > 0 && !utf8.RuneStart(b[n]) {
+		n--
+	}
+	return b[:n]
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
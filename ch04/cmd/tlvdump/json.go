@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/huGgW/network-study-with-go/ch04"
+)
+
+// JSON 표현에서 사용하는 타입 이름
+// 등록되지 않은 타입은 type 코드를 10진수 문자열로 표현하고 값은 hex로 표현한다.
+var typeNames = map[uint8]string{
+	ch04.BinaryType:    "Binary",
+	ch04.StringType:    "String",
+	ch04.Int64Type:     "Int64",
+	ch04.Uint64Type:    "Uint64",
+	ch04.VarintType:    "Varint",
+	ch04.UvarintType:   "Uvarint",
+	ch04.Float64Type:   "Float64",
+	ch04.BoolType:      "Bool",
+	ch04.TimestampType: "Timestamp",
+	ch04.ListType:      "List",
+	ch04.MapType:       "Map",
+	ch04.ChunkType:     "Stream",
+}
+
+var flagNames = map[string]uint8{
+	"deflate": ch04.FlagDeflate,
+	"gzip":    ch04.FlagGzip,
+	"crc32c":  ch04.FlagCRC32C,
+}
+
+// payload의 JSON 표현
+//
+//	Binary, Stream: hex 문자열
+//	Timestamp: RFC 3339 문자열
+//	Float64: 숫자. NaN, Inf는 문자열
+//	List: element 배열
+//	Map: key와 element의 객체
+type element struct {
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+
+	payload ch04.Payload
+}
+
+func elementOf(p ch04.Payload) (element, error) {
+	e := element{payload: p}
+
+	switch v := p.(type) {
+	case *ch04.Binary:
+		e.Value = hex.EncodeToString(*v)
+	case *ch04.String:
+		e.Value = string(*v)
+	case *ch04.Int64:
+		e.Value = int64(*v)
+	case *ch04.Uint64:
+		e.Value = uint64(*v)
+	case *ch04.Varint:
+		e.Value = int64(*v)
+	case *ch04.Uvarint:
+		e.Value = uint64(*v)
+	case *ch04.Float64:
+		f := float64(*v)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			e.Value = strconv.FormatFloat(f, 'g', -1, 64)
+		} else {
+			e.Value = f
+		}
+	case *ch04.Bool:
+		e.Value = bool(*v)
+	case *ch04.Timestamp:
+		e.Value = v.Time().Format(time.RFC3339Nano)
+	case *ch04.Stream:
+		e.Value = hex.EncodeToString(v.Bytes())
+	case *ch04.List:
+		elems := make([]element, 0, len(*v))
+		for _, p := range *v {
+			elem, err := elementOf(p)
+			if err != nil {
+				return e, err
+			}
+			elems = append(elems, elem)
+		}
+		e.Value = elems
+	case *ch04.Map:
+		elems := make(map[string]element, len(*v))
+		for k, p := range *v {
+			elem, err := elementOf(p)
+			if err != nil {
+				return e, err
+			}
+			elems[k] = elem
+		}
+		e.Value = elems
+	case *raw:
+		e.Type = strconv.Itoa(int(v.code))
+		e.Value = hex.EncodeToString(v.value)
+		return e, nil
+	default:
+		return e, fmt.Errorf("unsupported payload type %T", p)
+	}
+
+	// typeNames의 이름은 ch04의 타입 이름과 같음
+	e.Type = strings.TrimPrefix(fmt.Sprintf("%T", p), "*ch04.")
+	return e, nil
+}
+
+// JSON으로 기술된 payload
+// -json 출력의 각 줄도 그대로 사용할 수 있다.
+// 단, 디코딩 시 Flagged는 벗겨지고 Stream의 frame 크기는 알 수 없으므로
+// 다시 인코딩한 결과는 값은 같지만 byte 단위로는 원본과 다를 수 있다.
+type description struct {
+	Type      string          `json:"type"`
+	Value     json.RawMessage `json:"value"`
+	Flags     []string        `json:"flags"`      // Flagged로 감쌀 때 사용할 flag
+	ChunkSize int             `json:"chunk_size"` // Stream의 frame 크기
+}
+
+// r의 JSON 값들을 TLV로 인코딩하여 w에 기록
+// 각 값은 description 객체이거나 description의 배열이어야 한다.
+func encodeJSON(w io.Writer, r io.Reader) error {
+	dec := json.NewDecoder(r)
+	for {
+		var v json.RawMessage
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var descs []description
+		if bytes.HasPrefix(bytes.TrimSpace(v), []byte("[")) {
+			err = json.Unmarshal(v, &descs)
+		} else {
+			descs = make([]description, 1)
+			err = json.Unmarshal(v, &descs[0])
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, d := range descs {
+			p, err := d.payload()
+			if err != nil {
+				return err
+			}
+			_, err = p.WriteTo(w)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func typeCode(name string) (uint8, bool) {
+	for code, n := range typeNames {
+		if strings.EqualFold(n, name) {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+func (d description) payload() (ch04.Payload, error) {
+	code, ok := typeCode(d.Type)
+	if !ok {
+		n, err := strconv.ParseUint(d.Type, 0, 8)
+		if err != nil || n == 0 {
+			return nil, fmt.Errorf("unknown type %q", d.Type)
+		}
+		b, err := d.hex()
+		return &raw{code: uint8(n), value: b}, err
+	}
+
+	p, err := d.decode(code)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", d.Type, err)
+	}
+
+	if len(d.Flags) == 0 {
+		return p, nil
+	}
+	if code == ch04.ChunkType {
+		return nil, errors.New("flags are not supported for Stream")
+	}
+
+	f := &ch04.Flagged{Payload: p}
+	for _, name := range d.Flags {
+		flag, ok := flagNames[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown flag %q", name)
+		}
+		f.Flags |= flag
+	}
+
+	return f, nil
+}
+
+func (d description) decode(code uint8) (ch04.Payload, error) {
+	switch code {
+	case ch04.BinaryType:
+		b, err := d.hex()
+		return ptr(ch04.Binary(b)), err
+	case ch04.StringType:
+		var s string
+		err := json.Unmarshal(d.Value, &s)
+		return ptr(ch04.String(s)), err
+	case ch04.Int64Type, ch04.VarintType:
+		var n int64
+		err := json.Unmarshal(d.Value, &n)
+		if code == ch04.VarintType {
+			return ptr(ch04.Varint(n)), err
+		}
+		return ptr(ch04.Int64(n)), err
+	case ch04.Uint64Type, ch04.UvarintType:
+		var n uint64
+		err := json.Unmarshal(d.Value, &n)
+		if code == ch04.UvarintType {
+			return ptr(ch04.Uvarint(n)), err
+		}
+		return ptr(ch04.Uint64(n)), err
+	case ch04.Float64Type:
+		var f float64
+		err := json.Unmarshal(d.Value, &f)
+		if err != nil {
+			// NaN, Inf는 문자열로 표현
+			var s string
+			if json.Unmarshal(d.Value, &s) != nil {
+				return nil, err
+			}
+			f, err = strconv.ParseFloat(s, 64)
+		}
+		return ptr(ch04.Float64(f)), err
+	case ch04.BoolType:
+		var b bool
+		err := json.Unmarshal(d.Value, &b)
+		return ptr(ch04.Bool(b)), err
+	case ch04.TimestampType:
+		var s string
+		err := json.Unmarshal(d.Value, &s)
+		if err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		return ptr(ch04.Timestamp(t)), err
+	case ch04.ChunkType:
+		b, err := d.hex()
+		s := ch04.NewStream(bytes.NewReader(b))
+		s.ChunkSize = d.ChunkSize
+		return s, err
+	case ch04.ListType:
+		var descs []description
+		err := json.Unmarshal(d.Value, &descs)
+		if err != nil {
+			return nil, err
+		}
+		l := make(ch04.List, 0, len(descs))
+		for _, e := range descs {
+			p, err := e.payload()
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, p)
+		}
+		return &l, nil
+	case ch04.MapType:
+		var descs map[string]description
+		err := json.Unmarshal(d.Value, &descs)
+		if err != nil {
+			return nil, err
+		}
+		m := make(ch04.Map, len(descs))
+		for k, e := range descs {
+			p, err := e.payload()
+			if err != nil {
+				return nil, err
+			}
+			m[k] = p
+		}
+		return &m, nil
+	}
+
+	return nil, ch04.ErrUnknownType
+}
+
+func (d description) hex() ([]byte, error) {
+	var s string
+	err := json.Unmarshal(d.Value, &s)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(s)
+}
+
+// 등록되지 않은 타입의 frame
+type raw struct {
+	code  uint8
+	value []byte
+}
+
+func (m *raw) Bytes() []byte { return m.value }
+
+func (m *raw) String() string { return hex.EncodeToString(m.value) }
+
+func (m *raw) WriteTo(w io.Writer) (int64, error) {
+	header := binary.BigEndian.AppendUint32([]byte{m.code}, uint32(len(m.value)))
+	n, err := w.Write(append(header, m.value...))
+	return int64(n), err
+}
+
+func (m *raw) ReadFrom(io.Reader) (int64, error) {
+	return 0, errors.New("raw frames are read by readRaw")
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+var (
+	jsonOutput bool
+	reverse    bool
+	preview    int
+	listen     string
+	upstream   string
+	output     string
+)
+
+func init() {
+	flag.BoolVar(&jsonOutput, "json", false, "print frames as JSON lines")
+	flag.BoolVar(&reverse, "reverse", false, "build a TLV stream from a JSON description")
+	flag.IntVar(&preview, "preview", 32, "maximum preview length in bytes")
+	flag.StringVar(&listen, "listen", "", "tap TLV streams on this address")
+	flag.StringVar(&upstream, "upstream", "", "forward tapped connections to this address")
+	flag.StringVar(&output, "o", "-", "output file for reverse mode")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(),
+			`
+Usage: %s [flags] [file]
+    dump TLV frames read from file (or stdin if omitted or "-")
+    -listen: dump frames of each accepted connection,
+             forwarding both directions to -upstream if given
+    -reverse: read JSON frame descriptions (same form as -json output)
+              from file and write the encoded TLV stream to -o
+Flags:
+`,
+			filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	var err error
+	switch {
+	case listen != "":
+		err = tap(listen, upstream)
+	case reverse:
+		err = build()
+	default:
+		err = dumpFile()
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func open(name string) (io.ReadCloser, error) {
+	if name == "" || name == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(name)
+}
+
+func dumpFile() error {
+	in, err := open(flag.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	return newPrinter(os.Stdout).dump("", in)
+}
+
+func build() error {
+	in, err := open(flag.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var out io.WriteCloser = os.Stdout
+	if output != "-" {
+		out, err = os.Create(output)
+		if err != nil {
+			return err
+		}
+	}
+
+	w := bufio.NewWriter(out)
+	err = encodeJSON(w, in)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cErr := out.Close(); err == nil {
+		err = cErr
+	}
+
+	return err
+}
+
+// address에서 연결을 받아 각 방향의 TLV frame을 출력
+// upstream이 주어진 경우 데이터를 그대로 중계하므로 실제 트래픽 사이에 끼워 사용할 수 있다.
+func tap(address, upstream string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	log.Printf("listening on %s", listener.Addr())
+
+	p := newPrinter(os.Stdout)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			err := tapConn(p, conn, upstream)
+			if err != nil {
+				log.Printf("%s: %v", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+func tapConn(p *printer, conn net.Conn, upstream string) error {
+	defer conn.Close()
+
+	client := conn.RemoteAddr().String()
+	if upstream == "" {
+		return p.dump(client, conn)
+	}
+
+	server, err := net.Dial("tcp", upstream)
+	if err != nil {
+		return err
+	}
+	defer server.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- relay(p, client+" -> "+upstream, server, conn)
+	}()
+	err = relay(p, client+" <- "+upstream, conn, server)
+
+	return errors.Join(err, <-done)
+}
+
+// src에서 읽은 데이터를 dst로 전달하면서 frame을 출력
+// 디코딩에 실패하더라도 연결이 끊기지 않도록 나머지 데이터는 그대로 전달한다.
+func relay(p *printer, label string, dst, src net.Conn) error {
+	r := io.TeeReader(src, dst)
+
+	err := p.dump(label, r)
+	if err != nil {
+		log.Printf("%s: %v", label, err)
+		_, err = io.Copy(io.Discard, r)
+	}
+
+	// 반대 방향은 계속 동작하도록 write 방향만 종료
+	if c, ok := dst.(interface{ CloseWrite() error }); ok {
+		_ = c.CloseWrite()
+	}
+
+	return err
+}
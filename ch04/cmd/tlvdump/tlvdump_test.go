@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/huGgW/network-study-with-go/ch04"
+)
+
+const testFrames = `
+{"type":"String","value":"hello, 세계"}
+[{"type":"Binary","value":"00ff10"},{"type":"varint","value":-300},{"type":"Float64","value":"+Inf"}]
+{"type":"Map","value":{"ok":{"type":"Bool","value":true},"l":{"type":"List","value":[{"type":"Uvarint","value":7},{"type":"Timestamp","value":"2024-06-01T12:30:00.5Z"}]}}}
+{"type":"Stream","value":"68656c6c6f20776f726c64","chunk_size":4}
+{"type":"100","value":"cafe"}
+{"type":"String","value":"compressed compressed compressed","flags":["deflate","crc32c"]}
+`
+
+func decodeAll(t *testing.T, r io.Reader) []ch04.Payload {
+	t.Helper()
+
+	var payloads []ch04.Payload
+	d := newDumper(r)
+	for {
+		f, err := d.next()
+		if err == io.EOF {
+			return payloads
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		payloads = append(payloads, f.payload)
+	}
+}
+
+func TestDumpRoundTrip(t *testing.T) {
+	var tlv bytes.Buffer
+	err := encodeJSON(&tlv, strings.NewReader(testFrames))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := bytes.Clone(tlv.Bytes())
+
+	// -json 출력을 다시 인코딩하면 같은 값으로 디코딩되어야 함
+	jsonOutput = true
+	defer func() { jsonOutput = false }()
+
+	var out bytes.Buffer
+	err = newPrinter(&out).dump("", &tlv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(out.String())
+
+	var again bytes.Buffer
+	err = encodeJSON(&again, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := decodeAll(t, bytes.NewReader(encoded))
+	actual := decodeAll(t, &again)
+	if len(expected) != 8 || len(actual) != 8 {
+		t.Fatalf("expected 8 frames; actual %d, %d", len(expected), len(actual))
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(expected[i].Bytes(), actual[i].Bytes()) {
+			t.Errorf("frame %d: expected %v; actual %v", i, expected[i], actual[i])
+		}
+	}
+}
+
+func TestDumpText(t *testing.T) {
+	var tlv bytes.Buffer
+	err := encodeJSON(&tlv, strings.NewReader(testFrames))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	err = newPrinter(&out).dump("", &tlv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log("\n" + out.String())
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	for i, expected := range []string{
+		`00000000  String           13  hello, 세계`,
+		`00000012  Binary            3  00ff10`,
+	} {
+		if lines[i] != expected {
+			t.Errorf("expected %q; actual %q", expected, lines[i])
+		}
+	}
+
+	// 잘린 frame은 offset과 함께 에러 반환
+	tlv.Reset()
+	_ = encodeJSON(&tlv, strings.NewReader(testFrames))
+	err = newPrinter(io.Discard).dump("", io.LimitReader(&tlv, 20))
+	if err == nil || !strings.Contains(err.Error(), "offset 18") {
+		t.Errorf("expected error at offset 18; actual: %v", err)
+	}
+}
+
+func TestTapRelay(t *testing.T) {
+	// 받은 데이터를 그대로 돌려주는 upstream
+	server, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	go func() {
+		conn, err := server.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(conn, conn)
+	}()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	jsonOutput = false
+	var out bytes.Buffer
+	p := newPrinter(&out)
+	done := make(chan error)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			done <- err
+			return
+		}
+		done <- tapConn(p, conn, server.Addr().String())
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	msg := ch04.String("through the tap")
+	_, err = msg.WriteTo(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.(*net.TCPConn).CloseWrite()
+
+	// upstream이 돌려준 frame을 그대로 수신
+	p2, err := ch04.NewDecoder(conn).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p2.String() != string(msg) {
+		t.Errorf("expected %q; actual %q", msg, p2)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// 양방향 모두 출력
+	dump := out.String()
+	t.Log("\n" + dump)
+	if strings.Count(dump, "through the tap") != 2 {
+		t.Errorf("expected frames in both directions")
+	}
+}
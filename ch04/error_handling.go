@@ -1,46 +1,46 @@
 package ch04
 
 import (
-	"errors"
+	"context"
 	"log"
-	"net"
 	"time"
+
+	"github.com/huGgW/network-study-with-go/ch04/retry"
 )
 
 func sendHelloWorldRetry() error {
-	var (
-		err error
-		n int
-		i = 7
-	)
+	// 일시적 에러 (timeout, ECONNREFUSED, ECONNRESET)에 대해
+	// 지수 백오프 + jitter로 최대 7번까지 시도
+	policy := retry.Policy{
+		MaxAttempts:     7,
+		InitialInterval: time.Second,
+		MaxInterval:     10 * time.Second,
+		AttemptTimeout:  10 * time.Second, // 시도마다 연결, 쓰기의 timeout
+		Retryable: func(err error) bool {
+			if retry.Temporary(err) {
+				log.Println("temporary error:", err)
+				return true
+			}
+			return false
+		},
+	}
 
-	conn, err := net.DialTimeout("tcp", "127.0.0.1:", time.Second * 10)
+	ctx := context.Background()
+	conn, err := retry.Dial(
+		ctx, policy, nil, "tcp", "127.0.0.1:",
+	)
 	if err != nil {
 		return err
 	}
 	defer conn.Close()
 
-	// For loop을 통해 일시적 에러에 대한 retry를 시도
-	for ; i > 0; i-- {
-		n, err = conn.Write([]byte("hello world"))
-		if err != nil {
-			// error가 net.Error로 assertion되고 Timeout일 경우 잠시 대기 후 재시도
-			// net.Error.Temporary()는 deprecated됨
-			if nErr, ok := err.(net.Error); ok && nErr.Timeout() {
-				log.Println("timeout error (timeout):", nErr)
-				time.Sleep(10 * time.Second)
-				continue
-			}
-			return err
-		}
-		break
-	}
-
-	if i == 0 {
-		return errors.New("temporary write failure threshold exceeded")
+	// retry.Write는 일부만 쓰인 경우 남은 부분부터 다시 씀
+	// ECONNRESET, EPIPE는 같은 연결로 다시 써도 실패하므로 재시도하지 않음
+	n, err := retry.Write(ctx, policy, conn, []byte("hello world"))
+	if err != nil {
+		return err
 	}
 
 	log.Printf("wrote %d bytes to %s\n", n, conn.RemoteAddr())
 	return nil
 }
-
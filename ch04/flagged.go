@@ -0,0 +1,194 @@
+package ch04
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Flagged 헤더의 flag
+const (
+	FlagDeflate uint8 = 1 << iota // 내부 frame을 deflate로 압축
+	FlagGzip                      // 내부 frame을 gzip으로 압축
+	FlagCRC32C                    // flag와 본문에 대한 CRC32-C checksum을 뒤에 붙임
+
+	flagMask = FlagDeflate | FlagGzip | FlagCRC32C
+)
+
+var (
+	ErrChecksum     = errors.New("checksum mismatch")
+	ErrInvalidFlags = errors.New("invalid flags")
+
+	castagnoli = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// Flagged TLV Payload Data Type
+// 다른 payload의 frame 전체를 감싸 압축하거나 checksum을 붙이는 확장 헤더
+//
+//	[FlaggedType][size][flags][본문 (압축된 내부 frame)][CRC32-C (FlagCRC32C인 경우)]
+//
+// TCP checksum은 16bit로 약하고, 중간 장비에서 데이터를 다시 쓰는 경우 손상을 잡지 못할 수 있다.
+// CRC32-C는 flags와 본문에 대해 계산하며, 일치하지 않으면 ErrChecksum을 반환한다.
+//
+// Decoder는 Flagged를 만나면 checksum 검증, 압축 해제 후 내부 payload를 반환하므로
+// 수신측에서는 flag 유무를 신경쓰지 않아도 된다.
+type Flagged struct {
+	Flags   uint8
+	Payload Payload
+}
+
+func (m *Flagged) typ() uint8 { return FlaggedType }
+
+func (m *Flagged) Bytes() []byte {
+	b, _ := m.encode(1)
+	return b
+}
+
+func (m *Flagged) String() string {
+	if m.Payload == nil {
+		return ""
+	}
+	return m.Payload.String()
+}
+
+func (m *Flagged) WriteTo(w io.Writer) (int64, error) {
+	value, err := m.encode(1)
+	if err != nil {
+		return 0, err
+	}
+	return writeTLV(w, FlaggedType, value)
+}
+
+func (m *Flagged) ReadFrom(r io.Reader) (int64, error) {
+	value, n, err := readTLV(r, FlaggedType)
+	if err != nil {
+		return n, err
+	}
+	return n, m.decode(value, DefaultRegistry, newBudget(MaxPayloadSize), 1)
+}
+
+func validFlags(flags uint8) bool {
+	return flags&^flagMask == 0 && flags&(FlagDeflate|FlagGzip) != FlagDeflate|FlagGzip
+}
+
+func (m *Flagged) encode(depth int) ([]byte, error) {
+	if !validFlags(m.Flags) {
+		return nil, ErrInvalidFlags
+	}
+	if m.Payload == nil {
+		return nil, errors.New("nil Flagged payload")
+	}
+
+	var inner bytes.Buffer
+	err := encodeElement(&inner, m.Payload, depth+1)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(m.Flags)
+
+	var zw io.WriteCloser
+	switch {
+	case m.Flags&FlagDeflate != 0:
+		zw, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case m.Flags&FlagGzip != 0:
+		zw = gzip.NewWriter(&buf)
+	}
+
+	if zw != nil {
+		_, err = inner.WriteTo(zw)
+		if err == nil {
+			err = zw.Close()
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		_, _ = inner.WriteTo(&buf)
+	}
+
+	if m.Flags&FlagCRC32C != 0 {
+		sum := crc32.Checksum(buf.Bytes(), castagnoli)
+		_ = binary.Write(&buf, binary.BigEndian, sum)
+	}
+
+	if buf.Len() > int(MaxPayloadSize) {
+		return nil, ErrMaxPayloadSize
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (m *Flagged) decode(value []byte, registry *Registry, budget *int64, depth int) error {
+	if depth > MaxNestingDepth {
+		return ErrMaxNestingDepth
+	}
+	if len(value) < 1 {
+		return ErrInvalidSize
+	}
+
+	flags := value[0]
+
+	// checksum을 먼저 검증하여 손상된 flag나 데이터를 해석하지 않도록 함
+	if flags&FlagCRC32C != 0 {
+		if len(value) < 5 {
+			return ErrInvalidSize
+		}
+		data, sum := value[:len(value)-4], value[len(value)-4:]
+		if crc32.Checksum(data, castagnoli) != binary.BigEndian.Uint32(sum) {
+			return ErrChecksum
+		}
+		value = data
+	}
+
+	if !validFlags(flags) {
+		return fmt.Errorf("%w: %#x", ErrInvalidFlags, flags)
+	}
+
+	body := value[1:]
+
+	var zr io.Reader
+	switch {
+	case flags&FlagDeflate != 0:
+		zr = flate.NewReader(bytes.NewReader(body))
+	case flags&FlagGzip != 0:
+		gr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		zr = gr
+	}
+
+	if zr != nil {
+		// 압축 폭탄 방지: 내부 frame은 헤더(5byte) + 남은 budget을 넘을 수 없음
+		limit := max(*budget, 0) + 5
+		inner, err := io.ReadAll(io.LimitReader(zr, limit+1))
+		if err != nil {
+			return err
+		}
+		if int64(len(inner)) > limit {
+			return ErrMaxPayloadSize
+		}
+		*budget -= int64(len(inner))
+		body = inner
+	}
+
+	r := bytes.NewReader(body)
+	p, err := decodeElement(r, registry, budget, depth+1)
+	if err != nil {
+		return err
+	}
+	if r.Len() != 0 {
+		return ErrInvalidSize
+	}
+
+	m.Flags = flags
+	m.Payload = p
+	return nil
+}
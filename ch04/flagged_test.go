@@ -0,0 +1,183 @@
+package ch04
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFlaggedPayload(t *testing.T) {
+	expected := &Map{
+		"text": ptr(String(bytes.Repeat([]byte("compress me "), 100))),
+		"list": &List{ptr(Uvarint(1)), ptr(Bool(true))},
+	}
+	plain := len(expected.Bytes())
+
+	for _, flags := range []uint8{
+		0,
+		FlagCRC32C,
+		FlagDeflate,
+		FlagGzip,
+		FlagDeflate | FlagCRC32C,
+		FlagGzip | FlagCRC32C,
+	} {
+		var buf bytes.Buffer
+		_, err := (&Flagged{Flags: flags, Payload: expected}).WriteTo(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("flags %03b: %d bytes (plain %d bytes)", flags, buf.Len(), plain)
+
+		if flags&(FlagDeflate|FlagGzip) != 0 && buf.Len() >= plain {
+			t.Errorf("flags %03b: expected compressed frame", flags)
+		}
+
+		// Decoder는 Flagged를 벗겨 내부 payload를 반환
+		actual, err := decode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("flags %03b: value mismatch: %v != %v", flags, expected, actual)
+		}
+	}
+
+	// List의 원소로 들어간 Flagged도 투명하게 디코딩
+	l := &List{&Flagged{Flags: FlagDeflate, Payload: ptr(String("inner"))}}
+	var buf bytes.Buffer
+	_, err := l.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&List{ptr(String("inner"))}, actual) {
+		t.Errorf("value mismatch: %v", actual)
+	}
+}
+
+func TestFlaggedChecksum(t *testing.T) {
+	f := &Flagged{Flags: FlagGzip | FlagCRC32C, Payload: ptr(String("integrity"))}
+	frame := f.Bytes()
+	var buf bytes.Buffer
+	_, err := f.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(frame, buf.Bytes()[5:]) {
+		t.Fatal("Bytes should return the frame value")
+	}
+
+	// 본문, flag, checksum 어디가 손상되어도 ErrChecksum 반환
+	for _, i := range []int{5, 5 + len(frame)/2, buf.Len() - 1} {
+		corrupted := bytes.Clone(buf.Bytes())
+		corrupted[i] ^= 0x01
+
+		_, err := decode(bytes.NewReader(corrupted))
+		if !errors.Is(err, ErrChecksum) {
+			t.Errorf("byte %d: expected ErrChecksum; actual: %v", i, err)
+		}
+	}
+}
+
+func TestFlaggedInvalid(t *testing.T) {
+	s := String("x")
+	_, err := (&Flagged{Flags: FlagDeflate | FlagGzip, Payload: &s}).WriteTo(&bytes.Buffer{})
+	if !errors.Is(err, ErrInvalidFlags) {
+		t.Fatalf("expected ErrInvalidFlags; actual: %v", err)
+	}
+
+	var buf bytes.Buffer
+	_, _ = writeTLV(&buf, FlaggedType, []byte{0x80})
+	_, err = decode(&buf)
+	if !errors.Is(err, ErrInvalidFlags) {
+		t.Fatalf("expected ErrInvalidFlags; actual: %v", err)
+	}
+}
+
+func TestFlaggedDecompressionLimit(t *testing.T) {
+	// 제한 크기의 payload는 압축하여 전송 가능
+	big := make(Binary, MaxPayloadSize)
+	f := &Flagged{Flags: FlagDeflate, Payload: &big}
+	var buf bytes.Buffer
+	_, err := f.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("compressed %d bytes into %d bytes", len(big), buf.Len())
+	if _, err = decode(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// 압축 후에는 작지만 풀면 제한을 넘는 frame은 끝까지 풀지 않고 거부
+	var value bytes.Buffer
+	value.WriteByte(FlagDeflate)
+	zw, _ := flate.NewWriter(&value, flate.BestCompression)
+	_, _ = zw.Write(make([]byte, MaxPayloadSize+10))
+	_ = zw.Close()
+
+	buf.Reset()
+	_, _ = writeTLV(&buf, FlaggedType, value.Bytes())
+	_, err = decode(&buf)
+	if err != ErrMaxPayloadSize {
+		t.Fatalf("expected ErrMaxPayloadSize; actual: %v", err)
+	}
+}
+
+// 원소 하나하나는 제한보다 작아도, frame 전체에서 풀 수 있는 크기는 MaxPayloadSize로 제한
+func TestFlaggedDecompressionBudget(t *testing.T) {
+	chunk := make(Binary, MaxPayloadSize/8)
+	elem := &Flagged{Flags: FlagDeflate, Payload: &chunk}
+
+	for _, tc := range []struct {
+		count    int
+		expected error
+	}{
+		{count: 4},
+		{count: 64, expected: ErrMaxPayloadSize},
+	} {
+		l := make(List, tc.count)
+		for i := range l {
+			l[i] = elem
+		}
+
+		var buf bytes.Buffer
+		_, err := l.WriteTo(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("%d elements: %d bytes", tc.count, buf.Len())
+
+		_, err = decode(&buf)
+		if err != tc.expected {
+			t.Errorf("%d elements: expected %v; actual: %v", tc.count, tc.expected, err)
+		}
+	}
+}
+
+func TestTLVConnFlags(t *testing.T) {
+	client, server := tlvConnPair(t)
+	client.Flags = FlagDeflate | FlagCRC32C
+
+	data := String(bytes.Repeat([]byte("a"), 10000))
+	go func() {
+		if err := client.Send(&data); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	p, err := server.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.String() != string(data) {
+		t.Error("payload mismatch")
+	}
+	if n := server.Stats().BytesReceived; n >= uint64(len(data)) {
+		t.Errorf("expected compressed transfer; actual %d bytes", n)
+	}
+}
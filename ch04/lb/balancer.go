@@ -0,0 +1,111 @@
+package lb
+
+import (
+	"hash/fnv"
+	"net"
+	"sync/atomic"
+)
+
+// 연결을 전달할 upstream 서버
+type Upstream struct {
+	Addr string
+
+	healthy atomic.Bool
+	fails   atomic.Int32
+	active  atomic.Int64
+	total   atomic.Uint64
+}
+
+// 처음에는 health check 전에도 연결을 받을 수 있도록 healthy 상태로 생성
+func NewUpstream(addr string) *Upstream {
+	u := &Upstream{Addr: addr}
+	u.healthy.Store(true)
+	return u
+}
+
+func (u *Upstream) Healthy() bool { return u.healthy.Load() }
+
+// 현재 연결 중이거나 전달 중인 연결 수
+func (u *Upstream) Active() int64 { return u.active.Load() }
+
+// 지금까지 전달한 연결 수
+func (u *Upstream) Total() uint64 { return u.total.Load() }
+
+// 연결을 전달할 upstream을 선택하는 정책
+// upstreams는 비어 있지 않은 healthy upstream 목록이며, 여러 고루틴에서 동시에 호출된다.
+type Balancer interface {
+	Pick(client net.Addr, upstreams []*Upstream) *Upstream
+}
+
+// 순서대로 돌아가며 선택
+type RoundRobin struct {
+	next atomic.Uint64
+}
+
+func (r *RoundRobin) Pick(_ net.Addr, upstreams []*Upstream) *Upstream {
+	n := r.next.Add(1) - 1
+	return upstreams[n%uint64(len(upstreams))]
+}
+
+// 전달 중인 연결이 가장 적은 upstream 선택
+// 연결 수가 같으면 항상 앞의 upstream으로 몰리지 않도록 시작 위치를 돌아가며 비교
+type LeastConn struct {
+	next atomic.Uint64
+}
+
+func (l *LeastConn) Pick(_ net.Addr, upstreams []*Upstream) *Upstream {
+	start := int((l.next.Add(1) - 1) % uint64(len(upstreams)))
+
+	var picked *Upstream
+	for i := range upstreams {
+		u := upstreams[(start+i)%len(upstreams)]
+		if picked == nil || u.Active() < picked.Active() {
+			picked = u
+		}
+	}
+
+	return picked
+}
+
+// client IP를 기준으로 항상 같은 upstream 선택
+//
+// rendezvous(HRW) hashing을 사용하여 (client IP, upstream 주소) 쌍의 hash가 가장 큰 upstream을 선택한다.
+// upstream이 추가되거나 빠져도 해당 upstream에 매핑된 client만 다른 upstream으로 옮겨간다.
+type ConsistentHash struct{}
+
+func (ConsistentHash) Pick(client net.Addr, upstreams []*Upstream) *Upstream {
+	key := clientIP(client)
+
+	var (
+		picked *Upstream
+		best   uint64
+	)
+	for _, u := range upstreams {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(key))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(u.Addr))
+
+		if score := h.Sum64(); picked == nil || score > best {
+			picked, best = u, score
+		}
+	}
+
+	return picked
+}
+
+// 포트를 제외한 client의 IP
+func clientIP(addr net.Addr) string {
+	switch a := addr.(type) {
+	case nil:
+		return ""
+	case *net.TCPAddr:
+		return a.IP.String()
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
@@ -0,0 +1,319 @@
+package lb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/huGgW/network-study-with-go/ch04"
+	"github.com/huGgW/network-study-with-go/ch04/sockopt"
+)
+
+const (
+	defaultDialTimeout    = 3 * time.Second
+	defaultHealthInterval = 5 * time.Second
+	defaultHealthTimeout  = time.Second
+	defaultMaxFails       = 2
+	defaultDrainTimeout   = 30 * time.Second
+)
+
+var (
+	ErrNoUpstream    = errors.New("no healthy upstream")
+	ErrDrainTimeout  = errors.New("drain timeout")
+	errNoUpstreamSet = errors.New("no upstream configured")
+)
+
+// L4(TCP) 로드 밸런서
+//
+// 받은 연결마다 Balancer로 upstream을 골라 연결한 뒤 ch04.Proxy로 양방향 데이터를 전달한다.
+// upstream은 주기적으로 TCP handshake로 상태를 확인(ch04.Ping과 같은 방식)하며,
+// 연속으로 MaxFails번 실패하면 복구될 때까지 선택하지 않는다.
+type Server struct {
+	Upstreams []*Upstream
+	Balancer  Balancer // 기본 RoundRobin
+
+	Dialer         *net.Dialer   // upstream 연결에 사용할 dialer. nil인 경우 zero value
+	DialTimeout    time.Duration // upstream 연결 timeout (기본 3초)
+	HealthInterval time.Duration // health check 주기 (기본 5초). 음수이면 health check를 하지 않음
+	HealthTimeout  time.Duration // health check의 handshake timeout (기본 1초)
+	MaxFails       int           // unhealthy로 판단할 연속 실패 횟수 (기본 2)
+	DrainTimeout   time.Duration // 종료 시 기존 연결이 끝나기를 기다리는 시간 (기본 30초)
+
+	ErrorLog *log.Logger // nil이면 log 패키지의 기본 logger
+
+	SocketOptions sockopt.Options // ListenAndServe의 listen 소켓 옵션
+
+	mu    sync.Mutex
+	conns map[io.Closer]struct{} // drain 시간이 지나면 닫을 연결과 진행 중인 upstream 연결 시도
+	wg    sync.WaitGroup
+}
+
+// 주소 목록으로 Server 생성
+func New(addrs ...string) *Server {
+	s := &Server{}
+	for _, addr := range addrs {
+		s.Upstreams = append(s.Upstreams, NewUpstream(addr))
+	}
+	return s
+}
+
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	l, err := s.SocketOptions.Listen(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("binding to tcp %s: %w", addr, err)
+	}
+
+	return s.Serve(ctx, l)
+}
+
+// l에서 연결을 받아 upstream으로 전달
+//
+// ctx가 취소되면 새 연결을 받지 않고, 기존 연결이 끝나기를 DrainTimeout까지 기다린다.
+// 모든 연결이 끝나면 nil, 시간 안에 끝나지 않아 강제로 닫은 경우 ErrDrainTimeout을 반환한다.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	if len(s.Upstreams) == 0 {
+		return errNoUpstreamSet
+	}
+	if s.Balancer == nil {
+		s.Balancer = &RoundRobin{}
+	}
+
+	sctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		<-sctx.Done() // context를 취소하면 더 이상 연결을 받지 않도록
+		_ = l.Close()
+	}()
+	go s.healthCheck(sctx)
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return s.drain()
+			}
+			return err
+		}
+
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(client net.Conn) {
+	defer s.wg.Done()
+	defer func() { _ = client.Close() }()
+	s.track(client, true)
+	defer s.track(client, false)
+
+	u, server, err := s.dial(client.RemoteAddr())
+	if err != nil {
+		s.logf("%s: %v", client.RemoteAddr(), err)
+		return
+	}
+	defer func() { _ = server.Close() }()
+	defer s.track(server, false)
+	defer u.active.Add(-1)
+	u.total.Add(1)
+
+	_, err = ch04.Proxy(client, server)
+	if err != nil {
+		s.logf("%s <-> %s: %v", client.RemoteAddr(), u.Addr, err)
+	}
+}
+
+// healthy upstream 중 하나에 연결. 연결에 실패하면 다른 upstream으로 재시도
+// 반환한 upstream의 active와 연결은 호출자가 연결을 끝낼 때 되돌려야 한다.
+func (s *Server) dial(client net.Addr) (*Upstream, net.Conn, error) {
+	tried := make(map[*Upstream]bool)
+
+	for {
+		var candidates []*Upstream
+		for _, u := range s.Upstreams {
+			if u.Healthy() && !tried[u] {
+				candidates = append(candidates, u)
+			}
+		}
+		if len(candidates) == 0 {
+			return nil, nil, ErrNoUpstream
+		}
+
+		u := s.Balancer.Pick(client, candidates)
+		tried[u] = true
+
+		// 연결 중에도 LeastConn이 같은 upstream으로 몰리지 않도록, drain이 연결 시도를 기다리도록
+		// 연결 전에 active를 늘리고 연결 시도를 등록
+		u.active.Add(1)
+		ctx, cancel := context.WithTimeout(context.Background(), s.dialTimeout())
+		attempt := &dialAttempt{cancel: cancel}
+		s.track(attempt, true)
+
+		conn, err := s.dialer().DialContext(ctx, "tcp", u.Addr)
+		if err == nil {
+			s.track(conn, true)
+		}
+		s.track(attempt, false)
+		cancel()
+
+		if err != nil {
+			u.active.Add(-1)
+			s.fail(u, err)
+			continue
+		}
+		s.succeed(u)
+
+		return u, conn, nil
+	}
+}
+
+// drain 시간이 지나면 진행 중인 upstream 연결 시도를 취소
+type dialAttempt struct {
+	cancel context.CancelFunc
+}
+
+func (a *dialAttempt) Close() error {
+	a.cancel()
+	return nil
+}
+
+// 새 연결을 받지 않는 상태에서 기존 연결이 끝나기를 기다림
+func (s *Server) drain() error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(s.drainTimeout())
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+	}
+
+	s.mu.Lock()
+	n := len(s.conns)
+	for c := range s.conns {
+		_ = c.Close()
+	}
+	s.mu.Unlock()
+	<-done
+
+	return fmt.Errorf("%w: closed %d connections", ErrDrainTimeout, n)
+}
+
+func (s *Server) track(c io.Closer, add bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conns == nil {
+		s.conns = make(map[io.Closer]struct{})
+	}
+	if add {
+		s.conns[c] = struct{}{}
+	} else {
+		delete(s.conns, c)
+	}
+}
+
+func (s *Server) healthCheck(ctx context.Context) {
+	if s.HealthInterval < 0 {
+		return
+	}
+	interval := s.HealthInterval
+	if interval == 0 {
+		interval = defaultHealthInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		var wg sync.WaitGroup
+		for _, u := range s.Upstreams {
+			wg.Add(1)
+			go func(u *Upstream) {
+				defer wg.Done()
+				s.check(u)
+			}(u)
+		}
+		wg.Wait()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// TCP handshake가 성공하는지 확인
+func (s *Server) check(u *Upstream) {
+	timeout := s.HealthTimeout
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+
+	c, err := net.DialTimeout("tcp", u.Addr, timeout)
+	if err != nil {
+		s.fail(u, err)
+		return
+	}
+	_ = c.Close()
+	s.succeed(u)
+}
+
+func (s *Server) fail(u *Upstream, err error) {
+	maxFails := s.MaxFails
+	if maxFails <= 0 {
+		maxFails = defaultMaxFails
+	}
+
+	if int(u.fails.Add(1)) >= maxFails && u.healthy.Swap(false) {
+		s.logf("upstream %s is down: %v", u.Addr, err)
+	}
+}
+
+func (s *Server) succeed(u *Upstream) {
+	u.fails.Store(0)
+	if !u.healthy.Swap(true) {
+		s.logf("upstream %s is up", u.Addr)
+	}
+}
+
+func (s *Server) dialer() *net.Dialer {
+	if s.Dialer == nil {
+		return new(net.Dialer)
+	}
+	return s.Dialer
+}
+
+func (s *Server) dialTimeout() time.Duration {
+	if s.DialTimeout <= 0 {
+		return defaultDialTimeout
+	}
+	return s.DialTimeout
+}
+
+func (s *Server) drainTimeout() time.Duration {
+	if s.DrainTimeout <= 0 {
+		return defaultDrainTimeout
+	}
+	return s.DrainTimeout
+}
+
+func (s *Server) logf(format string, v ...any) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, v...)
+		return
+	}
+	log.Printf(format, v...)
+}
@@ -0,0 +1,272 @@
+package lb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// 연결되면 자신의 이름을 보내고, 이후 받은 데이터를 그대로 돌려주는 upstream
+func backend(t *testing.T, name string) net.Listener {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = conn.Write([]byte(name))
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	return l
+}
+
+// s를 실행하고 주소와 종료 함수 반환
+func serve(t *testing.T, s *Server) (string, func() error) {
+	t.Helper()
+
+	if s.ErrorLog == nil {
+		s.ErrorLog = log.New(io.Discard, "", 0)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, l) }()
+
+	stop := sync.OnceValue(func() error {
+		cancel()
+		return <-done
+	})
+	t.Cleanup(func() { _ = stop() })
+
+	return l.Addr().String(), stop
+}
+
+// lb에 연결하여 upstream의 이름을 받음
+func hello(t *testing.T, addr string) (net.Conn, string) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	buf := make([]byte, 16)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	return conn, string(buf[:n])
+}
+
+func TestRoundRobin(t *testing.T) {
+	s := New(
+		backend(t, "a").Addr().String(),
+		backend(t, "b").Addr().String(),
+		backend(t, "c").Addr().String(),
+	)
+	addr, stop := serve(t, s)
+	defer stop()
+
+	actual := ""
+	for i := 0; i < 6; i++ {
+		conn, name := hello(t, addr)
+		_ = conn.Close()
+		actual += name
+	}
+	if actual != "abcabc" {
+		t.Errorf("expected %q; actual %q", "abcabc", actual)
+	}
+}
+
+func TestLeastConn(t *testing.T) {
+	s := New(
+		backend(t, "a").Addr().String(),
+		backend(t, "b").Addr().String(),
+	)
+	s.Balancer = &LeastConn{}
+	addr, stop := serve(t, s)
+	defer stop()
+
+	// 연결을 열어둔 upstream은 선택하지 않음
+	held, first := hello(t, addr)
+	defer held.Close()
+	for i := 0; i < 3; i++ {
+		conn, name := hello(t, addr)
+		if name == first {
+			t.Errorf("expected upstream other than %q; actual %q", first, name)
+		}
+		_ = conn.Close()
+
+		// 닫은 연결이 반영될 때까지 대기
+		for s.Upstreams[0].Active()+s.Upstreams[1].Active() != 1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+// upstream에 연결하는 동안에도 active와 drain 대상에 포함
+func TestDialTracked(t *testing.T) {
+	s := New(backend(t, "a").Addr().String())
+
+	type observed struct {
+		active  int64
+		tracked int
+	}
+	dialing := make(chan observed, 1)
+	s.Dialer = &net.Dialer{
+		Control: func(string, string, syscall.RawConn) error {
+			s.mu.Lock()
+			tracked := len(s.conns)
+			s.mu.Unlock()
+			dialing <- observed{active: s.Upstreams[0].Active(), tracked: tracked}
+			return nil
+		},
+	}
+	addr, stop := serve(t, s)
+	defer stop()
+
+	conn, _ := hello(t, addr)
+	defer conn.Close()
+
+	// client 연결과 upstream 연결 시도
+	if o := <-dialing; o.active != 1 || o.tracked != 2 {
+		t.Errorf("expected active 1, tracked 2 while dialing; actual %+v", o)
+	}
+}
+
+func TestConsistentHash(t *testing.T) {
+	upstreams := []*Upstream{
+		NewUpstream("10.0.0.1:80"),
+		NewUpstream("10.0.0.2:80"),
+		NewUpstream("10.0.0.3:80"),
+		NewUpstream("10.0.0.4:80"),
+	}
+	var b ConsistentHash
+
+	moved := 0
+	for i := 0; i < 256; i++ {
+		client := &net.TCPAddr{IP: net.IPv4(192, 168, 0, byte(i)), Port: 1000 + i}
+
+		picked := b.Pick(client, upstreams)
+		// 같은 IP는 포트가 달라도 같은 upstream
+		again := b.Pick(&net.TCPAddr{IP: client.IP, Port: 9999}, upstreams)
+		if picked != again {
+			t.Fatalf("%s: expected %s; actual %s", client.IP, picked.Addr, again.Addr)
+		}
+
+		// upstream 하나를 빼도 해당 upstream에 매핑된 client만 이동
+		after := b.Pick(client, upstreams[1:])
+		if picked != upstreams[0] && after != picked {
+			t.Fatalf("%s: moved from %s to %s", client.IP, picked.Addr, after.Addr)
+		}
+		if picked == upstreams[0] {
+			moved++
+		}
+	}
+	t.Logf("%d/256 clients moved", moved)
+}
+
+func TestHealthCheck(t *testing.T) {
+	down := backend(t, "down")
+	s := New(down.Addr().String(), backend(t, "up").Addr().String())
+	s.HealthInterval = 10 * time.Millisecond
+	s.MaxFails = 1
+	_ = down.Close()
+
+	addr, stop := serve(t, s)
+	defer stop()
+
+	// 연결에 실패해도 다른 upstream으로 재시도하므로 모든 연결은 성공
+	for i := 0; i < 4; i++ {
+		conn, name := hello(t, addr)
+		if name != "up" {
+			t.Errorf("expected %q; actual %q", "up", name)
+		}
+		_ = conn.Close()
+	}
+	if s.Upstreams[0].Healthy() {
+		t.Error("expected upstream to be marked down")
+	}
+}
+
+func TestDrain(t *testing.T) {
+	s := New(backend(t, "a").Addr().String())
+	s.DrainTimeout = time.Second
+	addr, stop := serve(t, s)
+
+	conn, _ := hello(t, addr)
+
+	stopped := make(chan error)
+	go func() { stopped <- stop() }()
+
+	// 종료 중에도 기존 연결은 동작
+	time.Sleep(50 * time.Millisecond)
+	_, err := conn.Write([]byte("ping"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 새 연결은 받지 않음
+	if c, err := net.Dial("tcp", addr); err == nil {
+		_ = c.Close()
+		t.Error("expected new connections to be refused")
+	}
+
+	_ = conn.Close()
+	if err := <-stopped; err != nil {
+		t.Fatalf("expected clean drain; actual: %v", err)
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	s := New(backend(t, "a").Addr().String())
+	s.DrainTimeout = 50 * time.Millisecond
+	addr, stop := serve(t, s)
+
+	conn, _ := hello(t, addr)
+
+	err := stop()
+	if !errors.Is(err, ErrDrainTimeout) {
+		t.Fatalf("expected ErrDrainTimeout; actual: %v", err)
+	}
+
+	// 남아 있던 연결은 강제로 닫힘
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	if err != io.EOF {
+		t.Errorf("expected EOF; actual: %v", err)
+	}
+}
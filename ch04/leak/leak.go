@@ -0,0 +1,262 @@
+// 닫히지 않은 연결(CLOSE_WAIT) 탐지
+//
+// ch04.CloseWaitStuckErr처럼 Close를 잊으면 상대가 연결을 닫아도 소켓은 CLOSE_WAIT에 남아
+// file descriptor를 계속 차지한다. Tracker는 listener가 받은 연결을 Close될 때까지 기록하고,
+// 커널의 TCP 상태를 확인하여 상대는 닫았지만 서버가 닫지 않은 연결을 생성 위치와 함께 보고한다.
+package leak
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/huGgW/network-study-with-go/ch04"
+	"github.com/huGgW/network-study-with-go/ch04/tcpinfo"
+)
+
+// 받은 연결을 Close될 때까지 추적
+//
+// 추적 중인 연결은 Tracker가 참조하므로, 참조를 잃은 연결을 GC가 닫아주지도 않는다.
+// 따라서 Close를 잊은 연결은 반드시 Report에 남는다.
+type Tracker struct {
+	mu     sync.Mutex
+	conns  map[*Conn]struct{}
+	nextID atomic.Uint64
+}
+
+// l이 받은 연결을 추적하는 listener 반환
+func (t *Tracker) Listener(l net.Listener) net.Listener {
+	return &listener{Listener: l, tracker: t}
+}
+
+// conn을 추적. Listener를 사용할 수 없는 경우(Dial한 연결 등) 직접 호출
+// skip은 생성 위치로 기록할 stack에서 건너뛸 호출자 수 (0이면 Track을 호출한 함수부터)
+func (t *Tracker) Track(conn net.Conn, skip int) *Conn {
+	c := &Conn{
+		Conn:    conn,
+		id:      t.nextID.Add(1),
+		created: time.Now(),
+		tracker: t,
+	}
+	n := runtime.Callers(skip+2, c.stack[:])
+	c.pcs = c.stack[:n]
+
+	t.mu.Lock()
+	if t.conns == nil {
+		t.conns = make(map[*Conn]struct{})
+	}
+	t.conns[c] = struct{}{}
+	t.mu.Unlock()
+
+	return c
+}
+
+func (t *Tracker) remove(c *Conn) {
+	t.mu.Lock()
+	delete(t.conns, c)
+	t.mu.Unlock()
+}
+
+// 추적 중인 연결 수
+func (t *Tracker) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.conns)
+}
+
+// 추적 중인 모든 연결의 상태를 오래된 순서로 반환
+func (t *Tracker) Report() []ConnInfo {
+	t.mu.Lock()
+	conns := make([]*Conn, 0, len(t.conns))
+	for c := range t.conns {
+		conns = append(conns, c)
+	}
+	t.mu.Unlock()
+
+	slices.SortFunc(conns, func(a, b *Conn) int { return cmp.Compare(a.id, b.id) })
+
+	now := time.Now()
+	infos := make([]ConnInfo, len(conns))
+	for i, c := range conns {
+		infos[i] = c.info(now)
+	}
+	return infos
+}
+
+// 상대가 닫았지만 아직 Close하지 않은 연결 중 minAge보다 오래된 연결
+// 상대가 닫은 직후에는 서버가 정상적으로 Close하는 중일 수 있으므로 minAge로 걸러낸다.
+func (t *Tracker) Leaks(minAge time.Duration) []ConnInfo {
+	var leaks []ConnInfo
+	for _, info := range t.Report() {
+		if info.PeerClosed && info.Age >= minAge {
+			leaks = append(leaks, info)
+		}
+	}
+	return leaks
+}
+
+// Report를 사람이 읽을 수 있는 형태로 출력. 상대가 닫은 연결은 생성 stack도 출력
+func (t *Tracker) WriteReport(w io.Writer) error {
+	infos := t.Report()
+	leaks := 0
+	for _, info := range infos {
+		if info.PeerClosed {
+			leaks++
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%d tracked connections, %d closed by peer\n", len(infos), leaks)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		_, err = fmt.Fprintf(w, "\n#%d %s->%s %s age=%s\n",
+			info.ID, info.Local, info.Remote, info.State, info.Age.Round(time.Millisecond))
+		if err != nil {
+			return err
+		}
+		if info.Err != "" {
+			_, err = fmt.Fprintf(w, "\terror: %s\n", info.Err)
+		} else if info.PeerClosed {
+			_, err = io.WriteString(w, info.Stack)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Report를 JSON으로 반환하는 handler. ?leaks=<duration>이면 Leaks(duration)만 반환
+func (t *Tracker) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		infos := t.Report()
+		if s := r.URL.Query().Get("leaks"); s != "" {
+			minAge, err := time.ParseDuration(s)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			infos = t.Leaks(minAge)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(infos)
+	})
+}
+
+// 연결 하나의 상태
+type ConnInfo struct {
+	ID         uint64        `json:"id"`
+	Local      string        `json:"local"`
+	Remote     string        `json:"remote"`
+	Created    time.Time     `json:"created"`
+	Age        time.Duration `json:"age_ns"`
+	State      string        `json:"state"`
+	PeerClosed bool          `json:"peer_closed"` // 상대가 FIN을 보냈지만 Close하지 않은 상태 (CLOSE_WAIT)
+	Stack      string        `json:"stack"`       // 연결을 받은 위치
+	Err        string        `json:"error,omitempty"`
+}
+
+// 추적 중인 연결. Close하면 Tracker에서 제거됨
+type Conn struct {
+	net.Conn
+
+	id      uint64
+	created time.Time
+	stack   [32]uintptr
+	pcs     []uintptr
+	tracker *Tracker
+	once    sync.Once
+}
+
+func (c *Conn) Close() error {
+	c.once.Do(func() { c.tracker.remove(c) })
+	return c.Conn.Close()
+}
+
+// half-close는 연결을 닫지 않으므로 Close할 때까지 계속 추적
+func (c *Conn) CloseWrite() error {
+	return ch04.CloseWrite(c.Conn)
+}
+
+// 생성 위치의 stack
+func (c *Conn) Stack() string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(c.pcs)
+	for {
+		f, more := frames.Next()
+		fmt.Fprintf(&b, "\t%s\n\t\t%s:%d\n", f.Function, f.File, f.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+func (c *Conn) info(now time.Time) ConnInfo {
+	info := ConnInfo{
+		ID:      c.id,
+		Local:   c.LocalAddr().String(),
+		Remote:  c.RemoteAddr().String(),
+		Created: c.created,
+		Age:     now.Sub(c.created),
+		Stack:   c.Stack(),
+	}
+
+	state, err := c.state()
+	if err != nil {
+		info.Err = err.Error()
+		return info
+	}
+	info.State = state.String()
+	// FIN을 받은 뒤 Close하지 않은 상태. CLOSING, LAST_ACK는 이미 Close한 상태
+	info.PeerClosed = state == tcpinfo.CloseWait
+
+	return info
+}
+
+// TCP_INFO로 상태를 확인하고, *net.TCPConn이 아니거나 지원되지 않으면 /proc/net/tcp에서 찾음
+func (c *Conn) state() (tcpinfo.State, error) {
+	if tc, ok := c.Conn.(*net.TCPConn); ok {
+		info, err := tcpinfo.Get(tc)
+		if err == nil {
+			return info.State, nil
+		}
+		if err != tcpinfo.ErrUnsupported {
+			return 0, err
+		}
+	}
+
+	local, lok := c.LocalAddr().(*net.TCPAddr)
+	remote, rok := c.RemoteAddr().(*net.TCPAddr)
+	if !lok || !rok {
+		return 0, fmt.Errorf("not a TCP connection: %s", c.LocalAddr().Network())
+	}
+	return procState(local, remote)
+}
+
+type listener struct {
+	net.Listener
+	tracker *Tracker
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	// Accept를 호출한 함수부터 기록
+	return l.tracker.Track(conn, 1), nil
+}
@@ -0,0 +1,179 @@
+package leak
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/huGgW/network-study-with-go/ch04/tcpinfo"
+)
+
+func TestTrackerCloseWait(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	tracker := new(Tracker)
+	tl := tracker.Listener(l)
+
+	accepted := make(chan net.Conn)
+	go func() {
+		for {
+			conn, err := tl.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	dial := func() (client, server net.Conn) {
+		client, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client, <-accepted
+	}
+
+	// 정상적으로 Close하는 연결과 Close를 잊은 연결
+	closedClient, closedServer := dial()
+	leakedClient, leakedServer := dial()
+	openClient, openServer := dial()
+	defer openClient.Close()
+	defer openServer.Close()
+
+	if n := tracker.Len(); n != 3 {
+		t.Fatalf("expected 3 tracked; actual %d", n)
+	}
+
+	_ = closedClient.Close()
+	_ = closedServer.Close()
+	_ = leakedClient.Close() // 서버는 leakedServer를 닫지 않음
+	defer leakedServer.Close()
+
+	var leaks []ConnInfo
+	deadline := time.Now().Add(time.Second)
+	for len(leaks) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		leaks = tracker.Leaks(0)
+	}
+
+	if len(leaks) != 1 {
+		t.Fatalf("expected 1 leak; actual %d", len(leaks))
+	}
+	leak := leaks[0]
+	if leak.State != "CLOSE_WAIT" || leak.Remote != leakedClient.LocalAddr().String() {
+		t.Errorf("unexpected leak %+v", leak)
+	}
+	// 생성 위치는 Accept를 호출한 고루틴
+	if !strings.Contains(leak.Stack, "TestTrackerCloseWait.func1") {
+		t.Errorf("expected stack from accept loop; actual\n%s", leak.Stack)
+	}
+
+	if n := tracker.Len(); n != 2 {
+		t.Errorf("expected 2 tracked after close; actual %d", n)
+	}
+	if leaks := tracker.Leaks(time.Hour); len(leaks) != 0 {
+		t.Errorf("expected no leaks older than an hour; actual %d", len(leaks))
+	}
+
+	report := new(strings.Builder)
+	err = tracker.WriteReport(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(report.String(), "2 tracked connections, 1 closed by peer\n") ||
+		!strings.Contains(report.String(), "ESTABLISHED") ||
+		!strings.Contains(report.String(), "CLOSE_WAIT") {
+		t.Errorf("unexpected report\n%s", report)
+	}
+
+	rec := httptest.NewRecorder()
+	tracker.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/?leaks=0s", nil))
+	var infos []ConnInfo
+	err = json.Unmarshal(rec.Body.Bytes(), &infos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || !infos[0].PeerClosed {
+		t.Errorf("expected 1 leak in JSON; actual %s", rec.Body)
+	}
+}
+
+func TestParseProcAddr(t *testing.T) {
+	for s, expected := range map[string]string{
+		"0100007F:1F90":                         "127.0.0.1:8080",
+		"00000000000000000000000001000000:0016": "[::1]:22",
+		"0000000000000000FFFF00000100007F:01BB": "[::ffff:127.0.0.1]:443",
+	} {
+		ap, err := parseProcAddr(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ap.String() != expected {
+			t.Errorf("%s: expected %s; actual %s", s, expected, ap)
+		}
+	}
+
+	for _, s := range []string{"0100007F", "01007F:0016", "ZZ00007F:0016", "0100007F:FFFFF"} {
+		if _, err := parseProcAddr(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
+
+func TestFindState(t *testing.T) {
+	const procTCP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 1 1 0 100 0 0 10 0
+   1: 0100007F:1F90 0100007F:D431 08 00000000:00000000 00:00000000 00000000  1000        0 2 1 0 20 4 30 10 -1
+`
+	local := netip.MustParseAddrPort("127.0.0.1:8080")
+	remote := netip.MustParseAddrPort("127.0.0.1:54321")
+
+	state, err := findState(bufio.NewScanner(strings.NewReader(procTCP)), local, remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != tcpinfo.CloseWait {
+		t.Errorf("expected CLOSE_WAIT; actual %s", state)
+	}
+
+	_, err = findState(bufio.NewScanner(strings.NewReader(procTCP)), local, netip.MustParseAddrPort("127.0.0.1:1"))
+	if err != errNotFound {
+		t.Errorf("expected errNotFound; actual %v", err)
+	}
+}
+
+func TestProcState(t *testing.T) {
+	if _, err := os.Stat(procFiles[0]); err != nil {
+		t.Skip(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	state, err := procState(conn.LocalAddr().(*net.TCPAddr), conn.RemoteAddr().(*net.TCPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != tcpinfo.Established {
+		t.Errorf("expected ESTABLISHED; actual %s", state)
+	}
+}
@@ -0,0 +1,103 @@
+package leak
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/huGgW/network-study-with-go/ch04/tcpinfo"
+)
+
+var errNotFound = errors.New("socket not found in /proc/net/tcp")
+
+// IPv4, IPv6 소켓 목록
+var procFiles = []string{"/proc/net/tcp", "/proc/net/tcp6"}
+
+// /proc/net/tcp, tcp6에서 local, remote 주소가 같은 소켓의 상태를 찾음
+func procState(local, remote *net.TCPAddr) (tcpinfo.State, error) {
+	l, r := local.AddrPort(), remote.AddrPort()
+
+	for _, name := range procFiles {
+		f, err := os.Open(name)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return 0, err
+		}
+		state, err := findState(bufio.NewScanner(f), l, r)
+		_ = f.Close()
+		if !errors.Is(err, errNotFound) {
+			return state, err
+		}
+	}
+
+	return 0, errNotFound
+}
+
+// "  sl  local_address rem_address   st ..." 형식의 줄에서 주소가 같은 소켓 검색
+func findState(s *bufio.Scanner, local, remote netip.AddrPort) (tcpinfo.State, error) {
+	s.Scan() // header
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		l, err := parseProcAddr(fields[1])
+		if err != nil {
+			return 0, err
+		}
+		r, err := parseProcAddr(fields[2])
+		if err != nil {
+			return 0, err
+		}
+		// tcp6 파일의 IPv4 연결은 ::ffff:a.b.c.d로 표시됨
+		if unmap(l) != unmap(local) || unmap(r) != unmap(remote) {
+			continue
+		}
+
+		st, err := strconv.ParseUint(fields[3], 16, 8)
+		if err != nil {
+			return 0, fmt.Errorf("invalid state %q: %w", fields[3], err)
+		}
+		return tcpinfo.State(st), nil
+	}
+	if err := s.Err(); err != nil {
+		return 0, err
+	}
+	return 0, errNotFound
+}
+
+func unmap(ap netip.AddrPort) netip.AddrPort {
+	return netip.AddrPortFrom(ap.Addr().Unmap(), ap.Port())
+}
+
+// "0100007F:1F90"와 같은 주소. IP는 32bit 단위로 host byte order(little endian), 포트는 big endian
+func parseProcAddr(s string) (netip.AddrPort, error) {
+	ipHex, portHex, ok := strings.Cut(s, ":")
+	if !ok {
+		return netip.AddrPort{}, fmt.Errorf("invalid address %q", s)
+	}
+	b, err := hex.DecodeString(ipHex)
+	if err != nil || (len(b) != 4 && len(b) != 16) {
+		return netip.AddrPort{}, fmt.Errorf("invalid address %q", s)
+	}
+	port, err := strconv.ParseUint(portHex, 16, 16)
+	if err != nil {
+		return netip.AddrPort{}, fmt.Errorf("invalid port %q", s)
+	}
+
+	for i := 0; i < len(b); i += 4 {
+		binary.BigEndian.PutUint32(b[i:], binary.LittleEndian.Uint32(b[i:]))
+	}
+	addr, _ := netip.AddrFromSlice(b)
+
+	return netip.AddrPortFrom(addr, uint16(port)), nil
+}
@@ -0,0 +1,21 @@
+package ch04
+
+import "log"
+
+// log.Logger를 embedding하여 네트워크 트래픽 로깅
+type Monitor struct {
+	*log.Logger
+}
+
+// Implements io.Writer interface
+func (m *Monitor) Write(p []byte) (int, error) {
+	// return len(p), m.Output(2, string(p))
+
+	// TeeReader, MultiWriter의 경우 writer에서 오류가 나면 전체 reader/writer도 오류 발생
+	// 따라서 로깅에 의해 주요 로직이 영향받지 않도록 하려면 로깅 에러는 내부에서 처리하는 방식이 적절
+	err := m.Output(2, string(p))
+	if err != nil {
+		log.Println(err)
+	}
+	return len(p), nil
+}
@@ -1,67 +1,314 @@
 package ch04
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"math"
 	"net"
 	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
 	"time"
 )
 
-var (
-	count = flag.Int("c", 3, "number of pings: <= 0 means forever")
-	interval = flag.Duration("i", time.Second, "interval between pings")
-	timeout = flag.Duration("W", 5 * time.Second, "time to wait for a reply")
-)
-
-func init() {
-	flag.Usage = func() {
-		fmt.Printf("Usage: %s [options] host:port\nOptions:\n", os.Args[0])
-		flag.PrintDefaults()
-	}
-}
-
 // ICMP가 필터링된 상황에서 TCP의 Handshake 요청을 사용하여 원격 시스템의 상태 확인
 // 원격 시스템의 포트를 소진, TCP는 ICMP에 비해 오버헤드가 크므로 유의 필요
+//
+// 여러 host:port를 동시에 확인하며, 종료 시 (CTRL+C 포함) 대상별 통계를 출력한다.
 func Ping() {
-	flag.Parse()
+	// 패키지를 import하는 다른 프로그램의 flag에 영향을 주지 않도록 별도의 FlagSet 사용
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	count := fs.Int("c", 3, "number of pings: <= 0 means forever")
+	interval := fs.Duration("i", time.Second, "interval between pings")
+	timeout := fs.Duration("W", 5*time.Second, "time to wait for a reply")
+	keepGoing := fs.Bool("k", false, "keep going on errors other than timeouts (e.g. connection refused)")
+	jsonOutput := fs.Bool("json", false, "print results as JSON lines")
+	fs.Usage = func() {
+		fmt.Printf("Usage: %s [options] host:port [host:port ...]\nOptions:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(os.Args[1:])
 
-	if flag.NArg() != 1 {
+	if fs.NArg() == 0 {
 		fmt.Print("host:port is required\n\n")
-		flag.Usage()
+		fs.Usage()
 		os.Exit(1)
 	}
 
-	target := flag.Arg(0)
-	fmt.Println("PING", target)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	p := TCPPing{Count: *count, Interval: *interval, Timeout: *timeout, KeepGoing: *keepGoing}
+	out := newPingPrinter(os.Stdout, fs.Args(), *jsonOutput)
+
+	if !*jsonOutput {
+		for _, target := range fs.Args() {
+			fmt.Println("PING", target)
+		}
+		if *count <= 0 {
+			fmt.Println("CTRL+C to stop.")
+		}
+	}
+
+	stats := p.Run(ctx, fs.Args(), out.result)
 
-	if *count <= 0 {
-		fmt.Println("CTRL+C to stop.")
+	failed := false
+	for _, s := range stats {
+		out.summary(s)
+		failed = failed || s.Received == 0
+	}
+	if failed {
+		os.Exit(1)
 	}
+}
 
-	msg := 0
+// TCP handshake로 대상의 상태를 확인하는 설정
+type TCPPing struct {
+	Count     int           // 대상별 시도 횟수. 0 이하이면 ctx가 취소될 때까지
+	Interval  time.Duration // 시도 간격
+	Timeout   time.Duration // handshake timeout
+	KeepGoing bool          // timeout이 아닌 에러(연결 거부 등)에도 계속 시도
+}
 
-	for (*count <= 0) || (msg < *count) {
-		msg++
-		fmt.Print(msg, " ")
+// 한 번의 시도 결과
+type PingResult struct {
+	Target string
+	Seq    int
+	RTT    time.Duration // Handshake가 끝나는데 걸리는 시간
+	Err    error
+}
+
+// 대상 하나에 한 번 연결을 시도
+func (p TCPPing) Probe(ctx context.Context, target string, seq int) PingResult {
+	d := net.Dialer{Timeout: p.Timeout}
+
+	start := time.Now()
+	// TCP 연결 시도, 호스트 응답 없음을 대비하여 timeout 설정
+	c, err := d.DialContext(ctx, "tcp", target)
+	r := PingResult{Target: target, Seq: seq, RTT: time.Since(start), Err: err}
+	if err == nil {
+		_ = c.Close()
+	}
+
+	return r
+}
 
-		start := time.Now()
-		// TCP 연결 시도, 호스트 응답 없음을 대비하여 timeout 설정
-		c, err := net.DialTimeout("tcp", target, *timeout)
-		dur := time.Since(start) // Handshake가 끝나는데 걸리는 시간 측정
+// targets를 동시에 확인하며 매 시도마다 report 호출, 대상별 통계를 targets 순서로 반환
+// report는 여러 고루틴에서 호출되므로 동시성에 안전해야 한다.
+func (p TCPPing) Run(ctx context.Context, targets []string, report func(PingResult)) []*PingStats {
+	stats := make([]*PingStats, len(targets))
 
-		if err != nil {
-			fmt.Printf("fail in %s: %v\n", dur, err)
-			// timeout일 경우 재시도, 아니면 프로그램 종료
-			// TCP 재시작 후 상태를 모니터링 하는 경우 유용
-			if nErr, ok := err.(net.Error); !ok || !nErr.Timeout() {
-				os.Exit(1)
-			}
-		} else {
-			_ = c.Close()
-			fmt.Println(dur)
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		stats[i] = &PingStats{Target: target}
+
+		wg.Add(1)
+		go func(s *PingStats) {
+			defer wg.Done()
+			p.run(ctx, s, report)
+		}(stats[i])
+	}
+	wg.Wait()
+
+	return stats
+}
+
+func (p TCPPing) run(ctx context.Context, s *PingStats, report func(PingResult)) {
+	for seq := 1; p.Count <= 0 || seq <= p.Count; seq++ {
+		r := p.Probe(ctx, s.Target, seq)
+		if ctx.Err() != nil {
+			// 중단으로 실패한 시도는 통계에 포함하지 않음
+			return
+		}
+
+		s.add(r)
+		if report != nil {
+			report(r)
+		}
+
+		// timeout일 경우 재시도, 아니면 해당 대상은 중단
+		// TCP 재시작 후 상태를 모니터링 하는 경우 KeepGoing이 유용
+		if r.Err != nil && !p.KeepGoing && !isTimeout(r.Err) {
+			return
+		}
+
+		if p.Count > 0 && seq == p.Count {
+			return
 		}
 
-		time.Sleep(*interval)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.Interval):
+		}
+	}
+}
+
+// 대상별 통계
+type PingStats struct {
+	Target   string
+	Sent     int
+	Received int
+	RTTs     []time.Duration // 성공한 시도의 RTT
+}
+
+func (s *PingStats) add(r PingResult) {
+	s.Sent++
+	if r.Err == nil {
+		s.Received++
+		s.RTTs = append(s.RTTs, r.RTT)
 	}
-}
\ No newline at end of file
+}
+
+// 손실률 (%)
+func (s *PingStats) Loss() float64 {
+	if s.Sent == 0 {
+		return 0
+	}
+	return float64(s.Sent-s.Received) / float64(s.Sent) * 100
+}
+
+func (s *PingStats) Min() time.Duration {
+	if len(s.RTTs) == 0 {
+		return 0
+	}
+	return slices.Min(s.RTTs)
+}
+
+func (s *PingStats) Max() time.Duration {
+	if len(s.RTTs) == 0 {
+		return 0
+	}
+	return slices.Max(s.RTTs)
+}
+
+func (s *PingStats) Avg() time.Duration {
+	if len(s.RTTs) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, rtt := range s.RTTs {
+		sum += rtt
+	}
+	return sum / time.Duration(len(s.RTTs))
+}
+
+// ping과 같은 방식의 평균 편차: sqrt(E[rtt^2] - E[rtt]^2)
+func (s *PingStats) Mdev() time.Duration {
+	if len(s.RTTs) == 0 {
+		return 0
+	}
+	var sum, sum2 float64
+	for _, rtt := range s.RTTs {
+		sum += float64(rtt)
+		sum2 += float64(rtt) * float64(rtt)
+	}
+	n := float64(len(s.RTTs))
+	avg := sum / n
+	return time.Duration(math.Sqrt(max(sum2/n-avg*avg, 0)))
+}
+
+// nearest-rank 방식의 백분위수 (0 < p <= 100)
+func (s *PingStats) Percentile(p float64) time.Duration {
+	if len(s.RTTs) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(s.RTTs)
+	slices.Sort(sorted)
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// 여러 대상의 결과를 섞이지 않게 출력
+type pingPrinter struct {
+	mu    sync.Mutex
+	w     io.Writer
+	json  *json.Encoder
+	width int // 대상 이름을 정렬하기 위한 최대 길이
+}
+
+func newPingPrinter(w io.Writer, targets []string, jsonOutput bool) *pingPrinter {
+	p := &pingPrinter{w: w}
+	if jsonOutput {
+		p.json = json.NewEncoder(w)
+	}
+	for _, t := range targets {
+		p.width = max(p.width, len(t))
+	}
+	return p
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func (p *pingPrinter) result(r PingResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.json != nil {
+		v := map[string]any{
+			"type":   "probe",
+			"time":   time.Now().Format(time.RFC3339Nano),
+			"target": r.Target,
+			"seq":    r.Seq,
+			"rtt_ms": ms(r.RTT),
+		}
+		if r.Err != nil {
+			v["error"] = r.Err.Error()
+			v["timeout"] = isTimeout(r.Err)
+		}
+		_ = p.json.Encode(v)
+		return
+	}
+
+	if r.Err != nil {
+		fmt.Fprintf(p.w, "%-*s  seq=%-4d fail in %s: %v\n", p.width, r.Target, r.Seq, r.RTT, r.Err)
+		return
+	}
+	fmt.Fprintf(p.w, "%-*s  seq=%-4d time=%s\n", p.width, r.Target, r.Seq, r.RTT)
+}
+
+func (p *pingPrinter) summary(s *PingStats) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.json != nil {
+		_ = p.json.Encode(map[string]any{
+			"type":     "summary",
+			"target":   s.Target,
+			"sent":     s.Sent,
+			"received": s.Received,
+			"loss":     s.Loss(),
+			"min_ms":   ms(s.Min()),
+			"avg_ms":   ms(s.Avg()),
+			"max_ms":   ms(s.Max()),
+			"mdev_ms":  ms(s.Mdev()),
+			"p50_ms":   ms(s.Percentile(50)),
+			"p90_ms":   ms(s.Percentile(90)),
+			"p99_ms":   ms(s.Percentile(99)),
+		})
+		return
+	}
+
+	fmt.Fprintf(p.w, "\n--- %s tcp ping statistics ---\n", s.Target)
+	fmt.Fprintf(p.w, "%d sent, %d received, %.1f%% loss\n", s.Sent, s.Received, s.Loss())
+	if s.Received > 0 {
+		fmt.Fprintf(p.w, "rtt min/avg/max/mdev = %.3f/%.3f/%.3f/%.3f ms\n",
+			ms(s.Min()), ms(s.Avg()), ms(s.Max()), ms(s.Mdev()))
+		fmt.Fprintf(p.w, "rtt p50/p90/p99 = %.3f/%.3f/%.3f ms\n",
+			ms(s.Percentile(50)), ms(s.Percentile(90)), ms(s.Percentile(99)))
+	}
+}
+
+func isTimeout(err error) bool {
+	var nErr net.Error
+	return errors.As(err, &nErr) && nErr.Timeout()
+}
@@ -0,0 +1,111 @@
+package ch04
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPingStats(t *testing.T) {
+	s := &PingStats{Target: "test"}
+	for i := 1; i <= 10; i++ {
+		s.add(PingResult{RTT: time.Duration(i) * time.Millisecond})
+	}
+	s.add(PingResult{Err: context.DeadlineExceeded})
+
+	if s.Sent != 11 || s.Received != 10 {
+		t.Errorf("expected 11 sent, 10 received; actual %d, %d", s.Sent, s.Received)
+	}
+	for _, c := range []struct {
+		name             string
+		expected, actual time.Duration
+	}{
+		{"min", time.Millisecond, s.Min()},
+		{"max", 10 * time.Millisecond, s.Max()},
+		{"avg", 5500 * time.Microsecond, s.Avg()},
+		{"p50", 5 * time.Millisecond, s.Percentile(50)},
+		{"p90", 9 * time.Millisecond, s.Percentile(90)},
+		{"p99", 10 * time.Millisecond, s.Percentile(99)},
+	} {
+		if c.expected != c.actual {
+			t.Errorf("%s: expected %s; actual %s", c.name, c.expected, c.actual)
+		}
+	}
+
+	// 1~10의 표준편차는 약 2.872
+	if mdev := s.Mdev(); mdev < 2870*time.Microsecond || mdev > 2875*time.Microsecond {
+		t.Errorf("expected mdev about 2.872ms; actual %s", mdev)
+	}
+	if loss := s.Loss(); loss < 9.09 || loss > 9.1 {
+		t.Errorf("expected 9.09%% loss; actual %.2f", loss)
+	}
+}
+
+func TestTCPPingRun(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// 닫힌 포트로 연결하면 연결 거부
+	closed, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refused := closed.Addr().String()
+	_ = closed.Close()
+
+	targets := []string{listener.Addr().String(), refused}
+
+	for _, keepGoing := range []bool{false, true} {
+		p := TCPPing{Count: 3, Interval: 10 * time.Millisecond, Timeout: time.Second, KeepGoing: keepGoing}
+
+		var out bytes.Buffer
+		printer := newPingPrinter(&out, targets, true)
+		stats := p.Run(context.Background(), targets, printer.result)
+
+		if s := stats[0]; s.Sent != 3 || s.Received != 3 {
+			t.Errorf("expected 3/3 for listening target; actual %d/%d", s.Received, s.Sent)
+		}
+
+		// KeepGoing이 아니면 연결 거부시 해당 대상만 중단
+		expected := 1
+		if keepGoing {
+			expected = 3
+		}
+		if s := stats[1]; s.Sent != expected || s.Received != 0 {
+			t.Errorf("keepGoing=%t: expected 0/%d for refused target; actual %d/%d",
+				keepGoing, expected, s.Received, s.Sent)
+		}
+
+		// 각 줄은 JSON 객체
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != 3+expected {
+			t.Errorf("expected %d lines; actual %d", 3+expected, len(lines))
+		}
+		for _, line := range lines {
+			var v map[string]any
+			if err := json.Unmarshal([]byte(line), &v); err != nil {
+				t.Fatalf("%q: %v", line, err)
+			}
+		}
+	}
+}
+
+func TestPingPrinterAligned(t *testing.T) {
+	var out bytes.Buffer
+	p := newPingPrinter(&out, []string{"a:1", "longer:1"}, false)
+	p.result(PingResult{Target: "a:1", Seq: 1, RTT: time.Millisecond})
+	p.result(PingResult{Target: "longer:1", Seq: 1, RTT: time.Millisecond})
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	t.Log("\n" + out.String())
+	if strings.Index(lines[0], "seq=") != strings.Index(lines[1], "seq=") {
+		t.Error("expected aligned output")
+	}
+}
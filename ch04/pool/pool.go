@@ -0,0 +1,376 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/huGgW/network-study-with-go/ch03"
+)
+
+const (
+	defaultMaxIdle       = 2
+	defaultCheckTimeout  = time.Second
+	defaultReapFrequency = time.Minute
+)
+
+var ErrPoolClosed = errors.New("pool: closed")
+
+// 주소별로 유휴 연결을 보관하여 재사용하는 TCP 연결 풀
+//
+// 유휴 연결은 건네주기 전에 ch03.Heartbeat의 ping/pong 왕복으로 상태를 확인한다.
+// 따라서 기본 HealthCheck를 사용하는 경우 원격지는 ch03.Ponger와 같이 ping에 응답해야 한다.
+type Pool struct {
+	Dialer      *net.Dialer          // nil인 경우 zero value
+	MaxIdle     int                  // 주소별 최대 유휴 연결 수 (기본 2)
+	MaxOpen     int                  // 주소별 최대 연결 수 (사용 중 + 유휴). 0 이하면 제한 없음
+	IdleTimeout time.Duration        // 유휴 연결을 닫기까지의 시간. 0 이하면 제한 없음
+	HealthCheck func(net.Conn) error // nil인 경우 HeartbeatCheck(1초)
+
+	mu     sync.Mutex
+	hosts  map[string]*host
+	stats  Stats
+	closed bool
+	reaper sync.Once
+	done   chan struct{}
+}
+
+type Stats struct {
+	Hits      uint64 // 유휴 연결을 재사용한 횟수
+	Misses    uint64 // 유휴 연결이 없어 새로 연결한 횟수
+	Evictions uint64 // 만료, health check 실패, MaxIdle 초과로 닫힌 유휴 연결 수
+	Open      int    // 현재 열린 연결 수 (사용 중 + 유휴)
+	Idle      int    // 현재 유휴 연결 수
+}
+
+type host struct {
+	idle     []idleConn    // 마지막에 반환된 연결이 마지막에 위치 (LIFO)
+	sem      chan struct{} // MaxOpen 제한. nil이면 제한 없음
+	returned chan struct{} // 유휴 연결이 반환되거나 풀이 닫힐 때마다 닫고 새로 생성하여 대기 중인 Get을 모두 깨움
+}
+
+// 대기 중인 Get을 모두 깨움. p.mu를 잡은 상태에서 호출
+func (h *host) broadcast() {
+	close(h.returned)
+	h.returned = make(chan struct{})
+}
+
+type idleConn struct {
+	conn  net.Conn
+	since time.Time
+}
+
+// ch03.Heartbeat로 ping을 한 번 보내 timeout 내에 pong을 받는지 확인
+func HeartbeatCheck(timeout time.Duration) func(net.Conn) error {
+	return func(c net.Conn) error {
+		hb := &ch03.Heartbeat{Timeout: timeout}
+		_, err := hb.Probe(c)
+		return err
+	}
+}
+
+// address에 대한 연결을 반환. 반환된 연결을 Close하면 풀로 돌아간다.
+// MaxOpen에 도달한 경우 다른 연결이 반환되거나 닫힐 때까지 대기한다.
+func (p *Pool) Get(ctx context.Context, address string) (*Conn, error) {
+	p.startReaper()
+
+	for {
+		c, sem, returned, err := p.idle(address)
+		if err != nil || c != nil {
+			return c, err
+		}
+
+		// 유휴 연결이 없는 경우 MaxOpen 한도 내에서 새로 연결
+		if sem != nil {
+			select {
+			case sem <- struct{}{}:
+			case <-returned: // 유휴 연결이 반환되었거나 풀이 닫힘 -> 다시 확인
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		return p.dial(ctx, address, sem)
+	}
+}
+
+// health check를 통과한 유휴 연결을 꺼냄. 없는 경우 nil과 함께 대기에 필요한 채널 반환
+func (p *Pool) idle(
+	address string,
+) (*Conn, chan struct{}, <-chan struct{}, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, nil, nil, ErrPoolClosed
+		}
+		h := p.host(address)
+
+		n := len(h.idle)
+		if n == 0 {
+			sem, returned := h.sem, h.returned
+			p.mu.Unlock()
+			return nil, sem, returned, nil
+		}
+
+		ic := h.idle[n-1]
+		h.idle = h.idle[:n-1]
+		p.stats.Idle--
+		expired := p.IdleTimeout > 0 && time.Since(ic.since) > p.IdleTimeout
+		p.mu.Unlock()
+
+		if expired || p.check(ic.conn) != nil {
+			p.evict(address, ic.conn)
+			continue
+		}
+
+		p.mu.Lock()
+		p.stats.Hits++
+		p.mu.Unlock()
+
+		return &Conn{Conn: ic.conn, pool: p, address: address}, nil, nil, nil
+	}
+}
+
+func (p *Pool) dial(
+	ctx context.Context, address string, sem chan struct{},
+) (*Conn, error) {
+	dialer := p.Dialer
+	if dialer == nil {
+		dialer = new(net.Dialer)
+	}
+
+	c, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		if sem != nil {
+			<-sem
+		}
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.stats.Misses++
+	p.stats.Open++
+	p.mu.Unlock()
+
+	return &Conn{Conn: c, pool: p, address: address}, nil
+}
+
+// 호출 시 p.mu를 잡고 있어야 함
+func (p *Pool) host(address string) *host {
+	if p.hosts == nil {
+		p.hosts = make(map[string]*host)
+	}
+
+	h, ok := p.hosts[address]
+	if !ok {
+		h = &host{returned: make(chan struct{})}
+		if p.MaxOpen > 0 {
+			h.sem = make(chan struct{}, p.MaxOpen)
+		}
+		p.hosts[address] = h
+	}
+
+	return h
+}
+
+func (p *Pool) check(c net.Conn) error {
+	check := p.HealthCheck
+	if check == nil {
+		check = HeartbeatCheck(defaultCheckTimeout)
+	}
+
+	if err := check(c); err != nil {
+		return err
+	}
+
+	// health check에서 설정한 데드라인 초기화
+	return c.SetDeadline(time.Time{})
+}
+
+func (p *Pool) maxIdle() int {
+	if p.MaxIdle <= 0 {
+		return defaultMaxIdle
+	}
+	return p.MaxIdle
+}
+
+// 사용이 끝난 연결을 유휴 목록으로 되돌림. 풀이 닫혔거나 유휴 목록이 가득 찬 경우 연결을 닫음
+func (p *Pool) put(address string, c net.Conn) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		p.discard(address, c)
+		return
+	}
+
+	h := p.host(address)
+	if len(h.idle) < p.maxIdle() {
+		h.idle = append(h.idle, idleConn{conn: c, since: time.Now()})
+		p.stats.Idle++
+		h.broadcast()
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+
+	p.evict(address, c)
+}
+
+// 유휴 연결을 닫고 통계에 반영
+func (p *Pool) evict(address string, c net.Conn) {
+	p.mu.Lock()
+	p.stats.Evictions++
+	p.mu.Unlock()
+
+	p.discard(address, c)
+}
+
+// 연결을 닫고 MaxOpen 슬롯을 반환
+func (p *Pool) discard(address string, c net.Conn) {
+	_ = c.Close()
+
+	p.mu.Lock()
+	h := p.host(address)
+	p.stats.Open--
+	sem := h.sem
+	p.mu.Unlock()
+
+	if sem != nil {
+		<-sem
+	}
+}
+
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
+
+// 유휴 연결을 모두 닫음. 사용 중인 연결은 반환될 때 닫힌다.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	if p.done != nil {
+		close(p.done)
+	}
+
+	idle := make(map[string][]idleConn)
+	for address, h := range p.hosts {
+		idle[address] = h.idle
+		p.stats.Idle -= len(h.idle)
+		h.idle = nil
+		h.broadcast() // 대기 중인 Get이 ErrPoolClosed를 반환하도록
+	}
+	p.mu.Unlock()
+
+	for address, conns := range idle {
+		for _, ic := range conns {
+			p.discard(address, ic.conn)
+		}
+	}
+
+	return nil
+}
+
+// IdleTimeout이 설정된 경우 주기적으로 만료된 유휴 연결을 닫음
+func (p *Pool) startReaper() {
+	if p.IdleTimeout <= 0 {
+		return
+	}
+
+	p.reaper.Do(func() {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return
+		}
+		p.done = make(chan struct{})
+		done := p.done
+		p.mu.Unlock()
+
+		freq := p.IdleTimeout / 2
+		if freq > defaultReapFrequency {
+			freq = defaultReapFrequency
+		}
+
+		go func() {
+			ticker := time.NewTicker(freq)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					p.reap()
+				}
+			}
+		}()
+	})
+}
+
+func (p *Pool) reap() {
+	expired := make(map[string][]net.Conn)
+
+	p.mu.Lock()
+	for address, h := range p.hosts {
+		kept := h.idle[:0]
+		for _, ic := range h.idle {
+			if time.Since(ic.since) > p.IdleTimeout {
+				expired[address] = append(expired[address], ic.conn)
+				p.stats.Idle--
+				continue
+			}
+			kept = append(kept, ic)
+		}
+		h.idle = kept
+	}
+	p.mu.Unlock()
+
+	for address, conns := range expired {
+		for _, c := range conns {
+			p.evict(address, c)
+		}
+	}
+}
+
+// 풀에서 꺼낸 연결. Close 시 실제로 닫지 않고 풀로 반환한다.
+type Conn struct {
+	net.Conn
+
+	pool     *Pool
+	address  string
+	once     sync.Once
+	unusable bool
+}
+
+// 에러가 발생하는 등 재사용하면 안 되는 연결인 경우 Close 전에 호출하여 풀로 반환하지 않고 닫음
+func (c *Conn) MarkUnusable() {
+	c.unusable = true
+}
+
+func (c *Conn) Close() error {
+	c.once.Do(func() {
+		if c.unusable {
+			c.pool.discard(c.address, c.Conn)
+			return
+		}
+
+		// 사용 중 설정된 데드라인이 다음 사용자에게 영향을 주지 않도록 초기화
+		if err := c.Conn.SetDeadline(time.Time{}); err != nil {
+			c.pool.discard(c.address, c.Conn)
+			return
+		}
+		c.pool.put(c.address, c.Conn)
+	})
+
+	return nil
+}
@@ -0,0 +1,281 @@
+package pool
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/huGgW/network-study-with-go/ch03"
+)
+
+// 연결마다 ping에 pong으로 응답하는 서버. 서버 측 연결 목록을 함께 반환
+func pongServer(t *testing.T, ctx context.Context) (string, func() []net.Conn) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu    sync.Mutex
+		conns []net.Conn
+	)
+
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+
+			go func(c net.Conn) {
+				defer c.Close()
+				_ = ch03.Ponger(ctx, c)
+			}(conn)
+		}
+	}()
+
+	return listener.Addr().String(), func() []net.Conn {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]net.Conn(nil), conns...)
+	}
+}
+
+func TestPoolReuse(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	addr, _ := pongServer(t, ctx)
+
+	p := &Pool{MaxIdle: 1}
+	defer p.Close()
+
+	c1, err := p.Get(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := c1.LocalAddr().String()
+	_ = c1.Close()
+
+	// 반환된 연결을 health check 후 재사용
+	c2, err := p.Get(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c2.LocalAddr().String() != local {
+		t.Errorf("expected reused connection %s; actual %s", local, c2.LocalAddr())
+	}
+
+	// 유휴 연결이 없으므로 새로 연결
+	c3, err := p.Get(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = c2.Close()
+	_ = c3.Close() // MaxIdle 초과 -> evict
+
+	s := p.Stats()
+	t.Logf("%+v", s)
+	if s.Hits != 1 || s.Misses != 2 || s.Evictions != 1 || s.Open != 1 || s.Idle != 1 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
+
+func TestPoolHealthCheck(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	addr, serverConns := pongServer(t, ctx)
+
+	p := &Pool{HealthCheck: HeartbeatCheck(100 * time.Millisecond)}
+	defer p.Close()
+
+	c, err := p.Get(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := c.LocalAddr().String()
+	_ = c.Close()
+
+	// 서버 측에서 연결을 종료 -> 유휴 연결은 health check에 실패해야 함
+	for _, sc := range serverConns() {
+		_ = sc.Close()
+	}
+
+	c, err = p.Get(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if c.LocalAddr().String() == local {
+		t.Error("expected dead idle connection to be evicted")
+	}
+
+	s := p.Stats()
+	if s.Hits != 0 || s.Misses != 2 || s.Evictions != 1 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
+
+func TestPoolIdleTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	addr, _ := pongServer(t, ctx)
+
+	p := &Pool{IdleTimeout: 50 * time.Millisecond}
+	defer p.Close()
+
+	c, err := p.Get(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = c.Close()
+
+	// reaper가 만료된 유휴 연결을 닫을 때까지 대기
+	time.Sleep(200 * time.Millisecond)
+
+	s := p.Stats()
+	if s.Idle != 0 || s.Open != 0 || s.Evictions != 1 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
+
+func TestPoolMaxOpen(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	addr, _ := pongServer(t, ctx)
+
+	p := &Pool{MaxOpen: 1}
+	defer p.Close()
+
+	c1, err := p.Get(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 한도에 도달하여 context 만료까지 대기
+	tctx, tcancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer tcancel()
+	_, err = p.Get(tctx, addr)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded; actual: %v", err)
+	}
+
+	// 다른 고루틴에서 연결을 반환하면 대기 중인 Get이 해당 연결을 재사용
+	time.AfterFunc(50*time.Millisecond, func() { _ = c1.Close() })
+
+	c2, err := p.Get(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 연결을 닫으면 대기 중인 Get이 새로 연결
+	time.AfterFunc(50*time.Millisecond, func() {
+		c2.MarkUnusable()
+		_ = c2.Close()
+	})
+
+	c3, err := p.Get(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = c3.Close()
+
+	s := p.Stats()
+	if s.Open != 1 || s.Hits != 1 || s.Misses != 2 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
+
+// 대기 중인 Get이 여럿일 때 연이어 반환된 연결을 모두 받아야 함
+func TestPoolMaxOpenWaiters(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	addr, _ := pongServer(t, ctx)
+
+	p := &Pool{MaxOpen: 2}
+	defer p.Close()
+
+	c1, err := p.Get(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := p.Get(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tctx, tcancel := context.WithTimeout(ctx, 2*time.Second)
+	defer tcancel()
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			c, err := p.Get(tctx, addr)
+			if err == nil {
+				defer c.Close()
+			}
+			errs <- err
+		}()
+	}
+
+	// 두 Get이 모두 대기한 뒤 연속으로 반환
+	time.Sleep(50 * time.Millisecond)
+	_ = c1.Close()
+	_ = c2.Close()
+
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("expected idle conn; actual: %v", err)
+		}
+	}
+
+	s := p.Stats()
+	if s.Hits != 2 || s.Misses != 2 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
+
+func TestPoolCloseWakesGet(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	addr, _ := pongServer(t, ctx)
+
+	p := &Pool{MaxOpen: 1}
+	c, err := p.Get(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	errs := make(chan error)
+	go func() {
+		_, err := p.Get(ctx, addr)
+		errs <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	_ = p.Close()
+
+	select {
+	case err := <-errs:
+		if err != ErrPoolClosed {
+			t.Errorf("expected ErrPoolClosed; actual: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close did not wake the waiting Get")
+	}
+}
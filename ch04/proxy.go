@@ -1,6 +1,7 @@
 package ch04
 
 import (
+	"errors"
 	"io"
 	"net"
 )
@@ -21,18 +22,89 @@ func proxyConn(source, destination string) error {
 	}
 	defer connDestination.Close()
 
-	// io.Copy(dst io.Writer, src io.Reader)
-	// reader로부터 데이터를 읽어와 writer로 데이터를 써주는 함수
+	_, err = Proxy(connSource, connDestination)
 
-	// source -> destination
-	go func() { // 노드 중 하나라도 연결이 끊기면 io.Copy는 종료되어 memory leak 없음
-		io.Copy(connDestination, connSource)
+	return err
+}
+
+// 방향별로 전달한 byte 수
+type ProxyStats struct {
+	ClientToServer int64
+	ServerToClient int64
+}
+
+// client와 server 사이에서 양방향으로 데이터를 전달
+//
+// 한쪽에서 EOF(FIN)를 받으면 반대쪽의 write 방향만 닫아(CloseWrite) half-close를 전파하고,
+// 나머지 방향은 끝날 때까지 계속 전달한다. 한 방향에서 에러가 발생하면 양쪽 연결을 닫아
+// 다른 방향도 종료시키고 처음 발생한 에러를 반환한다.
+// 두 연결 모두 *net.TCPConn이면 io.Copy가 splice를 사용하므로 데이터가 user space를 경유하지 않는다.
+// 연결을 감싸면 이 경로를 사용할 수 없으므로 그대로 전달해야 한다.
+func Proxy(client, server net.Conn) (ProxyStats, error) {
+	type result struct {
+		n   int64
+		err error
+	}
+	upstream, downstream := make(chan result, 1), make(chan result, 1)
+
+	go func() {
+		n, err := copyHalf(server, client)
+		upstream <- result{n, err}
+	}()
+	go func() {
+		n, err := copyHalf(client, server)
+		downstream <- result{n, err}
 	}()
 
-	// destination -> source
-	_, err = io.Copy(connSource, connDestination)
+	var (
+		stats ProxyStats
+		first error
+	)
+	for i := 0; i < 2; i++ {
+		var r result
+		select {
+		case r = <-upstream:
+			stats.ClientToServer = r.n
+		case r = <-downstream:
+			stats.ServerToClient = r.n
+		}
 
-	return err
+		// 직접 닫아서 발생한 에러는 무시
+		if r.err == nil || errors.Is(r.err, net.ErrClosed) || first != nil {
+			continue
+		}
+		first = r.err
+		_ = client.Close()
+		_ = server.Close()
+	}
+
+	return stats, first
+}
+
+type closeWriter interface {
+	CloseWrite() error
+}
+
+// src를 EOF까지 dst로 복사한 뒤 dst의 write 방향을 닫음
+func copyHalf(dst, src net.Conn) (int64, error) {
+	n, err := io.Copy(dst, src)
+	if err != nil {
+		return n, err
+	}
+
+	// 상대가 이미 연결을 닫은 경우에도 복사한 데이터는 유효하므로 에러는 무시
+	_ = CloseWrite(dst)
+
+	return n, nil
+}
+
+// conn의 write 방향만 닫아 half-close. 연결을 감싸는 타입은 원래 연결에 전달할 때 사용
+// half-close를 지원하지 않으면 반대 방향이 끝나지 않을 수 있으므로 연결 전체를 닫음
+func CloseWrite(conn net.Conn) error {
+	if c, ok := conn.(closeWriter); ok {
+		return c.CloseWrite()
+	}
+	return conn.Close()
 }
 
 // io.Reader, io.Writer interface를 매개변수로 받아 다양한 종류의 io에 적용 가능
@@ -1,10 +1,16 @@
 package ch04
 
 import (
+	"errors"
 	"io"
 	"net"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestProxy(t *testing.T) {
@@ -138,3 +144,217 @@ func TestProxy(t *testing.T) {
 	server.Close()
 	wg.Wait()
 }
+
+// 연결된 TCP 연결 쌍 생성
+func tcpPair(tb testing.TB) (*net.TCPConn, *net.TCPConn) {
+	tb.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer listener.Close()
+
+	accepted := make(chan net.Conn)
+	go func() {
+		conn, _ := listener.Accept()
+		accepted <- conn
+	}()
+
+	dialed, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	conn := <-accepted
+	if conn == nil {
+		tb.Fatal("accept failed")
+	}
+
+	tb.Cleanup(func() {
+		_ = dialed.Close()
+		_ = conn.Close()
+	})
+
+	return dialed.(*net.TCPConn), conn.(*net.TCPConn)
+}
+
+// client <-> (a, b) Proxy (c, d) <-> server
+func proxyPair(tb testing.TB) (client, server *net.TCPConn, done <-chan proxyResult) {
+	tb.Helper()
+
+	client, a := tcpPair(tb)
+	d, server := tcpPair(tb)
+
+	ch := make(chan proxyResult, 1)
+	go func() {
+		stats, err := Proxy(a, d)
+		ch <- proxyResult{stats, err}
+	}()
+
+	return client, server, ch
+}
+
+type proxyResult struct {
+	stats ProxyStats
+	err   error
+}
+
+func TestProxyHalfClose(t *testing.T) {
+	client, server, done := proxyPair(t)
+
+	// client가 요청을 보내고 write 방향만 닫음
+	_, err := client.Write([]byte("request"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = client.CloseWrite()
+
+	// server는 EOF를 받은 뒤에도 응답 가능
+	req, err := io.ReadAll(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(req) != "request" {
+		t.Errorf("expected %q; actual %q", "request", req)
+	}
+
+	_, err = server.Write([]byte("a longer response"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = server.CloseWrite()
+
+	resp, err := io.ReadAll(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp) != "a longer response" {
+		t.Errorf("expected %q; actual %q", "a longer response", resp)
+	}
+
+	r := <-done
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	expected := ProxyStats{ClientToServer: 7, ServerToClient: 17}
+	if r.stats != expected {
+		t.Errorf("expected %+v; actual %+v", expected, r.stats)
+	}
+}
+
+func TestProxyError(t *testing.T) {
+	client, server, done := proxyPair(t)
+
+	_, err := client.Write([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(server, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// linger 0으로 닫아 RST 전송
+	_ = server.SetLinger(0)
+	_ = server.Close()
+
+	r := <-done
+	if !errors.Is(r.err, syscall.ECONNRESET) {
+		t.Fatalf("expected connection reset; actual: %v", r.err)
+	}
+	if r.stats.ClientToServer != 5 {
+		t.Errorf("expected 5 bytes to server; actual %+v", r.stats)
+	}
+
+	// 반대쪽 연결도 닫혀야 함
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = client.Read(buf)
+	if err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("expected client connection to be closed; actual: %v", err)
+	}
+}
+
+// /proc/self/io의 rchar: read 계열 system call로 user space에 복사된 byte 수
+// splice는 여기에 포함되지 않음
+func readChars(tb testing.TB) int64 {
+	b, err := os.ReadFile("/proc/self/io")
+	if err != nil {
+		tb.Skip("/proc/self/io not available")
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if v, ok := strings.CutPrefix(line, "rchar: "); ok {
+			n, _ := strconv.ParseInt(v, 10, 64)
+			return n
+		}
+	}
+	tb.Skip("rchar not found")
+	return 0
+}
+
+// splice를 사용하지 못하도록 io.ReaderFrom, io.WriterTo를 숨기는 wrapper
+type plainConn struct{ net.Conn }
+
+func benchmarkProxy(b *testing.B, wrap bool) {
+	const size = 1 << 20
+	data := make([]byte, size)
+	sink := make([]byte, 1<<20)
+
+	var copied int64
+	b.SetBytes(size)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		client, a := tcpPair(b)
+		d, server := tcpPair(b)
+		var src, dst net.Conn = a, d
+		if wrap {
+			src, dst = plainConn{a}, plainConn{d}
+		}
+		before := readChars(b)
+		b.StartTimer()
+
+		go func() {
+			_, _ = client.Write(data)
+			_ = client.CloseWrite()
+		}()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			// sink의 read도 rchar에 포함되므로 정확히 size만큼 읽음
+			for n := 0; n < size; {
+				m, err := server.Read(sink)
+				if err != nil {
+					return
+				}
+				n += m
+			}
+			_ = server.Close()
+		}()
+
+		_, err := Proxy(src, dst)
+		if err != nil {
+			b.Fatal(err)
+		}
+		<-done
+
+		b.StopTimer()
+		copied += readChars(b) - before - size
+		for _, c := range []net.Conn{client, a, d, server} {
+			_ = c.Close()
+		}
+		b.StartTimer()
+	}
+
+	// proxy가 user space로 복사한 byte의 비율. splice를 사용하면 0에 가까움
+	ratio := float64(copied) / float64(b.N*size)
+	b.ReportMetric(ratio, "userspace/byte")
+	if !wrap && ratio > 0.1 {
+		b.Fatalf("splice path not taken: %.2f of bytes copied through user space", ratio)
+	}
+}
+
+func BenchmarkProxySplice(b *testing.B) { benchmarkProxy(b, false) }
+
+func BenchmarkProxyUserspace(b *testing.B) { benchmarkProxy(b, true) }
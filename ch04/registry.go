@@ -0,0 +1,138 @@
+package ch04
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+var (
+	ErrUnknownType     = errors.New("unknown type")
+	ErrDuplicateType   = errors.New("type already registered")
+	ErrInvalidTypeCode = errors.New("invalid type code")
+)
+
+// TLV의 type 코드와 해당 Payload를 생성하는 factory 함수의 매핑
+// ch04 패키지를 수정하지 않고도 애플리케이션에서 자신만의 Payload 타입을 추가할 수 있다.
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[uint8]func() Payload
+}
+
+func NewRegistry() *Registry {
+	return &Registry{factories: make(map[uint8]func() Payload)}
+}
+
+// code에 대한 factory 등록. 이미 등록된 code인 경우 ErrDuplicateType 반환
+// 0은 유효하지 않은 type 코드로 예약됨
+func (r *Registry) Register(code uint8, factory func() Payload) error {
+	if code == 0 || factory == nil {
+		return ErrInvalidTypeCode
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.factories[code]; ok {
+		return fmt.Errorf("%w: %d", ErrDuplicateType, code)
+	}
+	r.factories[code] = factory
+
+	return nil
+}
+
+// code에 해당하는 zero value Payload 생성
+func (r *Registry) New(code uint8) (Payload, error) {
+	r.mu.RLock()
+	factory, ok := r.factories[code]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownType, code)
+	}
+
+	return factory(), nil
+}
+
+// ch04에 정의된 Payload 타입이 기본으로 등록된 registry
+var DefaultRegistry = NewRegistry()
+
+func init() {
+	_ = DefaultRegistry.Register(BinaryType, func() Payload { return new(Binary) })
+	_ = DefaultRegistry.Register(StringType, func() Payload { return new(String) })
+	_ = DefaultRegistry.Register(Int64Type, func() Payload { return new(Int64) })
+	_ = DefaultRegistry.Register(Uint64Type, func() Payload { return new(Uint64) })
+	_ = DefaultRegistry.Register(VarintType, func() Payload { return new(Varint) })
+	_ = DefaultRegistry.Register(UvarintType, func() Payload { return new(Uvarint) })
+	_ = DefaultRegistry.Register(Float64Type, func() Payload { return new(Float64) })
+	_ = DefaultRegistry.Register(BoolType, func() Payload { return new(Bool) })
+	_ = DefaultRegistry.Register(TimestampType, func() Payload { return new(Timestamp) })
+	_ = DefaultRegistry.Register(ListType, func() Payload { return new(List) })
+	_ = DefaultRegistry.Register(MapType, func() Payload { return new(Map) })
+	_ = DefaultRegistry.Register(ChunkType, func() Payload { return new(Stream) })
+	_ = DefaultRegistry.Register(FlaggedType, func() Payload { return new(Flagged) })
+}
+
+// DefaultRegistry에 Payload 타입 등록
+func RegisterType(code uint8, factory func() Payload) error {
+	return DefaultRegistry.Register(code, factory)
+}
+
+// reader에서 TLV를 읽어 registry에 등록된 Payload 타입으로 디코딩
+type Decoder struct {
+	r        io.Reader
+	Registry *Registry // nil인 경우 DefaultRegistry
+
+	// 압축을 푼 내부 payload와 Stream의 각 frame의 최대 크기. 0이면 패키지의 MaxPayloadSize
+	MaxPayloadSize uint32
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+func (d *Decoder) Decode() (Payload, error) {
+	var typ uint8
+	// type 추론을 위해 1byte를 읽음
+	err := binary.Read(d.r, binary.BigEndian, &typ)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := d.Registry
+	if registry == nil {
+		registry = DefaultRegistry
+	}
+	maxSize := d.MaxPayloadSize
+	if maxSize == 0 || maxSize > MaxPayloadSize {
+		maxSize = MaxPayloadSize
+	}
+
+	payload, err := registry.New(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	// 이미 앞에서 1byte를 읽었기 때문에, 해당 부분을 앞에 reader로 제공하여
+	// io.MultiReader를 통해 원래의 byte 전체를 읽는 것과 동일하도록 한다.
+	r := io.MultiReader(bytes.NewReader([]byte{typ}), d.r)
+	if s, ok := payload.(*Stream); ok {
+		// 이후 continuation frame도 같은 최대 크기로 확인
+		_, err = s.readFrom(r, maxSize)
+	} else {
+		err = readPayload(payload, typ, r, registry, newBudget(maxSize), 1)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 확장 헤더는 투명하게 벗겨서 원래의 payload 반환
+	if f, ok := payload.(*Flagged); ok {
+		return f.Payload, nil
+	}
+
+	return payload, nil
+}
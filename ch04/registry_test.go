@@ -0,0 +1,103 @@
+package ch04
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+)
+
+const pointType uint8 = 100
+
+// ch04 패키지 외부에서 정의한 것과 같은 사용자 정의 Payload
+type point struct{ X, Y int32 }
+
+func (p point) Bytes() []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b[:4], uint32(p.X))
+	binary.BigEndian.PutUint32(b[4:], uint32(p.Y))
+	return b
+}
+
+func (p point) String() string { return fmt.Sprintf("(%d, %d)", p.X, p.Y) }
+
+func (p point) WriteTo(w io.Writer) (int64, error) {
+	err := binary.Write(w, binary.BigEndian, pointType)
+	if err != nil {
+		return 0, err
+	}
+	err = binary.Write(w, binary.BigEndian, uint32(8))
+	if err != nil {
+		return 1, err
+	}
+	n, err := w.Write(p.Bytes())
+	return 5 + int64(n), err
+}
+
+func (p *point) ReadFrom(r io.Reader) (int64, error) {
+	var header struct {
+		Type uint8
+		Size uint32
+	}
+	err := binary.Read(r, binary.BigEndian, &header)
+	if err != nil {
+		return 0, err
+	}
+	if header.Type != pointType || header.Size != 8 {
+		return 5, errors.New("invalid point")
+	}
+	err = binary.Read(r, binary.BigEndian, p)
+	return 13, err
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	err := registry.Register(pointType, func() Payload { return new(point) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 같은 코드로 중복 등록 불가
+	err = registry.Register(pointType, func() Payload { return new(String) })
+	if !errors.Is(err, ErrDuplicateType) {
+		t.Errorf("expected ErrDuplicateType; actual: %v", err)
+	}
+
+	err = registry.Register(0, func() Payload { return new(String) })
+	if !errors.Is(err, ErrInvalidTypeCode) {
+		t.Errorf("expected ErrInvalidTypeCode; actual: %v", err)
+	}
+
+	// 기본 registry의 Binary, String 타입도 중복 등록 불가
+	err = RegisterType(BinaryType, func() Payload { return new(point) })
+	if !errors.Is(err, ErrDuplicateType) {
+		t.Errorf("expected ErrDuplicateType; actual: %v", err)
+	}
+
+	var buf bytes.Buffer
+	expected := &point{X: 3, Y: -4}
+	_, err = expected.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 기본 registry는 point 타입을 모름
+	_, err = decode(bytes.NewReader(buf.Bytes()))
+	if !errors.Is(err, ErrUnknownType) {
+		t.Errorf("expected ErrUnknownType; actual: %v", err)
+	}
+
+	d := NewDecoder(&buf)
+	d.Registry = registry
+	actual, err := d.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("value mismatch: %v != %v", expected, actual)
+	}
+}
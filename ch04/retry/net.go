@@ -0,0 +1,63 @@
+package retry
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// 정책에 따라 연결을 재시도. dialer가 nil인 경우 zero value net.Dialer 사용
+func Dial(
+	ctx context.Context, p Policy, dialer *net.Dialer, network, address string,
+) (net.Conn, error) {
+	if dialer == nil {
+		dialer = new(net.Dialer)
+	}
+
+	var conn net.Conn
+	err := p.Do(ctx, func(ctx context.Context) error {
+		var err error
+		conn, err = dialer.DialContext(ctx, network, address)
+		return err
+	})
+
+	return conn, err
+}
+
+// 정책에 따라 b 전체를 쓸 때까지 재시도.
+// 중간에 일부만 쓰인 경우 남은 부분부터 다시 쓴다.
+//
+// AttemptTimeout이 설정된 경우 Write가 conn의 write deadline을 관리한다.
+// 시도마다 context의 deadline을 write deadline으로 새로 설정하므로,
+// timeout 이후의 시도가 이미 지난 deadline 때문에 바로 실패하지 않으며, 반환 전에 write deadline을 해제한다.
+// AttemptTimeout이 0이면 호출자가 설정한 write deadline을 그대로 사용한다.
+// ECONNRESET, EPIPE는 연결이 이미 끊어진 것이므로 같은 연결에 다시 써도 성공할 수 없어 재시도하지 않는다.
+func Write(ctx context.Context, p Policy, conn net.Conn, b []byte) (int, error) {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = Temporary
+	}
+	p.Retryable = func(err error) bool {
+		return !IsConnReset(err) && !IsBrokenPipe(err) && retryable(err)
+	}
+	if p.AttemptTimeout > 0 {
+		defer func() { _ = conn.SetWriteDeadline(time.Time{}) }()
+	}
+
+	total := 0
+	err := p.Do(ctx, func(ctx context.Context) error {
+		if p.AttemptTimeout > 0 {
+			deadline, _ := ctx.Deadline()
+			err := conn.SetWriteDeadline(deadline)
+			if err != nil {
+				return err
+			}
+		}
+
+		n, err := conn.Write(b[total:])
+		total += n
+		return err
+	})
+
+	return total, err
+}
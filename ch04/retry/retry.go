@@ -0,0 +1,160 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultInitialInterval = 100 * time.Millisecond
+	defaultMaxInterval     = 10 * time.Second
+	defaultMultiplier      = 2.0
+)
+
+// 에러가 재시도 가능한 일시적 에러인지 판단
+type Classifier func(err error) bool
+
+// 지수 백오프 (exponential backoff) + full jitter 재시도 정책
+//
+// n번째 재시도 전 대기 시간은 [0, min(MaxInterval, InitialInterval * Multiplier^n)) 사이의 임의의 값.
+// 여러 클라이언트가 동시에 실패했을 때 같은 시점에 재시도가 몰리는 것을 jitter로 분산시킨다.
+type Policy struct {
+	MaxAttempts     int           // 최대 시도 횟수. 0 이하면 제한 없음
+	MaxElapsedTime  time.Duration // 첫 시도부터 재시도를 포기할 때까지의 시간. 0 이하면 제한 없음
+	InitialInterval time.Duration // 첫 재시도 대기 시간 상한 (기본 100ms)
+	MaxInterval     time.Duration // 대기 시간 상한의 최댓값 (기본 10s)
+	Multiplier      float64       // 재시도마다 대기 시간 상한을 늘리는 배수 (기본 2)
+	AttemptTimeout  time.Duration // 시도마다 op에 전달하는 context의 timeout. 0 이하면 제한 없음
+	Retryable       Classifier    // nil인 경우 Temporary
+}
+
+var ErrMaxElapsedTime = errors.New("retry: max elapsed time exceeded")
+
+// 재시도를 포기한 경우 반환하는 에러. 마지막 시도의 에러를 감싼다.
+type Error struct {
+	Attempts int
+	Err      error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("retry: giving up after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// op가 성공하거나, 재시도 불가능한 에러를 반환하거나, 정책의 한도에 도달하거나,
+// context가 취소될 때까지 op를 반복 호출한다.
+func (p Policy) Do(ctx context.Context, op func(ctx context.Context) error) error {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = Temporary
+	}
+
+	begin := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := p.attempt(ctx, op)
+		if err == nil {
+			return nil
+		}
+
+		if !retryable(err) {
+			return err
+		}
+
+		if p.MaxAttempts > 0 && attempt >= p.MaxAttempts {
+			return &Error{Attempts: attempt, Err: err}
+		}
+
+		wait := p.Backoff(attempt - 1)
+		if p.MaxElapsedTime > 0 && time.Since(begin)+wait > p.MaxElapsedTime {
+			return &Error{Attempts: attempt, Err: errors.Join(ErrMaxElapsedTime, err)}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+// AttemptTimeout이 있으면 시도마다 새 timeout을 가진 context로 op 호출
+func (p Policy) attempt(ctx context.Context, op func(ctx context.Context) error) error {
+	if p.AttemptTimeout <= 0 {
+		return op(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.AttemptTimeout)
+	defer cancel()
+	return op(ctx)
+}
+
+// n번째 (0부터 시작) 재시도 전 대기 시간
+func (p Policy) Backoff(n int) time.Duration {
+	initial := p.InitialInterval
+	if initial <= 0 {
+		initial = defaultInitialInterval
+	}
+	maxInterval := p.MaxInterval
+	if maxInterval <= 0 {
+		maxInterval = defaultMaxInterval
+	}
+	mult := p.Multiplier
+	if mult < 1 {
+		mult = defaultMultiplier
+	}
+
+	ceil := float64(initial) * math.Pow(mult, float64(n))
+	if ceil > float64(maxInterval) || math.IsInf(ceil, 0) {
+		ceil = float64(maxInterval)
+	}
+
+	// full jitter
+	return time.Duration(rand.Int64N(int64(ceil) + 1))
+}
+
+// Classifiers
+
+// net.Error의 timeout. net.Error.Temporary()는 deprecated되었으므로 사용하지 않음
+func IsTimeout(err error) bool {
+	var nErr net.Error
+	return errors.As(err, &nErr) && nErr.Timeout()
+}
+
+// 원격지가 아직 listen하지 않는 경우 (RST 응답)
+func IsConnRefused(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// 연결 도중 원격지가 RST를 보낸 경우
+func IsConnReset(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET)
+}
+
+// 원격지가 이미 연결을 닫았는데 쓴 경우
+func IsBrokenPipe(err error) bool {
+	return errors.Is(err, syscall.EPIPE)
+}
+
+// 주어진 classifier 중 하나라도 참이면 재시도
+func Any(classifiers ...Classifier) Classifier {
+	return func(err error) bool {
+		for _, c := range classifiers {
+			if c(err) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// 기본 classifier: timeout, ECONNREFUSED, ECONNRESET
+var Temporary = Any(IsTimeout, IsConnRefused, IsConnReset)
@@ -0,0 +1,289 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+var errTemporary = errors.New("temporary")
+
+func TestDoRetriesUntilSuccess(t *testing.T) {
+	p := Policy{
+		MaxAttempts:     5,
+		InitialInterval: time.Millisecond,
+		Retryable:       func(err error) bool { return err == errTemporary },
+	}
+
+	attempts := 0
+	err := p.Do(context.Background(), func(context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errTemporary
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts; actual: %d", attempts)
+	}
+}
+
+func TestDoStops(t *testing.T) {
+	permanent := errors.New("permanent")
+	retryable := func(err error) bool { return err == errTemporary }
+
+	testCases := []struct {
+		name     string
+		policy   Policy
+		err      error
+		ctx      func() context.Context
+		attempts int
+		expected error
+	}{
+		{
+			name:     "non-retryable",
+			policy:   Policy{Retryable: retryable},
+			err:      permanent,
+			attempts: 1,
+			expected: permanent,
+		},
+		{
+			name: "max attempts",
+			policy: Policy{
+				MaxAttempts: 4, InitialInterval: time.Millisecond, Retryable: retryable,
+			},
+			err:      errTemporary,
+			attempts: 4,
+			expected: errTemporary,
+		},
+		{
+			name: "max elapsed time",
+			policy: Policy{
+				MaxElapsedTime:  50 * time.Millisecond,
+				InitialInterval: 10 * time.Millisecond,
+				MaxInterval:     10 * time.Millisecond,
+				Retryable:       retryable,
+			},
+			err:      errTemporary,
+			expected: ErrMaxElapsedTime,
+		},
+		{
+			name:   "context canceled",
+			policy: Policy{InitialInterval: time.Second, Retryable: retryable},
+			err:    errTemporary,
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(10*time.Millisecond, cancel)
+				return ctx
+			},
+			attempts: 1,
+			expected: context.Canceled,
+		},
+	}
+
+	for _, c := range testCases {
+		ctx := context.Background()
+		if c.ctx != nil {
+			ctx = c.ctx()
+		}
+
+		attempts := 0
+		err := c.policy.Do(ctx, func(context.Context) error {
+			attempts++
+			return c.err
+		})
+
+		if !errors.Is(err, c.expected) {
+			t.Errorf("%s: expected %v; actual: %v", c.name, c.expected, err)
+		}
+		if c.attempts > 0 && attempts != c.attempts {
+			t.Errorf("%s: expected %d attempts; actual: %d", c.name, c.attempts, attempts)
+		}
+		t.Logf("%s: %d attempts, %v", c.name, attempts, err)
+	}
+}
+
+func TestBackoffFullJitter(t *testing.T) {
+	p := Policy{
+		InitialInterval: 10 * time.Millisecond,
+		MaxInterval:     80 * time.Millisecond,
+		Multiplier:      2,
+	}
+
+	for n, ceil := range []time.Duration{10, 20, 40, 80, 80, 80} {
+		ceil *= time.Millisecond
+		for i := 0; i < 100; i++ {
+			if d := p.Backoff(n); d < 0 || d > ceil {
+				t.Fatalf("backoff %d: expected [0, %s]; actual: %s", n, ceil, d)
+			}
+		}
+	}
+}
+
+func TestClassifiers(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected bool
+	}{
+		{&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+		{&net.OpError{Op: "read", Err: syscall.ECONNRESET}, true},
+		{&net.DNSError{IsTimeout: true}, true},
+		{&net.OpError{Op: "dial", Err: syscall.EHOSTUNREACH}, false},
+		{errors.New("other"), false},
+	}
+
+	for i, c := range testCases {
+		if actual := Temporary(c.err); actual != c.expected {
+			t.Errorf("%d: %v: expected %t; actual %t", i, c.err, c.expected, actual)
+		}
+	}
+}
+
+func TestDialRetry(t *testing.T) {
+	// 주소를 얻은 후 listener를 닫아 연결이 거절되도록 함
+	listener, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	// 잠시 후 같은 주소로 다시 listen
+	ready := make(chan net.Listener)
+	time.AfterFunc(50*time.Millisecond, func() {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			t.Error(err)
+		}
+		ready <- l
+	})
+
+	p := Policy{
+		MaxElapsedTime:  5 * time.Second,
+		InitialInterval: 20 * time.Millisecond,
+		MaxInterval:     20 * time.Millisecond,
+	}
+	conn, err := Dial(context.Background(), p, nil, "tcp", addr)
+
+	l := <-ready
+	if l != nil {
+		defer l.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.Close()
+}
+
+// 첫 쓰기가 timeout되어도 다음 시도는 새 deadline으로 성공
+func TestWriteRetryAfterTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	// 처음에는 읽지 않아 쓰기가 timeout됨
+	read := make(chan []byte)
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		buf := make([]byte, 5)
+		n, _ := server.Read(buf)
+		read <- buf[:n]
+	}()
+
+	attempts := 0
+	p := Policy{
+		MaxAttempts:     10,
+		InitialInterval: 10 * time.Millisecond,
+		MaxInterval:     10 * time.Millisecond,
+		AttemptTimeout:  50 * time.Millisecond,
+		Retryable: func(err error) bool {
+			attempts++
+			return Temporary(err)
+		},
+	}
+	n, err := Write(context.Background(), p, client, []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 {
+		t.Errorf("expected 5 bytes; actual %d", n)
+	}
+	if attempts == 0 {
+		t.Error("expected at least one timed out attempt")
+	}
+	if actual := string(<-read); actual != "hello" {
+		t.Errorf("expected %q; actual %q", "hello", actual)
+	}
+}
+
+// AttemptTimeout이 없으면 호출자가 설정한 write deadline을 유지
+func TestWriteKeepsCallerDeadline(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	_ = client.SetWriteDeadline(time.Now().Add(50 * time.Millisecond))
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := Write(context.Background(), Policy{MaxAttempts: 1}, client, []byte("hello"))
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Errorf("expected deadline exceeded; actual %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Write ignored the caller's write deadline")
+	}
+}
+
+// 연결이 끊어진 경우 같은 연결로 재시도하지 않음
+func TestWriteConnResetIsPermanent(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	peer, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = peer.(*net.TCPConn).SetLinger(0) // Close시 RST 전송
+	_ = peer.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	p := Policy{
+		MaxAttempts:     10,
+		InitialInterval: 10 * time.Millisecond,
+		Retryable:       func(error) bool { return true }, // Write는 그래도 재시도하지 않아야 함
+	}
+	_, err = Write(context.Background(), p, conn, []byte("hello"))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	var rErr *Error
+	if errors.As(err, &rErr) {
+		t.Errorf("expected no retry; actual %v", err)
+	}
+	if !IsConnReset(err) && !IsBrokenPipe(err) {
+		t.Errorf("expected ECONNRESET or EPIPE; actual %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/huGgW/network-study-with-go/ch04"
+)
+
+var ErrClientClosed = errors.New("rpc: client closed")
+
+// 하나의 연결 위에서 여러 요청을 동시에 처리하는 클라이언트
+type Client struct {
+	conn *ch04.TLVConn
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan message
+	err     error // 연결이 끊긴 원인
+
+	done chan struct{}
+}
+
+func NewClient(conn net.Conn) *Client {
+	c := &Client{
+		conn:    ch04.NewTLVConn(conn),
+		pending: make(map[uint64]chan message),
+		done:    make(chan struct{}),
+	}
+	go c.receive()
+
+	return c
+}
+
+// 응답을 읽어 correlation ID에 해당하는 요청에 전달
+func (c *Client) receive() {
+	var err error
+	for {
+		var p ch04.Payload
+		p, err = c.conn.Receive()
+		if err != nil {
+			break
+		}
+
+		m, pErr := parseMessage(p)
+		if pErr != nil {
+			err = pErr
+			break
+		}
+
+		c.mu.Lock()
+		ch, ok := c.pending[m.id]
+		delete(c.pending, m.id)
+		c.mu.Unlock()
+
+		// 이미 취소된 요청의 응답은 버림
+		if ok {
+			ch <- m
+		}
+	}
+
+	// 연결이 끊기면 대기 중인 모든 요청을 실패 처리
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = err
+	}
+	c.pending = nil
+	c.mu.Unlock()
+	close(c.done)
+}
+
+// method를 호출하고 응답을 기다림. ctx가 취소되면 응답을 기다리지 않고 반환
+// ctx에 데드라인이 있는 경우 서버의 handler에도 같은 데드라인이 적용된다.
+func (c *Client) Call(
+	ctx context.Context, method string, body ch04.Payload,
+) (ch04.Payload, error) {
+	ch := make(chan message, 1)
+
+	c.mu.Lock()
+	if c.pending == nil {
+		err := c.err
+		c.mu.Unlock()
+		return nil, errors.Join(ErrClientClosed, err)
+	}
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	req := message{id: id, method: method, body: body}
+	if deadline, ok := ctx.Deadline(); ok {
+		req.deadline = deadline
+	}
+
+	// 상대가 읽지 않아 전송이 막혀도 ctx가 끝나면 반환
+	// 전송은 고루틴에서 끝까지 진행되므로 frame이 중간에 끊겨 이후 요청이 깨지지 않는다.
+	sent := make(chan error, 1)
+	go func() { sent <- c.conn.Send(req.payload()) }()
+
+	select {
+	case err := <-sent:
+		if err != nil {
+			c.forget(id)
+			return nil, err
+		}
+	case <-ctx.Done():
+		c.forget(id)
+		return nil, ctx.Err()
+	}
+
+	select {
+	case m := <-ch:
+		if m.err != nil {
+			return nil, m.err
+		}
+		return m.body, nil
+	case <-ctx.Done():
+		c.forget(id)
+		return nil, ctx.Err()
+	case <-c.done:
+		c.mu.Lock()
+		err := c.err
+		c.mu.Unlock()
+		return nil, errors.Join(ErrClientClosed, err)
+	}
+}
+
+func (c *Client) forget(id uint64) {
+	c.mu.Lock()
+	if c.pending != nil {
+		delete(c.pending, id)
+	}
+	c.mu.Unlock()
+}
+
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = ErrClientClosed
+	}
+	c.mu.Unlock()
+
+	err := c.conn.Close()
+	<-c.done
+	return err
+}
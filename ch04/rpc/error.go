@@ -0,0 +1,137 @@
+package rpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/huGgW/network-study-with-go/ch04"
+)
+
+// ch04 기본 타입과 겹치지 않는 Error payload의 type 코드
+const ErrorType uint8 = 64
+
+type Code uint32
+
+const (
+	CodeUnknown Code = iota
+	CodeInvalidRequest
+	CodeMethodNotFound
+	CodeInternal
+	CodeCanceled
+	CodeDeadlineExceeded
+)
+
+func (c Code) String() string {
+	switch c {
+	case CodeInvalidRequest:
+		return "invalid request"
+	case CodeMethodNotFound:
+		return "method not found"
+	case CodeInternal:
+		return "internal"
+	case CodeCanceled:
+		return "canceled"
+	case CodeDeadlineExceeded:
+		return "deadline exceeded"
+	default:
+		return fmt.Sprintf("code(%d)", uint32(c))
+	}
+}
+
+func init() {
+	err := ch04.RegisterType(ErrorType, func() ch04.Payload { return new(Error) })
+	if err != nil {
+		panic(err)
+	}
+}
+
+// 서버에서 발생한 에러를 클라이언트에 전달하기 위한 TLV Payload
+// value: code(4byte) + message
+type Error struct {
+	Code    Code
+	Message string
+}
+
+func Errorf(code Code, format string, a ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, a...)}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rpc error: %s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code && (t.Message == "" || t.Message == e.Message)
+}
+
+func (e *Error) Bytes() []byte {
+	b := binary.BigEndian.AppendUint32(nil, uint32(e.Code))
+	return append(b, e.Message...)
+}
+
+func (e *Error) String() string { return e.Error() }
+
+func (e *Error) WriteTo(w io.Writer) (int64, error) {
+	value := e.Bytes()
+	if uint64(len(value)) > uint64(ch04.MaxPayloadSize) {
+		return 0, ch04.ErrMaxPayloadSize
+	}
+
+	var n int64 = 0
+	err := binary.Write(w, binary.BigEndian, ErrorType)
+	if err != nil {
+		return n, err
+	}
+	n += 1
+
+	err = binary.Write(w, binary.BigEndian, uint32(len(value)))
+	if err != nil {
+		return n, err
+	}
+	n += 4
+
+	o, err := w.Write(value)
+	return n + int64(o), err
+}
+
+func (e *Error) ReadFrom(r io.Reader) (int64, error) {
+	var n int64 = 0
+
+	var typ uint8
+	err := binary.Read(r, binary.BigEndian, &typ)
+	if err != nil {
+		return n, err
+	}
+	n += 1
+
+	if typ != ErrorType {
+		return n, errors.New("invalid Error type")
+	}
+
+	var size uint32
+	err = binary.Read(r, binary.BigEndian, &size)
+	if err != nil {
+		return n, err
+	}
+	n += 4
+
+	if size > ch04.MaxPayloadSize {
+		return n, ch04.ErrMaxPayloadSize
+	}
+	if size < 4 {
+		return n, ch04.ErrInvalidSize
+	}
+
+	value := make([]byte, size)
+	o, err := io.ReadFull(r, value)
+	if err != nil {
+		return n + int64(o), err
+	}
+
+	e.Code = Code(binary.BigEndian.Uint32(value[:4]))
+	e.Message = string(value[4:])
+	return n + int64(o), nil
+}
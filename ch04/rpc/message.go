@@ -0,0 +1,98 @@
+package rpc
+
+import (
+	"time"
+
+	"github.com/huGgW/network-study-with-go/ch04"
+)
+
+// 요청, 응답 frame은 ch04.Map으로 인코딩된다.
+//
+//	요청: {"id": Uvarint, "method": String, "body": Payload, "deadline": Timestamp}
+//	응답: {"id": Uvarint, "body": Payload} 혹은 {"id": Uvarint, "error": Error}
+//
+// id는 클라이언트가 요청마다 부여하는 correlation ID로,
+// 하나의 연결에서 여러 요청의 응답이 순서와 관계없이 도착해도 요청과 응답을 짝지을 수 있다.
+// deadline은 호출 context의 데드라인으로, 서버는 이를 handler의 context에 적용한다.
+const (
+	keyID     = "id"
+	keyMethod = "method"
+	keyBody   = "body"
+	keyError  = "error"
+
+	keyDeadline = "deadline"
+)
+
+type message struct {
+	id     uint64
+	method string
+	body   ch04.Payload
+	err    *Error
+
+	deadline time.Time
+}
+
+func (m message) payload() ch04.Payload {
+	id := ch04.Uvarint(m.id)
+	p := ch04.Map{keyID: &id}
+
+	if m.method != "" {
+		method := ch04.String(m.method)
+		p[keyMethod] = &method
+	}
+	if m.body != nil {
+		p[keyBody] = m.body
+	}
+	if m.err != nil {
+		p[keyError] = m.err
+	}
+	if !m.deadline.IsZero() {
+		deadline := ch04.Timestamp(m.deadline)
+		p[keyDeadline] = &deadline
+	}
+
+	return &p
+}
+
+func parseMessage(p ch04.Payload) (message, error) {
+	var m message
+
+	mp, ok := p.(*ch04.Map)
+	if !ok {
+		return m, Errorf(CodeInvalidRequest, "unexpected frame type %T", p)
+	}
+
+	id, ok := (*mp)[keyID].(*ch04.Uvarint)
+	if !ok {
+		return m, Errorf(CodeInvalidRequest, "missing id")
+	}
+	m.id = uint64(*id)
+
+	if v, ok := (*mp)[keyMethod]; ok {
+		method, ok := v.(*ch04.String)
+		if !ok {
+			return m, Errorf(CodeInvalidRequest, "invalid method type %T", v)
+		}
+		m.method = string(*method)
+	}
+
+	if v, ok := (*mp)[keyError]; ok {
+		e, ok := v.(*Error)
+		if !ok {
+			return m, Errorf(CodeInvalidRequest, "invalid error type %T", v)
+		}
+		m.err = e
+	}
+
+	if v, ok := (*mp)[keyDeadline]; ok {
+		deadline, ok := v.(*ch04.Timestamp)
+		if !ok {
+			return m, Errorf(CodeInvalidRequest, "invalid deadline type %T", v)
+		}
+		m.deadline = deadline.Time()
+	}
+
+	m.body = (*mp)[keyBody]
+
+	return m, nil
+}
@@ -0,0 +1,272 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/huGgW/network-study-with-go/ch04"
+)
+
+var errNegative = Errorf(100, "negative input")
+
+func newTestServer(t *testing.T) (*Client, context.CancelFunc) {
+	t.Helper()
+
+	s := NewServer()
+	handlers := map[string]HandlerFunc{
+		"echo": func(_ context.Context, body ch04.Payload) (ch04.Payload, error) {
+			return body, nil
+		},
+		"square": func(_ context.Context, body ch04.Payload) (ch04.Payload, error) {
+			n, ok := body.(*ch04.Varint)
+			if !ok {
+				return nil, Errorf(CodeInvalidRequest, "expected Varint; actual %T", body)
+			}
+			if *n < 0 {
+				return nil, errNegative
+			}
+			result := *n * *n
+			return &result, nil
+		},
+		"fail": func(context.Context, ch04.Payload) (ch04.Payload, error) {
+			return nil, errors.New("something went wrong")
+		},
+		"panic": func(context.Context, ch04.Payload) (ch04.Payload, error) {
+			panic("handler bug")
+		},
+		"sleep": func(ctx context.Context, _ ch04.Payload) (ch04.Payload, error) {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Second):
+				return nil, nil
+			}
+		},
+	}
+	for method, h := range handlers {
+		if err := s.Register(method, h); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.Register("echo", handlers["echo"]); err == nil {
+		t.Error("expected duplicate registration error")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = s.Serve(ctx, listener)
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(conn)
+
+	return client, func() {
+		_ = client.Close()
+		cancel()
+		<-done
+	}
+}
+
+func TestRPCMultiplex(t *testing.T) {
+	client, stop := newTestServer(t)
+	defer stop()
+
+	// 하나의 연결에서 여러 요청을 동시에 처리
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			n := ch04.Varint(i)
+			resp, err := client.Call(context.Background(), "square", &n)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if actual := *resp.(*ch04.Varint); actual != ch04.Varint(i*i) {
+				t.Errorf("square(%d): expected %d; actual %d", i, i*i, actual)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	msg := ch04.Map{"greeting": ptr(ch04.String("hello"))}
+	resp, err := client.Call(context.Background(), "echo", &msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.String() != msg.String() {
+		t.Errorf("expected %s; actual %s", msg, resp)
+	}
+}
+
+func TestRPCErrors(t *testing.T) {
+	client, stop := newTestServer(t)
+	defer stop()
+
+	n := ch04.Varint(-1)
+	testCases := []struct {
+		method string
+		body   ch04.Payload
+		code   Code
+	}{
+		{"unknown", nil, CodeMethodNotFound},
+		{"square", ptr(ch04.String("two")), CodeInvalidRequest},
+		{"square", &n, 100},
+		{"fail", nil, CodeInternal},
+	}
+
+	for _, c := range testCases {
+		_, err := client.Call(context.Background(), c.method, c.body)
+
+		var e *Error
+		if !errors.As(err, &e) {
+			t.Errorf("%s: expected *Error; actual %v", c.method, err)
+			continue
+		}
+		if e.Code != c.code {
+			t.Errorf("%s: expected code %s; actual %s", c.method, c.code, e.Code)
+		}
+		t.Log(e)
+	}
+
+	// 서버에서 반환한 에러 값과 비교 가능
+	_, err := client.Call(context.Background(), "square", &n)
+	if !errors.Is(err, errNegative) {
+		t.Errorf("expected %v; actual %v", errNegative, err)
+	}
+}
+
+func TestRPCTimeout(t *testing.T) {
+	client, stop := newTestServer(t)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	begin := time.Now()
+	_, err := client.Call(ctx, "sleep", nil)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded; actual: %v", err)
+	}
+	if elapsed := time.Since(begin); elapsed > 500*time.Millisecond {
+		t.Errorf("call did not time out in time: %s", elapsed)
+	}
+
+	// 타임아웃된 요청 이후에도 같은 연결로 요청 가능
+	s := ch04.String("still alive")
+	resp, err := client.Call(context.Background(), "echo", &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.String() != string(s) {
+		t.Errorf("expected %q; actual %q", s, resp)
+	}
+}
+
+// handler가 panic해도 서버는 에러를 응답하고 계속 동작
+func TestRPCHandlerPanic(t *testing.T) {
+	client, stop := newTestServer(t)
+	defer stop()
+
+	_, err := client.Call(context.Background(), "panic", nil)
+	var e *Error
+	if !errors.As(err, &e) || e.Code != CodeInternal {
+		t.Fatalf("expected internal error; actual: %v", err)
+	}
+
+	s := ch04.String("still alive")
+	resp, err := client.Call(context.Background(), "echo", &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.String() != string(s) {
+		t.Errorf("expected %q; actual %q", s, resp)
+	}
+}
+
+// 상대가 요청을 읽지 않아도 ctx의 데드라인에 반환
+func TestRPCSendTimeout(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	client := NewClient(clientConn)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	begin := time.Now()
+	_, err := client.Call(ctx, "echo", nil)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded; actual: %v", err)
+	}
+	if elapsed := time.Since(begin); elapsed > 500*time.Millisecond {
+		t.Errorf("call did not time out in time: %s", elapsed)
+	}
+}
+
+func TestRPCClientClosed(t *testing.T) {
+	client, stop := newTestServer(t)
+	stop()
+
+	_, err := client.Call(context.Background(), "echo", nil)
+	if !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("expected ErrClientClosed; actual: %v", err)
+	}
+}
+
+// client가 연결을 닫으면 처리 중인 handler의 context가 취소되고 ServeConn이 반환됨
+func TestServeConnCancelsHandlers(t *testing.T) {
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+
+	s := NewServer()
+	err := s.Register("block", func(ctx context.Context, _ ch04.Payload) (ch04.Payload, error) {
+		close(started)
+		<-ctx.Done()
+		close(canceled)
+		return nil, ctx.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverConn, clientConn := net.Pipe()
+	done := make(chan error)
+	go func() { done <- s.ServeConn(context.Background(), serverConn) }()
+
+	client := NewClient(clientConn)
+	go func() { _, _ = client.Call(context.Background(), "block", nil) }()
+
+	<-started
+	_ = client.Close()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ServeConn did not return after client closed")
+	}
+	select {
+	case <-canceled:
+	default:
+		t.Error("expected handler context to be canceled")
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,154 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/huGgW/network-study-with-go/ch04"
+)
+
+type HandlerFunc func(ctx context.Context, body ch04.Payload) (ch04.Payload, error)
+
+// method 이름으로 등록된 handler에 요청을 전달하는 서버
+type Server struct {
+	mu       sync.RWMutex
+	handlers map[string]HandlerFunc
+}
+
+func NewServer() *Server {
+	return &Server{handlers: make(map[string]HandlerFunc)}
+}
+
+func (s *Server) Register(method string, h HandlerFunc) error {
+	if method == "" || h == nil {
+		return errors.New("rpc: invalid handler")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.handlers[method]; ok {
+		return fmt.Errorf("rpc: method %q already registered", method)
+	}
+	s.handlers[method] = h
+
+	return nil
+}
+
+// context가 취소될 때까지 연결을 수락하여 각 연결을 ServeConn으로 처리
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		_ = l.Close()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		go func() {
+			defer func() { _ = conn.Close() }()
+			_ = s.ServeConn(ctx, conn)
+		}()
+	}
+}
+
+// 연결이 끊기거나 context가 취소될 때까지 요청을 읽어 handler를 호출
+// 요청마다 고루틴에서 처리하므로 응답은 요청 순서와 관계없이 전송될 수 있다.
+func (s *Server) ServeConn(ctx context.Context, conn net.Conn) error {
+	var wg sync.WaitGroup
+	defer wg.Wait() // defer는 역순으로 실행되므로 cancel 이후에 handler를 기다림
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // 연결이 끊기면 처리 중인 handler에게 취소를 알림
+
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+
+	tc := ch04.NewTLVConn(conn)
+
+	for {
+		p, err := tc.Receive()
+		if err != nil {
+			return err
+		}
+
+		req, err := parseMessage(p)
+		if err != nil {
+			// correlation ID를 알 수 없는 요청은 응답할 수 없으므로 연결 종료
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			resp := s.handle(ctx, req)
+			_ = tc.Send(resp.payload())
+		}()
+	}
+}
+
+func (s *Server) handle(ctx context.Context, req message) message {
+	resp := message{id: req.id}
+
+	s.mu.RLock()
+	h, ok := s.handlers[req.method]
+	s.mu.RUnlock()
+
+	if !ok {
+		resp.err = Errorf(CodeMethodNotFound, "%s", req.method)
+		return resp
+	}
+
+	if !req.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, req.deadline)
+		defer cancel()
+	}
+
+	body, err := call(ctx, h, req.body)
+	if err != nil {
+		resp.err = toError(err)
+		return resp
+	}
+	resp.body = body
+
+	return resp
+}
+
+// handler의 panic이 서버 전체를 멈추지 않도록 CodeInternal 에러로 변환
+func call(ctx context.Context, h HandlerFunc, body ch04.Payload) (resp ch04.Payload, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = Errorf(CodeInternal, "panic: %v", r)
+		}
+	}()
+
+	return h(ctx, body)
+}
+
+// handler가 반환한 에러를 Error payload로 변환
+func toError(err error) *Error {
+	var e *Error
+	switch {
+	case errors.As(err, &e):
+		return e
+	case errors.Is(err, context.Canceled):
+		return &Error{Code: CodeCanceled, Message: err.Error()}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Code: CodeDeadlineExceeded, Message: err.Error()}
+	default:
+		return &Error{Code: CodeInternal, Message: err.Error()}
+	}
+}
@@ -0,0 +1,199 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultConcurrency   = 100
+	defaultTimeout       = time.Second
+	defaultBannerTimeout = 500 * time.Millisecond
+	defaultBannerSize    = 256
+)
+
+// 포트 상태
+type State int
+
+const (
+	Open     State = iota // handshake 성공
+	Closed                // 연결 거부 (RST)
+	Filtered              // 응답 없음 (timeout) 혹은 ICMP unreachable
+	Error                 // 그 외의 에러 (이름 해석 실패 등)
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case Closed:
+		return "closed"
+	case Filtered:
+		return "filtered"
+	case Error:
+		return "error"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// 포트 하나의 결과
+type Result struct {
+	Host   string
+	Port   int
+	State  State
+	RTT    time.Duration // 연결 성공 혹은 실패까지 걸린 시간
+	Banner string
+	Err    error
+}
+
+// ch04.Ping과 같이 TCP handshake(connect)로 포트 상태를 확인하는 scanner
+//
+// 권한이 필요한 SYN scan과 달리 일반 사용자로 실행할 수 있지만, 상대에는 완전한 연결로 기록된다.
+// 자신이 관리하는 호스트에만 사용해야 한다.
+type Scanner struct {
+	Concurrency int           // 동시에 시도할 최대 연결 수 (기본 100)
+	Rate        float64       // 초당 최대 연결 시도 수. 0이면 제한 없음
+	Timeout     time.Duration // handshake timeout (기본 1초)
+
+	Banner        bool          // 열린 포트에서 서버가 먼저 보내는 banner를 읽음
+	BannerTimeout time.Duration // banner를 기다리는 시간 (기본 500ms)
+	BannerSize    int           // 읽을 banner의 최대 크기 (기본 256byte)
+
+	Dialer *net.Dialer // nil이면 기본 Dialer 사용. Timeout은 Scanner의 값으로 덮어씀
+}
+
+// hosts × ports를 scan하여 결과를 끝나는 순서대로 전달. 모든 scan이 끝나면 채널을 닫음
+// ctx가 취소되면 아직 시도하지 않은 포트는 건너뜀. 반환된 채널은 닫힐 때까지 읽어야 한다.
+func (s *Scanner) Scan(ctx context.Context, hosts []string, ports []int) <-chan Result {
+	type job struct {
+		host string
+		port int
+	}
+
+	jobs := make(chan job)
+	results := make(chan Result)
+
+	concurrency := s.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results <- s.Probe(ctx, j.host, j.port)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+
+		// 작업을 일정 간격으로 넘겨 연결 시도 속도를 제한
+		var tick <-chan time.Time
+		if s.Rate > 0 {
+			ticker := time.NewTicker(time.Duration(float64(time.Second) / s.Rate))
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		first := true
+		for _, host := range hosts {
+			for _, port := range ports {
+				if tick != nil && !first {
+					select {
+					case <-ctx.Done():
+						return
+					case <-tick:
+					}
+				}
+				first = false
+
+				select {
+				case <-ctx.Done():
+					return
+				case jobs <- job{host, port}:
+				}
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// 포트 하나의 상태를 확인
+func (s *Scanner) Probe(ctx context.Context, host string, port int) Result {
+	d := net.Dialer{}
+	if s.Dialer != nil {
+		d = *s.Dialer
+	}
+	d.Timeout = s.Timeout
+	if d.Timeout <= 0 {
+		d.Timeout = defaultTimeout
+	}
+
+	r := Result{Host: host, Port: port}
+
+	start := time.Now()
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	r.RTT = time.Since(start)
+	if err != nil {
+		r.State, r.Err = classify(err), err
+		return r
+	}
+	defer conn.Close()
+
+	r.State = Open
+	if s.Banner {
+		r.Banner = s.readBanner(conn)
+	}
+
+	return r
+}
+
+// 연결 실패 원인으로 포트 상태 판단
+func classify(err error) State {
+	var nErr net.Error
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return Closed
+	case errors.As(err, &nErr) && nErr.Timeout(),
+		errors.Is(err, syscall.EHOSTUNREACH),
+		errors.Is(err, syscall.ENETUNREACH):
+		return Filtered
+	}
+	return Error
+}
+
+// SSH, SMTP 등 연결 직후 서버가 먼저 보내는 banner를 읽음
+func (s *Scanner) readBanner(conn net.Conn) string {
+	timeout := s.BannerTimeout
+	if timeout <= 0 {
+		timeout = defaultBannerTimeout
+	}
+	size := s.BannerSize
+	if size <= 0 {
+		size = defaultBannerSize
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	buf := make([]byte, size)
+	n, _ := conn.Read(buf)
+
+	return strings.TrimSpace(string(buf[:n]))
+}
@@ -0,0 +1,173 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParsePorts(t *testing.T) {
+	ports, err := ParsePorts("22, 80,8000-8003,80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []int{22, 80, 8000, 8001, 8002, 8003}
+	if !slices.Equal(ports, expected) {
+		t.Errorf("expected %v; actual %v", expected, ports)
+	}
+
+	for _, spec := range []string{"", "0", "65536", "80-22", "http", "1-"} {
+		if _, err := ParsePorts(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestParseTargets(t *testing.T) {
+	hosts, err := ParseTargets([]string{"localhost,192.0.2.1", "192.0.2.0/30", "2001:db8::/127"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"localhost", "192.0.2.1", "192.0.2.0", "192.0.2.2", "192.0.2.3",
+		"2001:db8::", "2001:db8::1"}
+	if !slices.Equal(hosts, expected) {
+		t.Errorf("expected %v; actual %v", expected, hosts)
+	}
+
+	_, err = ParseTargets([]string{"10.0.0.0/8"})
+	if !errors.Is(err, ErrTooManyHosts) {
+		t.Errorf("expected ErrTooManyHosts; actual %v", err)
+	}
+}
+
+func listen(t *testing.T, banner string) int {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			if banner != "" {
+				_, _ = conn.Write([]byte(banner))
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// 연결을 받지 않는 포트 번호 (listener를 열었다 닫음)
+func closedPort(t *testing.T) int {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	_ = l.Close()
+
+	return port
+}
+
+func TestScan(t *testing.T) {
+	open := listen(t, "SSH-2.0-test\r\n")
+	silent := listen(t, "")
+	closed := closedPort(t)
+
+	s := Scanner{Concurrency: 2, Banner: true, BannerTimeout: 100 * time.Millisecond}
+
+	results := make(map[int]Result)
+	for r := range s.Scan(context.Background(), []string{"127.0.0.1"}, []int{open, silent, closed}) {
+		results[r.Port] = r
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results; actual %d", len(results))
+	}
+	if r := results[open]; r.State != Open || r.Banner != "SSH-2.0-test" {
+		t.Errorf("expected open with banner; actual %s %q (%v)", r.State, r.Banner, r.Err)
+	}
+	if r := results[silent]; r.State != Open || r.Banner != "" {
+		t.Errorf("expected open without banner; actual %s %q (%v)", r.State, r.Banner, r.Err)
+	}
+	if r := results[closed]; r.State != Closed || !errors.Is(r.Err, syscall.ECONNREFUSED) {
+		t.Errorf("expected closed; actual %s (%v)", r.State, r.Err)
+	}
+}
+
+func TestScanFiltered(t *testing.T) {
+	// handshake 전에 멈추어 응답 없는 호스트를 흉내
+	s := Scanner{
+		Timeout: 50 * time.Millisecond,
+		Dialer: &net.Dialer{
+			Control: func(_, _ string, _ syscall.RawConn) error {
+				time.Sleep(200 * time.Millisecond)
+				return nil
+			},
+		},
+	}
+
+	r := s.Probe(context.Background(), "127.0.0.1", closedPort(t))
+	if r.State != Filtered {
+		t.Errorf("expected filtered; actual %s (%v)", r.State, r.Err)
+	}
+}
+
+func TestScanRate(t *testing.T) {
+	port := listen(t, "")
+	ports := []int{port, port, port, port, port}
+
+	// 초당 20회: 5번의 시도 사이에 4번의 50ms 간격
+	s := Scanner{Rate: 20}
+
+	start := time.Now()
+	n := 0
+	for range s.Scan(context.Background(), []string{"127.0.0.1"}, ports) {
+		n++
+	}
+	elapsed := time.Since(start)
+
+	if n != len(ports) {
+		t.Errorf("expected %d results; actual %d", len(ports), n)
+	}
+	if elapsed < 190*time.Millisecond {
+		t.Errorf("expected at least 200ms; actual %s", elapsed)
+	}
+}
+
+func TestScanCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := Scanner{Rate: 10}
+
+	ports := make([]int, 100)
+	for i := range ports {
+		ports[i] = closedPort(t)
+	}
+
+	n := 0
+	for range s.Scan(ctx, []string{"127.0.0.1"}, ports) {
+		n++
+		if n == 2 {
+			cancel()
+		}
+	}
+
+	if n >= len(ports) {
+		t.Errorf("expected scan to stop early; actual %d results", n)
+	}
+}
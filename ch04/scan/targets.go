@@ -0,0 +1,107 @@
+package scan
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// CIDR 하나로 생성할 수 있는 주소는 최대 2^16개 (IPv4 /16, IPv6 /112)
+const maxCIDRBits = 16
+
+var ErrTooManyHosts = errors.New("too many hosts in CIDR")
+
+// 호스트 이름, IP, CIDR 목록을 호스트 목록으로 변환
+// CIDR은 네트워크에 포함된 모든 주소로 펼친다.
+func ParseTargets(specs []string) ([]string, error) {
+	var hosts []string
+	seen := make(map[string]bool)
+
+	add := func(h string) {
+		if !seen[h] {
+			seen[h] = true
+			hosts = append(hosts, h)
+		}
+	}
+
+	for _, spec := range specs {
+		for _, s := range strings.Split(spec, ",") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+
+			if !strings.Contains(s, "/") {
+				add(s)
+				continue
+			}
+
+			prefix, err := netip.ParsePrefix(s)
+			if err != nil {
+				return nil, err
+			}
+			prefix = prefix.Masked()
+
+			if bits := prefix.Addr().BitLen() - prefix.Bits(); bits > maxCIDRBits {
+				return nil, fmt.Errorf("%w: %s", ErrTooManyHosts, s)
+			}
+			for addr := prefix.Addr(); prefix.Contains(addr); addr = addr.Next() {
+				add(addr.String())
+			}
+		}
+	}
+
+	return hosts, nil
+}
+
+// "22,80,8000-8100"과 같은 포트 목록을 변환
+func ParsePorts(spec string) ([]int, error) {
+	var ports []int
+	seen := make(map[int]bool)
+
+	for _, s := range strings.Split(spec, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		lo, hi, isRange := strings.Cut(s, "-")
+		first, err := parsePort(lo)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if isRange {
+			last, err = parsePort(hi)
+			if err != nil {
+				return nil, err
+			}
+			if last < first {
+				return nil, fmt.Errorf("invalid port range %q", s)
+			}
+		}
+
+		for p := first; p <= last; p++ {
+			if !seen[p] {
+				seen[p] = true
+				ports = append(ports, p)
+			}
+		}
+	}
+
+	if len(ports) == 0 {
+		return nil, errors.New("no ports")
+	}
+
+	return ports, nil
+}
+
+func parsePort(s string) (int, error) {
+	p, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || p < 1 || p > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return p, nil
+}
@@ -0,0 +1,30 @@
+package sockopt
+
+import (
+	"flag"
+	"strconv"
+)
+
+// CLI에서 옵션을 설정할 수 있도록 fs에 flag 등록
+func (o *Options) RegisterFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&o.ReuseAddr, "so-reuseaddr", o.ReuseAddr, "set SO_REUSEADDR")
+	fs.BoolVar(&o.ReusePort, "so-reuseport", o.ReusePort, "set SO_REUSEPORT")
+	fs.Func("tcp-nodelay", "set TCP_NODELAY: true or false (false enables Nagle's algorithm)", func(s string) error {
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		o.NoDelay = &v
+		return nil
+	})
+	fs.DurationVar(&o.UserTimeout, "tcp-user-timeout", o.UserTimeout, "set TCP_USER_TIMEOUT")
+	fs.DurationVar(&o.KeepAliveIdle, "tcp-keepidle", o.KeepAliveIdle, "set TCP_KEEPIDLE (whole seconds)")
+	fs.DurationVar(&o.KeepAliveInterval, "tcp-keepintvl", o.KeepAliveInterval, "set TCP_KEEPINTVL (whole seconds)")
+	fs.IntVar(&o.KeepAliveCount, "tcp-keepcnt", o.KeepAliveCount, "set TCP_KEEPCNT")
+	fs.IntVar(&o.FastOpen, "tcp-fastopen", o.FastOpen, "set TCP_FASTOPEN queue length")
+	fs.DurationVar(&o.DeferAccept, "tcp-defer-accept", o.DeferAccept, "set TCP_DEFER_ACCEPT (whole seconds)")
+	fs.IntVar(&o.TOS, "ip-tos", o.TOS, "set IP_TOS / IPV6_TCLASS")
+	fs.IntVar(&o.RecvBuffer, "so-rcvbuf", o.RecvBuffer, "set SO_RCVBUF (bytes)")
+	fs.IntVar(&o.SendBuffer, "so-sndbuf", o.SendBuffer, "set SO_SNDBUF (bytes)")
+	fs.BoolVar(&o.FreeBind, "ip-freebind", o.FreeBind, "set IP_FREEBIND")
+}
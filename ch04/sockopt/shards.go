@@ -0,0 +1,77 @@
+package sockopt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+)
+
+// SO_REUSEPORT로 같은 주소에 연 listener 묶음
+//
+// 커널이 들어오는 연결을 (출발지, 목적지) 주소의 hash로 각 listener에 분배하므로,
+// listener마다 accept 고루틴을 두면 하나의 accept 루프에 연결이 몰리지 않는다.
+type Shards struct {
+	Listeners []net.Listener // Accept할 때마다 해당 shard의 연결 수가 증가
+
+	accepted []atomic.Uint64
+}
+
+// o에 ReusePort를 켜고 address에 n개의 listener를 염
+// address의 포트가 0이면 첫 listener가 받은 포트에 나머지를 염
+func (o Options) ListenShards(ctx context.Context, network, address string, n int) (*Shards, error) {
+	if n < 1 {
+		return nil, invalid("shards %d", n)
+	}
+	o.ReusePort = true
+
+	s := &Shards{accepted: make([]atomic.Uint64, n)}
+	for i := 0; i < n; i++ {
+		l, err := o.Listen(ctx, network, address)
+		if err != nil {
+			_ = s.Close()
+			return nil, err
+		}
+		if i == 0 {
+			address = l.Addr().String()
+		}
+		s.Listeners = append(s.Listeners, &shardListener{Listener: l, accepted: &s.accepted[i]})
+	}
+
+	return s, nil
+}
+
+func (s *Shards) Addr() net.Addr {
+	return s.Listeners[0].Addr()
+}
+
+// shard별로 지금까지 받은 연결 수
+func (s *Shards) Counts() []uint64 {
+	counts := make([]uint64, len(s.accepted))
+	for i := range s.accepted {
+		counts[i] = s.accepted[i].Load()
+	}
+	return counts
+}
+
+// 모든 listener를 닫음
+func (s *Shards) Close() error {
+	var errs []error
+	for _, l := range s.Listeners {
+		errs = append(errs, l.Close())
+	}
+	return errors.Join(errs...)
+}
+
+type shardListener struct {
+	net.Listener
+	accepted *atomic.Uint64
+}
+
+func (l *shardListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.accepted.Add(1)
+	}
+	return conn, err
+}
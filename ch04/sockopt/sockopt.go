@@ -0,0 +1,206 @@
+// 소켓 옵션을 선언적으로 설정
+//
+// ch04.ExampleTCPConn처럼 연결을 만든 뒤 옵션을 바꾸면 listen 소켓의 수신 버퍼(window scaling),
+// SO_REUSEPORT, TCP_DEFER_ACCEPT처럼 bind/listen/connect 이전에 설정해야 하는 옵션은 적용할 수 없다.
+// Options는 net.ListenConfig와 net.Dialer의 Control hook으로 소켓 생성 직후 옵션을 적용한다.
+package sockopt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrInvalidOption = errors.New("sockopt: invalid option")
+	ErrUnsupported   = errors.New("sockopt: unsupported platform")
+)
+
+// 적용할 소켓 옵션. 값이 0(false, nil)인 옵션은 운영체제 기본값을 유지한다.
+type Options struct {
+	ReuseAddr bool // SO_REUSEADDR. Go의 listener는 기본으로 켜져 있음
+	ReusePort bool // SO_REUSEPORT. 여러 소켓이 같은 주소에 bind하여 커널이 연결을 분산
+
+	// TCP_NODELAY. Go는 TCP 연결을 만든 뒤 항상 켜므로 Control로는 끌 수 없다.
+	// false(Nagle 사용)는 Listen으로 받은 연결과 Apply를 호출한 연결에만 적용된다.
+	NoDelay *bool
+
+	// TCP_USER_TIMEOUT. 보낸 데이터가 이 시간 동안 ACK되지 않으면 연결을 끊음
+	UserTimeout time.Duration
+
+	// TCP keepalive. 하나라도 설정하면 SO_KEEPALIVE를 켜고 Go의 기본 keepalive 설정은 사용하지 않는다.
+	KeepAliveIdle     time.Duration // TCP_KEEPIDLE. 첫 probe까지의 유휴 시간 (초 단위)
+	KeepAliveInterval time.Duration // TCP_KEEPINTVL. probe 간격 (초 단위)
+	KeepAliveCount    int           // TCP_KEEPCNT. 연결을 끊기 전 응답 없는 probe 수
+
+	// TCP_FASTOPEN. listen 소켓은 아직 완료되지 않은 TFO 요청 queue의 길이,
+	// dial 소켓은 0보다 크면 TCP_FASTOPEN_CONNECT를 켬
+	FastOpen int
+
+	// TCP_DEFER_ACCEPT. 데이터가 도착한 연결만 Accept로 전달 (listen 소켓만, 초 단위)
+	DeferAccept time.Duration
+
+	TOS int // IP_TOS (IPv6는 IPV6_TCLASS), 0 ~ 255
+
+	RecvBuffer int // SO_RCVBUF (byte). 커널은 관리용 공간을 위해 두 배로 설정함
+	SendBuffer int // SO_SNDBUF (byte)
+
+	FreeBind bool // IP_FREEBIND. 아직 인터페이스에 없는 주소에도 bind 허용
+}
+
+func (o Options) keepAlive() bool {
+	return o.KeepAliveIdle != 0 || o.KeepAliveInterval != 0 || o.KeepAliveCount != 0
+}
+
+func (o Options) hasTCP() bool {
+	return o.NoDelay != nil || o.UserTimeout != 0 || o.keepAlive() || o.FastOpen != 0 || o.DeferAccept != 0
+}
+
+func (o Options) hasIP() bool {
+	return o.TOS != 0 || o.FreeBind
+}
+
+func invalid(format string, a ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidOption, fmt.Sprintf(format, a...))
+}
+
+// 옵션 값이 커널이 받는 범위 안에 있는지 확인
+func (o Options) Validate() error {
+	switch {
+	case o.UserTimeout < 0 || o.UserTimeout.Milliseconds() > math.MaxInt32:
+		return invalid("user timeout %s", o.UserTimeout)
+	case o.KeepAliveIdle != 0 && !validSeconds(o.KeepAliveIdle, 32767):
+		return invalid("keepalive idle %s: must be 1s ~ 32767s", o.KeepAliveIdle)
+	case o.KeepAliveInterval != 0 && !validSeconds(o.KeepAliveInterval, 32767):
+		return invalid("keepalive interval %s: must be 1s ~ 32767s", o.KeepAliveInterval)
+	case o.KeepAliveCount < 0 || o.KeepAliveCount > 127:
+		return invalid("keepalive count %d: must be 0 ~ 127", o.KeepAliveCount)
+	case o.FastOpen < 0:
+		return invalid("fast open %d", o.FastOpen)
+	case o.DeferAccept != 0 && !validSeconds(o.DeferAccept, math.MaxInt32):
+		return invalid("defer accept %s", o.DeferAccept)
+	case o.TOS < 0 || o.TOS > 255:
+		return invalid("tos %d: must be 0 ~ 255", o.TOS)
+	case o.RecvBuffer < 0 || o.SendBuffer < 0:
+		return invalid("buffer size %d/%d", o.RecvBuffer, o.SendBuffer)
+	}
+	return nil
+}
+
+// 커널이 초 단위로 받는 값. 1초 미만이면 0이 되어 기본값으로 돌아가므로 거부
+func validSeconds(d time.Duration, maxSeconds int64) bool {
+	s := int64(d / time.Second)
+	return s >= 1 && s <= maxSeconds
+}
+
+// network에 맞지 않는 옵션이 있는지 확인
+func (o Options) validateNetwork(network string) error {
+	err := o.Validate()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case strings.HasPrefix(network, "tcp"):
+	case strings.HasPrefix(network, "udp"), strings.HasPrefix(network, "ip"):
+		if o.hasTCP() {
+			return invalid("tcp option on %s socket", network)
+		}
+	default:
+		if o.hasTCP() || o.hasIP() {
+			return invalid("tcp/ip option on %s socket", network)
+		}
+	}
+	return nil
+}
+
+// net.ListenConfig.Control로 사용할 함수
+func (o Options) ListenControl(network, address string, c syscall.RawConn) error {
+	err := o.validateNetwork(network)
+	if err != nil {
+		return err
+	}
+	return o.control(network, c, true)
+}
+
+// net.Dialer.Control로 사용할 함수
+func (o Options) DialControl(network, address string, c syscall.RawConn) error {
+	if o.DeferAccept != 0 {
+		return invalid("defer accept on dial socket")
+	}
+	err := o.validateNetwork(network)
+	if err != nil {
+		return err
+	}
+	return o.control(network, c, false)
+}
+
+// 옵션을 적용하는 net.ListenConfig
+func (o Options) ListenConfig() *net.ListenConfig {
+	lc := &net.ListenConfig{Control: o.ListenControl}
+	if o.keepAlive() {
+		// Go가 받은 연결마다 keepalive 주기를 덮어쓰지 않도록
+		lc.KeepAlive = -1
+	}
+	return lc
+}
+
+// 옵션을 적용하는 net.Dialer
+func (o Options) Dialer() *net.Dialer {
+	d := &net.Dialer{Control: o.DialControl}
+	if o.keepAlive() {
+		d.KeepAlive = -1
+	}
+	return d
+}
+
+// ListenConfig로 listen하고, 받은 연결마다 Apply를 호출하는 listener 반환
+func (o Options) Listen(ctx context.Context, network, address string) (net.Listener, error) {
+	l, err := o.ListenConfig().Listen(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	if o.NoDelay == nil {
+		return l, nil
+	}
+	return &listener{Listener: l, opts: o}, nil
+}
+
+func (o Options) ListenPacket(ctx context.Context, network, address string) (net.PacketConn, error) {
+	return o.ListenConfig().ListenPacket(ctx, network, address)
+}
+
+// Control 이후 Go가 덮어쓰는 옵션(TCP_NODELAY)을 이미 만들어진 연결에 적용
+func (o Options) Apply(conn net.Conn) error {
+	if o.NoDelay == nil {
+		return nil
+	}
+	tc, ok := conn.(*net.TCPConn)
+	if !ok {
+		return invalid("no delay on %T", conn)
+	}
+	return tc.SetNoDelay(*o.NoDelay)
+}
+
+type listener struct {
+	net.Listener
+	opts Options
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	err = l.opts.Apply(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
@@ -0,0 +1,111 @@
+package sockopt
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+type sockopt struct {
+	name       string
+	level, opt int
+	value      int
+}
+
+// 설정할 옵션 목록. listen이 false면 dial 소켓
+func (o Options) sockopts(network string, listen bool) []sockopt {
+	var opts []sockopt
+	add := func(name string, level, opt, value int) {
+		opts = append(opts, sockopt{name, level, opt, value})
+	}
+	boolInt := func(b bool) int {
+		if b {
+			return 1
+		}
+		return 0
+	}
+
+	if o.ReuseAddr {
+		add("SO_REUSEADDR", unix.SOL_SOCKET, unix.SO_REUSEADDR, 1)
+	}
+	if o.ReusePort {
+		add("SO_REUSEPORT", unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+	}
+	// 수신 버퍼는 listen 이전에 설정해야 SYN에 담기는 window scale에 반영됨
+	if o.RecvBuffer > 0 {
+		add("SO_RCVBUF", unix.SOL_SOCKET, unix.SO_RCVBUF, o.RecvBuffer)
+	}
+	if o.SendBuffer > 0 {
+		add("SO_SNDBUF", unix.SOL_SOCKET, unix.SO_SNDBUF, o.SendBuffer)
+	}
+
+	if o.TOS != 0 {
+		if strings.HasSuffix(network, "6") {
+			add("IPV6_TCLASS", unix.IPPROTO_IPV6, unix.IPV6_TCLASS, o.TOS)
+		} else {
+			add("IP_TOS", unix.IPPROTO_IP, unix.IP_TOS, o.TOS)
+		}
+	}
+	// IPv6 소켓에도 IP 수준의 IP_FREEBIND가 적용됨
+	if o.FreeBind {
+		add("IP_FREEBIND", unix.IPPROTO_IP, unix.IP_FREEBIND, 1)
+	}
+
+	if o.NoDelay != nil {
+		add("TCP_NODELAY", unix.IPPROTO_TCP, unix.TCP_NODELAY, boolInt(*o.NoDelay))
+	}
+	if o.UserTimeout > 0 {
+		add("TCP_USER_TIMEOUT", unix.IPPROTO_TCP, unix.TCP_USER_TIMEOUT, int(o.UserTimeout.Milliseconds()))
+	}
+	if o.keepAlive() {
+		add("SO_KEEPALIVE", unix.SOL_SOCKET, unix.SO_KEEPALIVE, 1)
+		if o.KeepAliveIdle > 0 {
+			add("TCP_KEEPIDLE", unix.IPPROTO_TCP, unix.TCP_KEEPIDLE, int(o.KeepAliveIdle/time.Second))
+		}
+		if o.KeepAliveInterval > 0 {
+			add("TCP_KEEPINTVL", unix.IPPROTO_TCP, unix.TCP_KEEPINTVL, int(o.KeepAliveInterval/time.Second))
+		}
+		if o.KeepAliveCount > 0 {
+			add("TCP_KEEPCNT", unix.IPPROTO_TCP, unix.TCP_KEEPCNT, o.KeepAliveCount)
+		}
+	}
+	if o.FastOpen > 0 {
+		if listen {
+			add("TCP_FASTOPEN", unix.IPPROTO_TCP, unix.TCP_FASTOPEN, o.FastOpen)
+		} else {
+			// connect 시 SYN에 데이터를 실어 보냄 (첫 Write까지 connect가 지연됨)
+			add("TCP_FASTOPEN_CONNECT", unix.IPPROTO_TCP, unix.TCP_FASTOPEN_CONNECT, 1)
+		}
+	}
+	if o.DeferAccept > 0 {
+		add("TCP_DEFER_ACCEPT", unix.IPPROTO_TCP, unix.TCP_DEFER_ACCEPT, int(o.DeferAccept/time.Second))
+	}
+
+	return opts
+}
+
+func (o Options) control(network string, c syscall.RawConn, listen bool) error {
+	opts := o.sockopts(network, listen)
+	if len(opts) == 0 {
+		return nil
+	}
+
+	var sockErr error
+	err := c.Control(func(fd uintptr) {
+		for _, opt := range opts {
+			sockErr = unix.SetsockoptInt(int(fd), opt.level, opt.opt, opt.value)
+			if sockErr != nil {
+				sockErr = fmt.Errorf("setting %s=%d: %w", opt.name, opt.value, sockErr)
+				return
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	return sockErr
+}
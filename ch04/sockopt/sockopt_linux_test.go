@@ -0,0 +1,245 @@
+package sockopt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func getsockopt(t *testing.T, c syscall.Conn, level, opt int) int {
+	t.Helper()
+
+	raw, err := c.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v int
+	var sockErr error
+	err = raw.Control(func(fd uintptr) {
+		v, sockErr = unix.GetsockoptInt(int(fd), level, opt)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sockErr != nil {
+		t.Fatal(sockErr)
+	}
+	return v
+}
+
+func TestListenOptions(t *testing.T) {
+	noDelay := false
+	o := Options{
+		ReusePort:         true,
+		NoDelay:           &noDelay,
+		UserTimeout:       1500 * time.Millisecond,
+		KeepAliveIdle:     30 * time.Second,
+		KeepAliveInterval: 5 * time.Second,
+		KeepAliveCount:    4,
+		DeferAccept:       time.Second,
+		TOS:               0x10,
+		RecvBuffer:        64 << 10,
+	}
+
+	l, err := o.Listen(context.Background(), "tcp4", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	ls := l.(*listener).Listener.(*net.TCPListener)
+	for name, c := range map[string]struct{ level, opt, expected int }{
+		"SO_REUSEPORT":     {unix.SOL_SOCKET, unix.SO_REUSEPORT, 1},
+		"TCP_USER_TIMEOUT": {unix.IPPROTO_TCP, unix.TCP_USER_TIMEOUT, 1500},
+		"TCP_KEEPIDLE":     {unix.IPPROTO_TCP, unix.TCP_KEEPIDLE, 30},
+		"TCP_KEEPINTVL":    {unix.IPPROTO_TCP, unix.TCP_KEEPINTVL, 5},
+		"TCP_KEEPCNT":      {unix.IPPROTO_TCP, unix.TCP_KEEPCNT, 4},
+		"IP_TOS":           {unix.IPPROTO_IP, unix.IP_TOS, 0x10},
+		"SO_RCVBUF":        {unix.SOL_SOCKET, unix.SO_RCVBUF, 128 << 10}, // 커널이 두 배로 설정
+	} {
+		if v := getsockopt(t, ls, c.level, c.opt); v != c.expected {
+			t.Errorf("%s: expected %d; actual %d", name, c.expected, v)
+		}
+	}
+
+	// TCP_DEFER_ACCEPT는 데이터가 와야 Accept되므로 연결 후 바로 데이터를 보냄
+	client, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	_, err = client.Write([]byte("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// 받은 연결은 listen 소켓의 옵션을 물려받고, TCP_NODELAY는 Accept에서 다시 설정됨
+	tc := conn.(*net.TCPConn)
+	if v := getsockopt(t, tc, unix.IPPROTO_TCP, unix.TCP_NODELAY); v != 0 {
+		t.Errorf("TCP_NODELAY: expected 0; actual %d", v)
+	}
+	if v := getsockopt(t, tc, unix.SOL_SOCKET, unix.SO_KEEPALIVE); v != 1 {
+		t.Errorf("SO_KEEPALIVE: expected 1; actual %d", v)
+	}
+	if v := getsockopt(t, tc, unix.IPPROTO_TCP, unix.TCP_KEEPIDLE); v != 30 {
+		t.Errorf("TCP_KEEPIDLE: expected 30; actual %d", v)
+	}
+}
+
+func TestReusePort(t *testing.T) {
+	o := Options{ReusePort: true}
+
+	l1, err := o.Listen(context.Background(), "tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l1.Close()
+
+	l2, err := o.Listen(context.Background(), "tcp", l1.Addr().String())
+	if err != nil {
+		t.Fatalf("expected second listener on %s: %v", l1.Addr(), err)
+	}
+	_ = l2.Close()
+
+	// 옵션이 없으면 같은 주소에 bind할 수 없음
+	_, err = Options{}.Listen(context.Background(), "tcp", l1.Addr().String())
+	if !errors.Is(err, syscall.EADDRINUSE) {
+		t.Errorf("expected EADDRINUSE; actual %v", err)
+	}
+}
+
+func TestDialOptions(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	d := Options{UserTimeout: time.Second, SendBuffer: 32 << 10, KeepAliveCount: 3}.Dialer()
+	d.Timeout = time.Second
+	conn, err := d.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	tc := conn.(*net.TCPConn)
+	if v := getsockopt(t, tc, unix.IPPROTO_TCP, unix.TCP_USER_TIMEOUT); v != 1000 {
+		t.Errorf("TCP_USER_TIMEOUT: expected 1000; actual %d", v)
+	}
+	if v := getsockopt(t, tc, unix.SOL_SOCKET, unix.SO_SNDBUF); v != 64<<10 {
+		t.Errorf("SO_SNDBUF: expected %d; actual %d", 64<<10, v)
+	}
+	if v := getsockopt(t, tc, unix.IPPROTO_TCP, unix.TCP_KEEPCNT); v != 3 {
+		t.Errorf("TCP_KEEPCNT: expected 3; actual %d", v)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	noDelay := true
+	for name, c := range map[string]struct {
+		opts    Options
+		network string
+	}{
+		"negative user timeout": {Options{UserTimeout: -time.Second}, "tcp"},
+		"sub-second keepalive":  {Options{KeepAliveIdle: 500 * time.Millisecond}, "tcp"},
+		"keepalive count":       {Options{KeepAliveCount: 128}, "tcp"},
+		"tos":                   {Options{TOS: 256}, "tcp"},
+		"buffer":                {Options{RecvBuffer: -1}, "tcp"},
+		"tcp option on udp":     {Options{NoDelay: &noDelay}, "udp"},
+		"ip option on unix":     {Options{TOS: 0x10}, "unix"},
+	} {
+		_, err := c.opts.ListenConfig().Listen(context.Background(), c.network, "127.0.0.1:")
+		if c.network == "udp" {
+			_, err = c.opts.ListenPacket(context.Background(), c.network, "127.0.0.1:")
+		}
+		if !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("%s: expected ErrInvalidOption; actual %v", name, err)
+		}
+	}
+
+	_, err := Options{DeferAccept: time.Second}.Dialer().Dial("tcp", "127.0.0.1:1")
+	if !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("expected ErrInvalidOption for defer accept on dial; actual %v", err)
+	}
+
+	// UDP에 적용 가능한 옵션
+	pc, err := Options{ReuseAddr: true, TOS: 0x10, RecvBuffer: 1 << 16}.ListenPacket(context.Background(), "udp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = pc.Close()
+}
+
+func TestListenShards(t *testing.T) {
+	s, err := Options{}.ListenShards(context.Background(), "tcp", "127.0.0.1:", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, l := range s.Listeners {
+		if l.Addr().String() != s.Addr().String() {
+			t.Fatalf("expected %s; actual %s", s.Addr(), l.Addr())
+		}
+		go func(l net.Listener) {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				_ = conn.Close()
+			}
+		}(l)
+	}
+
+	// 출발지 포트가 달라지므로 연결이 여러 shard로 분산됨
+	const conns = 64
+	for i := 0; i < conns; i++ {
+		conn, err := net.Dial("tcp", s.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = conn.Close()
+	}
+
+	var total uint64
+	used := 0
+	deadline := time.Now().Add(time.Second)
+	for {
+		total, used = 0, 0
+		for _, c := range s.Counts() {
+			total += c
+			if c > 0 {
+				used++
+			}
+		}
+		if total == conns || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if total != conns {
+		t.Errorf("expected %d accepted; actual %d", conns, total)
+	}
+	if used < 2 {
+		t.Errorf("expected connections on multiple shards; actual %v", s.Counts())
+	}
+
+	_, err = Options{}.ListenShards(context.Background(), "tcp", "127.0.0.1:", 0)
+	if !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("expected ErrInvalidOption; actual %v", err)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package sockopt
+
+import "syscall"
+
+// 옵션 번호와 단위가 OS마다 다르므로 linux만 지원. 설정한 옵션이 없으면 아무것도 하지 않음
+func (o Options) control(network string, c syscall.RawConn, listen bool) error {
+	if o != (Options{}) {
+		return ErrUnsupported
+	}
+	return nil
+}
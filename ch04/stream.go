@@ -0,0 +1,183 @@
+package ch04
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const DefaultChunkSize = 64 << 10 // 64KB
+
+// Stream은 연결에서 frame을 읽는 대로 데이터를 반환하므로
+// 값 전체를 먼저 인코딩/디코딩하는 List, Map, Flagged의 원소가 될 수 없다.
+var ErrNestedStream = errors.New("stream cannot be nested in a composite payload")
+
+// Stream TLV Payload Data Type
+//
+// MaxPayloadSize보다 큰 데이터를 ChunkType continuation frame의 연속으로 전송한다.
+// 각 frame은 일반 TLV와 같이 type(1byte) + size(4byte) + data로 구성되며,
+// size가 0인 frame이 stream의 끝을 나타낸다.
+//
+//	[ChunkType][size][data] [ChunkType][size][data] ... [ChunkType][0]
+//
+// 송신측은 NewStream으로 생성하여 WriteTo를 호출하면 원본 reader에서 읽는 대로 frame을 전송하고,
+// 수신측은 Decode로 받은 Stream의 Body를 읽으면 frame을 읽는 대로 데이터를 반환한다.
+// 따라서 양쪽 모두 전체 데이터를 메모리에 보관하지 않는다.
+//
+// 다른 Payload와 달리 ReadFrom은 첫 frame의 헤더만 읽으므로,
+// 같은 reader에서 다음 Payload를 디코딩하기 전에 Stream을 끝까지 읽어야 한다.
+// 같은 이유로 List, Map, Flagged의 원소로 사용할 수 없다 (ErrNestedStream).
+type Stream struct {
+	ChunkSize int // WriteTo 시 frame의 최대 크기 (기본 64KB)
+
+	src      io.Reader // 전송할 데이터 혹은 수신 중인 frame의 데이터
+	buffered []byte    // Bytes 호출 시 읽은 전체 데이터
+}
+
+// src의 데이터를 전송할 Stream 생성
+func NewStream(src io.Reader) *Stream {
+	return &Stream{src: src}
+}
+
+// 남은 데이터를 읽는 reader.
+// Stream 자체는 io.WriterTo를 구현하므로 io.Copy(dst, stream)은 frame을 그대로 복사한다.
+// 데이터만 복사하려면 io.Copy(dst, stream.Body())를 사용해야 한다.
+func (m *Stream) Body() io.Reader {
+	if m.src == nil {
+		return bytes.NewReader(nil)
+	}
+	return m.src
+}
+
+// 남은 데이터를 모두 메모리로 읽어 반환. 큰 stream에서는 사용을 피해야 함
+func (m *Stream) Bytes() []byte {
+	if m.buffered == nil {
+		m.buffered, _ = io.ReadAll(m.Body())
+		if m.buffered == nil {
+			m.buffered = []byte{}
+		}
+		// 이후 WriteTo, Body에서도 같은 데이터를 사용할 수 있도록 교체
+		m.src = bytes.NewReader(m.buffered)
+	}
+	return m.buffered
+}
+
+func (m *Stream) String() string { return string(m.Bytes()) }
+
+func (m *Stream) chunkSize() int {
+	if m.ChunkSize <= 0 || uint64(m.ChunkSize) > uint64(MaxPayloadSize) {
+		return DefaultChunkSize
+	}
+	return m.ChunkSize
+}
+
+// 남은 데이터를 continuation frame으로 w에 기록하고 종료 frame을 기록
+// 수신한 Stream의 WriteTo를 호출하면 버퍼링 없이 다른 연결로 전달할 수 있다.
+func (m *Stream) WriteTo(w io.Writer) (int64, error) {
+	return m.writeChunks(w, m.chunkSize())
+}
+
+// 최대 size byte의 frame으로 기록. ChunkSize를 바꾸지 않고 연결별 크기로 보낼 때 사용
+func (m *Stream) writeChunks(w io.Writer, size int) (int64, error) {
+	var n int64 = 0
+	buf := make([]byte, size)
+	body := m.Body()
+
+	for {
+		o, rErr := body.Read(buf)
+		if o > 0 {
+			c, err := writeTLV(w, ChunkType, buf[:o])
+			n += c
+			if err != nil {
+				return n, err
+			}
+		}
+
+		if rErr == io.EOF {
+			break
+		}
+		if rErr != nil {
+			return n, rErr
+		}
+	}
+
+	// 종료 frame
+	c, err := writeTLV(w, ChunkType, nil)
+	return n + c, err
+}
+
+// 첫 frame의 헤더만 읽고 이후 데이터는 Body를 읽을 때 읽음
+func (m *Stream) ReadFrom(r io.Reader) (int64, error) {
+	return m.readFrom(r, MaxPayloadSize)
+}
+
+// 모든 frame의 크기를 maxSize로 제한하여 읽음
+func (m *Stream) readFrom(r io.Reader, maxSize uint32) (int64, error) {
+	cr := &chunkReader{r: r, maxSize: maxSize}
+	n, err := cr.next()
+	if err != nil {
+		return n, err
+	}
+
+	m.src = cr
+	m.buffered = nil
+	return n, nil
+}
+
+// continuation frame의 데이터만 이어서 읽는 reader
+type chunkReader struct {
+	r         io.Reader
+	maxSize   uint32 // frame의 최대 크기
+	remaining uint32 // 현재 frame에서 읽지 않은 byte 수
+	done      bool   // 종료 frame을 읽음
+}
+
+// 다음 frame의 헤더 읽기
+func (c *chunkReader) next() (int64, error) {
+	var header [5]byte
+	n, err := io.ReadFull(c.r, header[:])
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF // stream 도중 연결 종료
+		}
+		return int64(n), err
+	}
+
+	if header[0] != ChunkType {
+		return int64(n), fmt.Errorf("invalid stream frame type: %d", header[0])
+	}
+
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > c.maxSize {
+		return int64(n), ErrMaxPayloadSize
+	}
+
+	c.remaining = size
+	c.done = size == 0
+	return int64(n), nil
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for c.remaining == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+		if _, err := c.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	if uint32(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+
+	n, err := c.r.Read(p)
+	c.remaining -= uint32(n)
+	if err == io.EOF {
+		// frame 도중 연결 종료
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
@@ -0,0 +1,177 @@
+package ch04
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"testing/iotest"
+)
+
+func TestStreamLargerThanMaxPayloadSize(t *testing.T) {
+	// MaxPayloadSize보다 큰 데이터를 전송
+	size := int64(MaxPayloadSize) + 1<<20
+
+	listener, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	sent := sha256.New()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		// 전송하는 데이터를 메모리에 보관하지 않고 생성하는 대로 전송
+		src := io.TeeReader(io.LimitReader(rand.Reader, size), sent)
+		payloads := []Payload{
+			ptr(String("before")),
+			NewStream(src),
+			ptr(String("after")),
+		}
+		for _, p := range payloads {
+			if _, err := p.WriteTo(conn); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	d := NewDecoder(conn)
+	p, err := d.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.String() != "before" {
+		t.Errorf("expected %q; actual %q", "before", p)
+	}
+
+	p, err = d.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, ok := p.(*Stream)
+	if !ok {
+		t.Fatalf("expected *Stream; actual %T", p)
+	}
+
+	// 다음 Payload를 디코딩하기 전에 stream을 끝까지 읽음
+	received := sha256.New()
+	n, err := io.Copy(received, stream.Body())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != size {
+		t.Errorf("expected %d bytes; actual %d", size, n)
+	}
+
+	p, err = d.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.String() != "after" {
+		t.Errorf("expected %q; actual %q", "after", p)
+	}
+
+	if !bytes.Equal(sent.Sum(nil), received.Sum(nil)) {
+		t.Error("stream data mismatch")
+	}
+}
+
+func TestStreamOneByteReader(t *testing.T) {
+	data := []byte("Don't communicate by sharing memory, share memory by communicating.")
+
+	var buf bytes.Buffer
+	s := NewStream(bytes.NewReader(data))
+	s.ChunkSize = 7
+	_, err := s.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoded := buf.Bytes()
+
+	// frame 헤더와 데이터가 1byte씩 나뉘어 도착해도 전체 데이터를 읽어야 함
+	p, err := decode(iotest.OneByteReader(bytes.NewReader(encoded)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := io.ReadAll(p.(*Stream).Body())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, actual) {
+		t.Errorf("expected %q; actual %q", data, actual)
+	}
+
+	// 수신한 stream을 그대로 다시 인코딩하면 같은 frame 생성
+	received := new(Stream)
+	_, err = received.ReadFrom(bytes.NewReader(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	received.ChunkSize = 7
+	var forwarded bytes.Buffer
+	_, err = received.WriteTo(iotest.TruncateWriter(&forwarded, int64(len(encoded))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(encoded, forwarded.Bytes()) {
+		t.Error("forwarded stream mismatch")
+	}
+
+	// 종료 frame 없이 끊긴 stream은 에러
+	p, err = decode(bytes.NewReader(encoded[:len(encoded)-5]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.ReadAll(p.(*Stream).Body())
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF; actual: %v", err)
+	}
+}
+
+// Stream은 List, Map, Flagged의 원소로 인코딩/디코딩할 수 없음
+func TestStreamNested(t *testing.T) {
+	for _, p := range []Payload{
+		&List{NewStream(bytes.NewReader([]byte("data")))},
+		&Map{"stream": NewStream(bytes.NewReader([]byte("data")))},
+		&Flagged{Flags: FlagCRC32C, Payload: NewStream(bytes.NewReader([]byte("data")))},
+	} {
+		_, err := p.WriteTo(io.Discard)
+		if err != ErrNestedStream {
+			t.Errorf("%T: expected ErrNestedStream; actual %v", p, err)
+		}
+	}
+
+	// 직접 만든 List 안의 Stream frame도 거부
+	var frames bytes.Buffer
+	_, err := NewStream(bytes.NewReader([]byte("data"))).WriteTo(&frames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list bytes.Buffer
+	_, err = writeTLV(&list, ListType, frames.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = decode(&list)
+	if err != ErrNestedStream {
+		t.Errorf("expected ErrNestedStream; actual %v", err)
+	}
+}
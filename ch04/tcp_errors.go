@@ -20,6 +20,7 @@ func ZeroWindowErr() error {
 		// handle로 인해 긴 시간 동안 blocking되고, 수신 버퍼는 계속 채워지게 됨.
 		// 결과적으로 수신 버퍼가 가득 차 더 이상 데이터를 받지 못하는 zero window 상태가 됨.
 		// 이 경우 송신자에서 데이터 흐름을 throttle을 하여 해소 시도 가능
+		// tcpinfo.Get으로 수신자의 RecvQueue, 송신자의 SendQueue가 계속 늘어나는지 확인하여 진단 가능
 	}
 }
 func handle(buf []byte) {
@@ -49,6 +50,7 @@ func CloseWaitStuckErr() error {
 				n, err := c.Read(buf)
 				// 해당 err가 발생시 defer를 등록하지 않으면 connection close 없이 고루틴 종료
 				// TCP 소켓은 CLOSE_WAIT 상태에 머물러 있게 됨
+				// tcpinfo.Get의 State로 확인 가능하며, leak.Tracker로 listener를 감싸면 생성 위치와 함께 탐지
 				if err != nil {
 					return
 				}
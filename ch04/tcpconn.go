@@ -10,6 +10,7 @@ import (
 
 // net.TCPConn의 경우 일부 메서드가 운영체제에 따라 사용할 수 없거나 hard limit이 존재할 수 있음.
 // 따라서 반드시 필요한 경우에만 해당 객체를 이용하여야 한다.
+// bind/listen/connect 이전에 설정해야 하는 옵션(SO_REUSEPORT, TCP_DEFER_ACCEPT 등)은 sockopt.Options 참고
 func ExampleTCPConn() error {
 	// Simple case: type assertion
 	ls, err := net.Listen("tcp", "127.0.0.1:")
@@ -0,0 +1,123 @@
+// 커널의 TCP_INFO로 연결 상태를 확인
+//
+// ch04/tcp_errors.go의 zero window, CLOSE_WAIT 같은 문제는 애플리케이션에서 보이지 않으므로
+// 커널이 소켓마다 유지하는 통계를 읽어 느린 상대나 닫히지 않은 연결을 진단한다.
+package tcpinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/huGgW/network-study-with-go/ch04"
+)
+
+var ErrUnsupported = errors.New("tcpinfo: unsupported platform")
+
+// TCP 상태 (linux include/net/tcp_states.h)
+type State uint8
+
+const (
+	Established State = iota + 1
+	SynSent
+	SynRecv
+	FinWait1
+	FinWait2
+	TimeWait
+	Close
+	CloseWait
+	LastAck
+	Listen
+	Closing
+)
+
+var stateNames = [...]string{
+	Established: "ESTABLISHED",
+	SynSent:     "SYN_SENT",
+	SynRecv:     "SYN_RECV",
+	FinWait1:    "FIN_WAIT1",
+	FinWait2:    "FIN_WAIT2",
+	TimeWait:    "TIME_WAIT",
+	Close:       "CLOSE",
+	CloseWait:   "CLOSE_WAIT",
+	LastAck:     "LAST_ACK",
+	Listen:      "LISTEN",
+	Closing:     "CLOSING",
+}
+
+func (s State) String() string {
+	if int(s) < len(stateNames) && stateNames[s] != "" {
+		return stateNames[s]
+	}
+	return fmt.Sprintf("State(%d)", uint8(s))
+}
+
+// 연결 하나의 TCP 통계
+type Info struct {
+	State State
+
+	RTT    time.Duration // smoothed RTT
+	RTTVar time.Duration // RTT 편차
+	RTO    time.Duration // 재전송 timeout
+
+	Retransmits  uint8  // 현재 세그먼트의 연속 재전송 횟수 (timeout마다 증가)
+	TotalRetrans uint32 // 연결 전체의 재전송 세그먼트 수
+
+	Cwnd    uint32 // congestion window (세그먼트 단위)
+	MSS     uint32 // 송신 MSS
+	Unacked uint32 // 전송했지만 ACK를 받지 못한 세그먼트 수
+
+	// 애플리케이션이 아직 읽지 않은 수신 버퍼의 데이터 (byte)
+	// 계속 늘어나면 읽는 쪽이 느려 곧 zero window가 됨
+	RecvQueue int
+	// 아직 상대가 ACK하지 않은 송신 버퍼의 데이터 (byte)
+	// 계속 늘어나면 상대가 느리거나 zero window 상태
+	SendQueue int
+}
+
+func (i Info) String() string {
+	return fmt.Sprintf("state=%s rtt=%s rttvar=%s rto=%s retrans=%d/%d cwnd=%d mss=%d unacked=%d recvq=%d sendq=%d",
+		i.State, i.RTT, i.RTTVar, i.RTO, i.Retransmits, i.TotalRetrans,
+		i.Cwnd, i.MSS, i.Unacked, i.RecvQueue, i.SendQueue)
+}
+
+// conn의 TCP 통계를 주기적으로 Monitor에 기록
+type Sampler struct {
+	Interval time.Duration // 기록 간격 (기본 1초)
+	Monitor  *ch04.Monitor // nil이면 log.Default() 사용
+}
+
+// ctx가 취소되거나 통계를 읽지 못할 때(연결이 닫힌 경우 등)까지 기록
+// ctx 취소로 끝나면 nil 반환
+func (s *Sampler) Run(ctx context.Context, conn *net.TCPConn) error {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	monitor := s.Monitor
+	if monitor == nil {
+		monitor = &ch04.Monitor{Logger: log.Default()}
+	}
+
+	prefix := fmt.Sprintf("%s->%s", conn.LocalAddr(), conn.RemoteAddr())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		info, err := Get(conn)
+		if err != nil {
+			return err
+		}
+		monitor.Printf("%s: %s", prefix, info)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package tcpinfo
+
+import (
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// conn의 TCP 통계를 읽음
+// conn.File()과 달리 SyscallConn을 사용하므로 file descriptor를 복제하지 않고,
+// 연결을 blocking 모드로 바꾸지도 않는다.
+func Get(conn *net.TCPConn) (Info, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return Info{}, err
+	}
+
+	var (
+		ti        *unix.TCPInfo
+		inq, outq int
+		sockErr   error
+	)
+	err = raw.Control(func(fd uintptr) {
+		ti, sockErr = unix.GetsockoptTCPInfo(int(fd), unix.IPPROTO_TCP, unix.TCP_INFO)
+		if sockErr != nil {
+			return
+		}
+		inq, sockErr = unix.IoctlGetInt(int(fd), unix.SIOCINQ)
+		if sockErr != nil {
+			return
+		}
+		outq, sockErr = unix.IoctlGetInt(int(fd), unix.SIOCOUTQ)
+	})
+	if err != nil {
+		return Info{}, err
+	}
+	if sockErr != nil {
+		return Info{}, sockErr
+	}
+
+	// 커널은 시간을 microsecond 단위로 보고
+	return Info{
+		State:        State(ti.State),
+		RTT:          time.Duration(ti.Rtt) * time.Microsecond,
+		RTTVar:       time.Duration(ti.Rttvar) * time.Microsecond,
+		RTO:          time.Duration(ti.Rto) * time.Microsecond,
+		Retransmits:  ti.Retransmits,
+		TotalRetrans: ti.Total_retrans,
+		Cwnd:         ti.Snd_cwnd,
+		MSS:          ti.Snd_mss,
+		Unacked:      ti.Unacked,
+		RecvQueue:    inq,
+		SendQueue:    outq,
+	}, nil
+}
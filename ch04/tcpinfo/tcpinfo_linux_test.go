@@ -0,0 +1,156 @@
+package tcpinfo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/huGgW/network-study-with-go/ch04"
+)
+
+// loopback TCP 연결 한 쌍
+func tcpPair(t *testing.T) (client, server *net.TCPConn) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	c, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = c.Close()
+		_ = s.Close()
+	})
+
+	return c.(*net.TCPConn), s.(*net.TCPConn)
+}
+
+// cond를 만족할 때까지 통계를 다시 읽음
+func waitFor(t *testing.T, conn *net.TCPConn, cond func(Info) bool) Info {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		info, err := Get(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cond(info) || time.Now().After(deadline) {
+			return info
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestGet(t *testing.T) {
+	client, server := tcpPair(t)
+
+	info, err := Get(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.State != Established {
+		t.Errorf("expected ESTABLISHED; actual %s", info.State)
+	}
+	if info.Cwnd == 0 || info.MSS == 0 {
+		t.Errorf("expected non-zero cwnd and mss; actual %s", info)
+	}
+
+	// 읽지 않은 데이터는 수신 queue에 남음
+	_, err = server.Write(make([]byte, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info = waitFor(t, client, func(i Info) bool { return i.RecvQueue == 100 })
+	if info.RecvQueue != 100 {
+		t.Errorf("expected recvq 100; actual %d", info.RecvQueue)
+	}
+	if info.RTT == 0 {
+		t.Errorf("expected non-zero rtt after data exchange")
+	}
+}
+
+func TestGetCloseWait(t *testing.T) {
+	client, server := tcpPair(t)
+
+	// 상대가 연결을 닫았는데 이쪽에서 닫지 않으면 CLOSE_WAIT에 머무름
+	_ = server.Close()
+
+	info := waitFor(t, client, func(i Info) bool { return i.State == CloseWait })
+	if info.State != CloseWait {
+		t.Errorf("expected CLOSE_WAIT; actual %s", info.State)
+	}
+
+	_ = client.Close()
+	_, err := Get(client)
+	if !errors.Is(err, net.ErrClosed) {
+		t.Errorf("expected net.ErrClosed; actual %v", err)
+	}
+}
+
+// 여러 고루틴에서 쓰는 buffer
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestSampler(t *testing.T) {
+	client, _ := tcpPair(t)
+
+	out := new(syncBuffer)
+	s := Sampler{
+		Interval: 10 * time.Millisecond,
+		Monitor:  &ch04.Monitor{Logger: log.New(out, "", 0)},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 55*time.Millisecond)
+	defer cancel()
+
+	err := s.Run(ctx, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) < 3 {
+		t.Errorf("expected at least 3 samples; actual %d", len(lines))
+	}
+	prefix := client.LocalAddr().String() + "->" + client.RemoteAddr().String() + ": state=ESTABLISHED"
+	if !strings.HasPrefix(lines[0], prefix) {
+		t.Errorf("expected prefix %q; actual %q", prefix, lines[0])
+	}
+
+	// 연결이 닫히면 에러와 함께 종료
+	_ = client.Close()
+	err = s.Run(context.Background(), client)
+	if !errors.Is(err, net.ErrClosed) {
+		t.Errorf("expected net.ErrClosed; actual %v", err)
+	}
+}
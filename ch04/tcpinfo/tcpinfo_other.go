@@ -0,0 +1,10 @@
+//go:build !linux
+
+package tcpinfo
+
+import "net"
+
+// TCP_INFO의 구조는 OS마다 다르므로 linux만 지원
+func Get(conn *net.TCPConn) (Info, error) {
+	return Info{}, ErrUnsupported
+}
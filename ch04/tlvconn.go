@@ -0,0 +1,196 @@
+package ch04
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// net.Conn 위에서 TLV Payload 단위로 주고받는 연결
+//
+// Send는 여러 고루틴에서 동시에 호출해도 frame이 섞이지 않는다.
+// Receive는 한 고루틴에서만 호출하는 것을 가정하며, Stream을 받은 경우
+// 다음 Receive 전에 Stream의 Body를 끝까지 읽어야 한다.
+type TLVConn struct {
+	net.Conn
+
+	MaxPayloadSize uint32        // 연결별 최대 payload 크기. 0이면 패키지의 MaxPayloadSize
+	ReadTimeout    time.Duration // Receive마다 설정할 read 데드라인. 0이면 설정하지 않음
+	WriteTimeout   time.Duration // Send마다 설정할 write 데드라인. 0이면 설정하지 않음
+	Flags          uint8         // 0이 아니면 Stream 외의 payload를 Flagged로 감싸서 전송
+
+	rmu sync.Mutex
+	r   *bufio.Reader
+	dec *Decoder
+
+	wmu sync.Mutex
+
+	bytesSent      atomic.Uint64
+	bytesReceived  atomic.Uint64
+	framesSent     atomic.Uint64
+	framesReceived atomic.Uint64
+}
+
+// 방향별 전송량
+type TLVStats struct {
+	BytesSent      uint64
+	BytesReceived  uint64
+	FramesSent     uint64
+	FramesReceived uint64
+}
+
+func NewTLVConn(conn net.Conn) *TLVConn {
+	c := &TLVConn{Conn: conn}
+	// 헤더를 1byte, 4byte씩 읽을 때마다 system call이 발생하지 않도록 buffering
+	c.r = bufio.NewReader(countingReader{r: conn, n: &c.bytesReceived})
+	c.dec = NewDecoder(c.r)
+	return c
+}
+
+// DefaultRegistry 대신 사용할 registry 설정
+func (c *TLVConn) SetRegistry(r *Registry) {
+	c.rmu.Lock()
+	c.dec.Registry = r
+	c.rmu.Unlock()
+}
+
+func (c *TLVConn) maxPayloadSize() uint32 {
+	if c.MaxPayloadSize == 0 || c.MaxPayloadSize > MaxPayloadSize {
+		return MaxPayloadSize
+	}
+	return c.MaxPayloadSize
+}
+
+// payload 하나를 frame으로 전송
+func (c *TLVConn) Send(p Payload) error {
+	var frame []byte
+
+	// Stream은 데이터 전체를 버퍼링하지 않도록 연결에 바로 기록
+	stream, isStream := p.(*Stream)
+	if !isStream {
+		if c.Flags != 0 {
+			p = &Flagged{Flags: c.Flags, Payload: p}
+		}
+
+		var buf bytes.Buffer
+		_, err := p.WriteTo(&buf)
+		if err != nil {
+			return err
+		}
+
+		frame = buf.Bytes()
+		if len(frame) >= 5 &&
+			binary.BigEndian.Uint32(frame[1:5]) > c.maxPayloadSize() {
+			return ErrMaxPayloadSize
+		}
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if c.WriteTimeout > 0 {
+		err := c.Conn.SetWriteDeadline(time.Now().Add(c.WriteTimeout))
+		if err != nil {
+			return err
+		}
+	}
+
+	w := countingWriter{w: c.Conn, n: &c.bytesSent}
+	if isStream {
+		// 호출자의 Stream을 바꾸지 않도록 연결별 chunk 크기는 따로 계산
+		size := stream.ChunkSize
+		if size <= 0 || uint32(size) > c.maxPayloadSize() {
+			size = int(min(DefaultChunkSize, c.maxPayloadSize()))
+		}
+		// 각 frame 헤더와 데이터가 개별 system call로 전송되지 않도록 buffering
+		bw := bufio.NewWriter(w)
+		_, err := stream.writeChunks(bw, size)
+		if err == nil {
+			err = bw.Flush()
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		// 하나의 Write로 frame 전체를 기록
+		_, err := w.Write(frame)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.framesSent.Add(1)
+	return nil
+}
+
+// 다음 payload를 수신
+func (c *TLVConn) Receive() (Payload, error) {
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+
+	if c.ReadTimeout > 0 {
+		err := c.Conn.SetReadDeadline(time.Now().Add(c.ReadTimeout))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 헤더를 미리 확인하여 연결별 최대 크기를 넘는 payload는 메모리를 할당하기 전에 거부
+	header, err := c.r.Peek(5)
+	if err != nil {
+		if err == io.EOF && len(header) > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if binary.BigEndian.Uint32(header[1:]) > c.maxPayloadSize() {
+		return nil, ErrMaxPayloadSize
+	}
+
+	// 압축된 frame은 헤더만으로 크기를 알 수 없고, Stream은 이후 frame의 헤더를 Body를 읽을 때 읽으므로
+	// 압축을 푼 뒤와 이후 frame에도 같은 제한 적용
+	c.dec.MaxPayloadSize = c.maxPayloadSize()
+	p, err := c.dec.Decode()
+	if err != nil {
+		return nil, err
+	}
+
+	c.framesReceived.Add(1)
+	return p, nil
+}
+
+func (c *TLVConn) Stats() TLVStats {
+	return TLVStats{
+		BytesSent:      c.bytesSent.Load(),
+		BytesReceived:  c.bytesReceived.Load(),
+		FramesSent:     c.framesSent.Load(),
+		FramesReceived: c.framesReceived.Load(),
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n *atomic.Uint64
+}
+
+func (c countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(uint64(n))
+	return n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n *atomic.Uint64
+}
+
+func (c countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n.Add(uint64(n))
+	return n, err
+}
@@ -0,0 +1,213 @@
+package ch04
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 연결된 TLVConn 쌍 생성
+func tlvConnPair(t *testing.T) (*TLVConn, *TLVConn) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	accepted := make(chan net.Conn)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- conn
+	}()
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := <-accepted
+	if server == nil {
+		t.FailNow()
+	}
+
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+
+	return NewTLVConn(client), NewTLVConn(server)
+}
+
+func TestTLVConnConcurrentSend(t *testing.T) {
+	client, server := tlvConnPair(t)
+
+	const senders, perSender = 8, 100
+
+	var wg sync.WaitGroup
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < perSender; j++ {
+				// frame이 섞이면 디코딩이 실패하도록 크기가 다른 payload 전송
+				msg := String(fmt.Sprintf("%d:%d:%s", id, j, bytes.Repeat([]byte("x"), j*10)))
+				if err := client.Send(&msg); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(i)
+	}
+
+	// 각 sender의 메시지는 보낸 순서대로 도착해야 함
+	next := make([]int, senders)
+	for i := 0; i < senders*perSender; i++ {
+		p, err := server.Receive()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var id, j int
+		_, err = fmt.Sscanf(p.String(), "%d:%d:", &id, &j)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next[id] != j {
+			t.Fatalf("sender %d: expected message %d; actual %d", id, next[id], j)
+		}
+		next[id]++
+	}
+	wg.Wait()
+
+	cs, ss := client.Stats(), server.Stats()
+	t.Logf("client: %+v, server: %+v", cs, ss)
+	if cs.FramesSent != senders*perSender || ss.FramesReceived != cs.FramesSent {
+		t.Errorf("frame count mismatch: %+v, %+v", cs, ss)
+	}
+	if cs.BytesSent != ss.BytesReceived {
+		t.Errorf("byte count mismatch: %d != %d", cs.BytesSent, ss.BytesReceived)
+	}
+}
+
+func TestTLVConnMaxPayloadSize(t *testing.T) {
+	client, server := tlvConnPair(t)
+	client.MaxPayloadSize = 8
+	server.MaxPayloadSize = 8
+
+	big := Binary("more than eight bytes")
+	if err := client.Send(&big); err != ErrMaxPayloadSize {
+		t.Fatalf("expected ErrMaxPayloadSize on send; actual: %v", err)
+	}
+
+	// 송신측 제한이 없는 경우에도 수신측에서 거부
+	client.MaxPayloadSize = 0
+	if err := client.Send(&big); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.Receive(); err != ErrMaxPayloadSize {
+		t.Fatalf("expected ErrMaxPayloadSize on receive; actual: %v", err)
+	}
+	if s := server.Stats(); s.FramesReceived != 0 {
+		t.Errorf("expected no received frames; actual: %+v", s)
+	}
+}
+
+// 압축된 frame은 헤더의 크기가 작더라도 압축을 푼 크기로 제한
+func TestTLVConnMaxPayloadSizeCompressed(t *testing.T) {
+	client, server := tlvConnPair(t)
+	client.Flags = FlagDeflate
+	server.MaxPayloadSize = 1024
+
+	big := Binary(bytes.Repeat([]byte{'a'}, 4096))
+	if err := client.Send(&big); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.Receive(); err != ErrMaxPayloadSize {
+		t.Fatalf("expected ErrMaxPayloadSize on receive; actual: %v", err)
+	}
+	if s := server.Stats(); s.FramesReceived != 0 {
+		t.Errorf("expected no received frames; actual: %+v", s)
+	}
+}
+
+func TestTLVConnReadTimeout(t *testing.T) {
+	_, server := tlvConnPair(t)
+	server.ReadTimeout = 50 * time.Millisecond
+
+	_, err := server.Receive()
+	nErr, ok := err.(net.Error)
+	if !ok || !nErr.Timeout() {
+		t.Fatalf("expected timeout error; actual: %v", err)
+	}
+}
+
+func TestTLVConnStream(t *testing.T) {
+	client, server := tlvConnPair(t)
+	client.MaxPayloadSize = 1024
+
+	data := bytes.Repeat([]byte("stream "), 1000)
+	go func() {
+		// Stream은 연결별 최대 크기 이하의 chunk로 나뉘어 전송
+		stream := NewStream(bytes.NewReader(data))
+		if err := client.Send(stream); err != nil {
+			t.Error(err)
+		}
+		if stream.ChunkSize != 0 {
+			t.Errorf("expected Send to keep ChunkSize; actual %d", stream.ChunkSize)
+		}
+		end := String("end")
+		if err := client.Send(&end); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	p, err := server.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := io.ReadAll(p.(*Stream).Body())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, actual) {
+		t.Error("stream data mismatch")
+	}
+
+	p, err = server.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.String() != "end" {
+		t.Errorf("expected %q; actual %q", "end", p)
+	}
+}
+
+// 첫 frame 이후의 continuation frame도 연결별 최대 크기로 제한
+func TestTLVConnStreamChunkLimit(t *testing.T) {
+	client, server := tlvConnPair(t)
+	server.MaxPayloadSize = 1024
+
+	go func() {
+		var buf bytes.Buffer
+		_, _ = writeTLV(&buf, ChunkType, []byte("small"))
+		_, _ = writeTLV(&buf, ChunkType, make([]byte, 4096))
+		_, _ = client.Conn.Write(buf.Bytes())
+	}()
+
+	p, err := server.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.ReadAll(p.(*Stream).Body())
+	if err != ErrMaxPayloadSize {
+		t.Fatalf("expected ErrMaxPayloadSize; actual: %v", err)
+	}
+}
@@ -1,7 +1,6 @@
 package ch04
 
 import (
-	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -13,11 +12,27 @@ import (
 const (
     BinaryType uint8 = iota + 1
     StringType
+    Int64Type     // 8byte 고정 크기 부호 있는 정수
+    Uint64Type    // 8byte 고정 크기 부호 없는 정수
+    VarintType    // 가변 길이 부호 있는 정수 (zigzag)
+    UvarintType   // 가변 길이 부호 없는 정수
+    Float64Type
+    BoolType
+    TimestampType
+    ListType      // TLV payload의 목록
+    MapType       // String key -> TLV payload
+    ChunkType     // Stream의 continuation frame
+    FlaggedType   // 압축, checksum flag가 있는 확장 헤더
 
     MaxPayloadSize uint32 = 10 << 20 // 10MB
+    MaxNestingDepth = 32 // List, Map의 최대 중첩 깊이
 )
 
-var ErrMaxPayloadSize = errors.New("maximum payload size exceeded")
+var (
+    ErrMaxPayloadSize = errors.New("maximum payload size exceeded")
+    ErrMaxNestingDepth = errors.New("maximum nesting depth exceeded")
+    ErrInvalidSize = errors.New("invalid payload size")
+)
 
 
 // Interface Definition for Payload types
@@ -88,9 +103,16 @@ func (m *Binary) ReadFrom(r io.Reader) (int64, error) {
     }
 
     // 읽은 size만큼 buffer를 생성 후 읽어서 반환.
-    *m = make([]byte, size)
-    o, err := r.Read(*m)
-    return n + int64(o), err
+    // r.Read는 TCP segment가 나뉘어 도착한 경우 size보다 적게 읽을 수 있으므로
+    // io.ReadFull을 통해 size만큼 모두 읽을 때까지 반복한다.
+    buf := make([]byte, size)
+    o, err := io.ReadFull(r, buf)
+    if err != nil {
+        return n + int64(o), err
+    }
+
+    *m = buf
+    return n + int64(o), nil
 }
 
 
@@ -153,46 +175,22 @@ func (m *String) ReadFrom(r io.Reader) (int64, error) {
     }
     n += 4
 
+    if size > MaxPayloadSize {
+        return n, ErrMaxPayloadSize
+    }
+
     buf := make([]byte, size)
-    o, err := r.Read(buf)
+    o, err := io.ReadFull(r, buf) // size만큼 모두 읽음
     if err != nil {
-        return n, err
+        return n + int64(o), err
     }
 
     *m = String(buf) // buf를 String type으로 convert하여 m에 할당
-    return n + int64(o), err
+    return n + int64(o), nil
 }
 
 
-// reader에서 byte를 읽어 Binary, String 타입으로 디코딩
+// reader에서 byte를 읽어 DefaultRegistry에 등록된 Payload 타입으로 디코딩
 func decode(r io.Reader) (Payload, error) {
-    var typ byte
-    // type 추론을 위해 1byte를 읽음
-    err := binary.Read(r, binary.BigEndian, &typ)
-    if err != nil {
-        return nil, err
-    }
-
-    // new: Allocate memory for given type with zero values, and return pointer
-    // 읽은 type을 중심으로 적절한 type의 payload로 초기화
-    var payload Payload
-    switch uint8(typ) {
-    case BinaryType:
-        payload = new(Binary) 
-    case StringType:
-        payload = new(String)
-    default:
-        return nil, errors.New("unknown type")
-    }
-
-    _, err = payload.ReadFrom(
-        // 이미 앞에서 1byte를 읽었기 때문에, 해당 부분을 앞에 reader로 제공하여
-        // io.MultiReader를 통해 원래의 byte 전체를 읽는 것과 동일하도록 한다.
-        io.MultiReader(bytes.NewReader([]byte{typ}), r),
-    )
-    if err != nil {
-        return nil, err
-    }
-
-    return payload, nil
+    return NewDecoder(r).Decode()
 }
@@ -0,0 +1,275 @@
+package ch04
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+// List, Map과 같이 다른 TLV payload를 원소로 가지는 타입은
+// 중첩 깊이를 추적하며 인코딩/디코딩하기 위해 아래 인터페이스를 구현한다.
+
+type compositeEncoder interface {
+	encode(depth int) ([]byte, error)
+	typ() uint8
+}
+
+type compositeDecoder interface {
+	decode(value []byte, registry *Registry, budget *int64, depth int) error
+}
+
+// 한 frame 안에서 압축을 풀 수 있는 남은 byte 수
+// 원소마다 제한하면 작은 압축 원소 여럿으로 frame보다 훨씬 큰 데이터를 풀 수 있으므로 frame 전체에서 공유한다.
+func newBudget(size uint32) *int64 {
+	b := int64(size)
+	return &b
+}
+
+// 원소 하나를 buf에 TLV로 인코딩
+func encodeElement(buf *bytes.Buffer, p Payload, depth int) error {
+	if _, ok := p.(*Stream); ok {
+		return ErrNestedStream
+	}
+	if c, ok := p.(compositeEncoder); ok {
+		value, err := c.encode(depth)
+		if err != nil {
+			return err
+		}
+		_, err = writeTLV(buf, c.typ(), value)
+		return err
+	}
+
+	_, err := p.WriteTo(buf)
+	return err
+}
+
+// r에서 원소 하나를 registry에 등록된 타입으로 디코딩
+func decodeElement(r *bytes.Reader, registry *Registry, budget *int64, depth int) (Payload, error) {
+	typ, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	_ = r.UnreadByte()
+
+	p, err := registry.New(typ)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.(*Stream); ok {
+		return nil, ErrNestedStream
+	}
+
+	err = readPayload(p, typ, r, registry, budget, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	if f, ok := p.(*Flagged); ok {
+		return f.Payload, nil
+	}
+
+	return p, nil
+}
+
+// 중첩 타입인 경우 깊이를 추적하여 디코딩, 그 외에는 ReadFrom 사용
+func readPayload(
+	p Payload, typ uint8, r io.Reader, registry *Registry, budget *int64, depth int,
+) error {
+	c, ok := p.(compositeDecoder)
+	if !ok {
+		_, err := p.ReadFrom(r)
+		return err
+	}
+
+	value, _, err := readTLV(r, typ)
+	if err != nil {
+		return err
+	}
+	return c.decode(value, registry, budget, depth)
+}
+
+// List TLV Payload Data Type
+// 값은 원소들의 TLV를 순서대로 이어 붙인 것
+
+type List []Payload
+
+func (m List) typ() uint8 { return ListType }
+
+func (m List) Bytes() []byte {
+	b, _ := m.encode(1)
+	return b
+}
+
+func (m List) String() string {
+	elems := make([]string, 0, len(m))
+	for _, p := range m {
+		elems = append(elems, p.String())
+	}
+	return "[" + strings.Join(elems, ", ") + "]"
+}
+
+func (m List) WriteTo(w io.Writer) (int64, error) {
+	value, err := m.encode(1)
+	if err != nil {
+		return 0, err
+	}
+	return writeTLV(w, ListType, value)
+}
+
+func (m *List) ReadFrom(r io.Reader) (int64, error) {
+	value, n, err := readTLV(r, ListType)
+	if err != nil {
+		return n, err
+	}
+	return n, m.decode(value, DefaultRegistry, newBudget(MaxPayloadSize), 1)
+}
+
+func (m List) encode(depth int) ([]byte, error) {
+	if depth > MaxNestingDepth {
+		return nil, ErrMaxNestingDepth
+	}
+
+	var buf bytes.Buffer
+	for _, p := range m {
+		if p == nil {
+			return nil, errors.New("nil List element")
+		}
+
+		err := encodeElement(&buf, p, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if buf.Len() > int(MaxPayloadSize) {
+			return nil, ErrMaxPayloadSize
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (m *List) decode(value []byte, registry *Registry, budget *int64, depth int) error {
+	if depth > MaxNestingDepth {
+		return ErrMaxNestingDepth
+	}
+
+	list := List{}
+	r := bytes.NewReader(value)
+	for r.Len() > 0 {
+		p, err := decodeElement(r, registry, budget, depth+1)
+		if err != nil {
+			return err
+		}
+		list = append(list, p)
+	}
+
+	*m = list
+	return nil
+}
+
+// Map TLV Payload Data Type
+// 값은 String key의 TLV와 값의 TLV 쌍을 key 순서대로 이어 붙인 것
+
+type Map map[string]Payload
+
+func (m Map) typ() uint8 { return MapType }
+
+func (m Map) keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	// 같은 Map은 항상 같은 byte로 인코딩되도록 정렬
+	sort.Strings(keys)
+	return keys
+}
+
+func (m Map) Bytes() []byte {
+	b, _ := m.encode(1)
+	return b
+}
+
+func (m Map) String() string {
+	elems := make([]string, 0, len(m))
+	for _, k := range m.keys() {
+		elems = append(elems, k+": "+m[k].String())
+	}
+	return "{" + strings.Join(elems, ", ") + "}"
+}
+
+func (m Map) WriteTo(w io.Writer) (int64, error) {
+	value, err := m.encode(1)
+	if err != nil {
+		return 0, err
+	}
+	return writeTLV(w, MapType, value)
+}
+
+func (m *Map) ReadFrom(r io.Reader) (int64, error) {
+	value, n, err := readTLV(r, MapType)
+	if err != nil {
+		return n, err
+	}
+	return n, m.decode(value, DefaultRegistry, newBudget(MaxPayloadSize), 1)
+}
+
+func (m Map) encode(depth int) ([]byte, error) {
+	if depth > MaxNestingDepth {
+		return nil, ErrMaxNestingDepth
+	}
+
+	var buf bytes.Buffer
+	for _, k := range m.keys() {
+		if m[k] == nil {
+			return nil, errors.New("nil Map value")
+		}
+
+		_, err := String(k).WriteTo(&buf)
+		if err != nil {
+			return nil, err
+		}
+
+		err = encodeElement(&buf, m[k], depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if buf.Len() > int(MaxPayloadSize) {
+			return nil, ErrMaxPayloadSize
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (m *Map) decode(value []byte, registry *Registry, budget *int64, depth int) error {
+	if depth > MaxNestingDepth {
+		return ErrMaxNestingDepth
+	}
+
+	result := make(Map)
+	r := bytes.NewReader(value)
+	for r.Len() > 0 {
+		key, err := decodeElement(r, registry, budget, depth+1)
+		if err != nil {
+			return err
+		}
+		k, ok := key.(*String)
+		if !ok {
+			return errors.New("invalid Map key type")
+		}
+
+		v, err := decodeElement(r, registry, budget, depth+1)
+		if err != nil {
+			return err
+		}
+
+		if _, dup := result[string(*k)]; dup {
+			return errors.New("duplicate Map key")
+		}
+		result[string(*k)] = v
+	}
+
+	*m = result
+	return nil
+}
@@ -0,0 +1,152 @@
+package ch04
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestScalarPayloads(t *testing.T) {
+	payloads := []Payload{
+		ptr(Int64(math.MinInt64)),
+		ptr(Uint64(math.MaxUint64)),
+		ptr(Varint(-300)),
+		ptr(Uvarint(1 << 40)),
+		ptr(Float64(math.Pi)),
+		ptr(Bool(true)),
+		ptr(Bool(false)),
+		ptr(Timestamp(time.Date(2024, 6, 1, 12, 30, 0, 123456789, time.UTC))),
+	}
+
+	var buf bytes.Buffer
+	for _, p := range payloads {
+		_, err := p.WriteTo(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, expected := range payloads {
+		actual, err := decode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("value mismatch: %v != %v", expected, actual)
+			continue
+		}
+		t.Logf("[%T] %s", actual, actual)
+	}
+
+	// Varint는 작은 값일수록 적은 byte 사용
+	if n := len(Varint(1).Bytes()); n != 1 {
+		t.Errorf("expected 1 byte varint; actual: %d", n)
+	}
+}
+
+func TestCompositePayloads(t *testing.T) {
+	expected := &Map{
+		"name":  ptr(String("gopher")),
+		"age":   ptr(Uvarint(15)),
+		"tags":  &List{ptr(String("go")), ptr(Binary("net"))},
+		"empty": &List{},
+		"nested": &Map{
+			"ok":    ptr(Bool(true)),
+			"score": ptr(Float64(99.5)),
+		},
+	}
+
+	var buf bytes.Buffer
+	_, err := expected.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("value mismatch: %v != %v", expected, actual)
+	}
+	t.Logf("[%T] %s", actual, actual)
+}
+
+// depth 단계로 중첩된 List 생성
+func nestedList(depth int) *List {
+	l := &List{}
+	for i := 1; i < depth; i++ {
+		l = &List{l}
+	}
+	return l
+}
+
+func TestMaxNestingDepth(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := nestedList(MaxNestingDepth).WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = nestedList(MaxNestingDepth + 1).WriteTo(&buf)
+	if err != ErrMaxNestingDepth {
+		t.Fatalf("expected ErrMaxNestingDepth; actual: %v", err)
+	}
+
+	// 인코딩 제한을 우회하여 직접 만든 깊은 중첩도 디코딩 시 거부
+	value := []byte{}
+	for i := 0; i <= MaxNestingDepth; i++ {
+		frame := []byte{ListType}
+		frame = binary.BigEndian.AppendUint32(frame, uint32(len(value)))
+		value = append(frame, value...)
+	}
+	_, err = decode(bytes.NewReader(value))
+	if err != ErrMaxNestingDepth {
+		t.Fatalf("expected ErrMaxNestingDepth; actual: %v", err)
+	}
+}
+
+func TestCompositeMaxPayloadSize(t *testing.T) {
+	// 원소 각각은 제한 이하지만 합치면 제한을 넘음
+	half := make(Binary, MaxPayloadSize/2)
+	l := List{&half, &half, &half}
+
+	_, err := l.WriteTo(&bytes.Buffer{})
+	if err != ErrMaxPayloadSize {
+		t.Fatalf("expected ErrMaxPayloadSize; actual: %v", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(ListType)
+	_ = binary.Write(&buf, binary.BigEndian, uint32(1<<30))
+	_, err = decode(&buf)
+	if err != ErrMaxPayloadSize {
+		t.Fatalf("expected ErrMaxPayloadSize; actual: %v", err)
+	}
+}
+
+func TestInvalidScalar(t *testing.T) {
+	// Int64는 반드시 8byte
+	var buf bytes.Buffer
+	_, err := writeTLV(&buf, Int64Type, []byte{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = decode(&buf)
+	if !errors.Is(err, ErrInvalidSize) {
+		t.Fatalf("expected ErrInvalidSize; actual: %v", err)
+	}
+}
@@ -0,0 +1,268 @@
+package ch04
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// type(1byte) + size(4byte) 헤더와 값을 write
+func writeTLV(w io.Writer, typ uint8, value []byte) (int64, error) {
+	if uint64(len(value)) > uint64(MaxPayloadSize) {
+		return 0, ErrMaxPayloadSize
+	}
+
+	var n int64 = 0
+	err := binary.Write(w, binary.BigEndian, typ)
+	if err != nil {
+		return n, err
+	}
+	n += 1
+
+	err = binary.Write(w, binary.BigEndian, uint32(len(value)))
+	if err != nil {
+		return n, err
+	}
+	n += 4
+
+	o, err := w.Write(value)
+	return n + int64(o), err
+}
+
+// 헤더를 읽어 type을 확인하고, size만큼의 값을 모두 읽어 반환
+func readTLV(r io.Reader, typ uint8) ([]byte, int64, error) {
+	var n int64 = 0
+
+	var actual uint8
+	err := binary.Read(r, binary.BigEndian, &actual)
+	if err != nil {
+		return nil, n, err
+	}
+	n += 1
+
+	if actual != typ {
+		return nil, n, fmt.Errorf("invalid type %d; expected %d", actual, typ)
+	}
+
+	var size uint32
+	err = binary.Read(r, binary.BigEndian, &size)
+	if err != nil {
+		return nil, n, err
+	}
+	n += 4
+
+	if size > MaxPayloadSize {
+		return nil, n, ErrMaxPayloadSize
+	}
+
+	// r.Read는 size보다 적게 읽을 수 있으므로 io.ReadFull로 전부 읽음
+	value := make([]byte, size)
+	o, err := io.ReadFull(r, value)
+	return value, n + int64(o), err
+}
+
+// 고정 크기 값을 읽고 크기를 검증
+func readFixedTLV(r io.Reader, typ uint8, size int) ([]byte, int64, error) {
+	value, n, err := readTLV(r, typ)
+	if err != nil {
+		return nil, n, err
+	}
+	if len(value) != size {
+		return nil, n, ErrInvalidSize
+	}
+	return value, n, nil
+}
+
+// Int64 TLV Payload Data Type
+
+type Int64 int64
+
+func (m Int64) Bytes() []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(m))
+}
+
+func (m Int64) String() string { return strconv.FormatInt(int64(m), 10) }
+
+func (m Int64) WriteTo(w io.Writer) (int64, error) {
+	return writeTLV(w, Int64Type, m.Bytes())
+}
+
+func (m *Int64) ReadFrom(r io.Reader) (int64, error) {
+	value, n, err := readFixedTLV(r, Int64Type, 8)
+	if err != nil {
+		return n, err
+	}
+	*m = Int64(binary.BigEndian.Uint64(value))
+	return n, nil
+}
+
+// Uint64 TLV Payload Data Type
+
+type Uint64 uint64
+
+func (m Uint64) Bytes() []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(m))
+}
+
+func (m Uint64) String() string { return strconv.FormatUint(uint64(m), 10) }
+
+func (m Uint64) WriteTo(w io.Writer) (int64, error) {
+	return writeTLV(w, Uint64Type, m.Bytes())
+}
+
+func (m *Uint64) ReadFrom(r io.Reader) (int64, error) {
+	value, n, err := readFixedTLV(r, Uint64Type, 8)
+	if err != nil {
+		return n, err
+	}
+	*m = Uint64(binary.BigEndian.Uint64(value))
+	return n, nil
+}
+
+// Varint TLV Payload Data Type
+// 작은 값일수록 적은 byte를 사용 (1 ~ 10byte)
+
+type Varint int64
+
+func (m Varint) Bytes() []byte { return binary.AppendVarint(nil, int64(m)) }
+
+func (m Varint) String() string { return strconv.FormatInt(int64(m), 10) }
+
+func (m Varint) WriteTo(w io.Writer) (int64, error) {
+	return writeTLV(w, VarintType, m.Bytes())
+}
+
+func (m *Varint) ReadFrom(r io.Reader) (int64, error) {
+	value, n, err := readTLV(r, VarintType)
+	if err != nil {
+		return n, err
+	}
+
+	v, o := binary.Varint(value)
+	if o <= 0 || o != len(value) {
+		return n, ErrInvalidSize
+	}
+	*m = Varint(v)
+	return n, nil
+}
+
+// Uvarint TLV Payload Data Type
+
+type Uvarint uint64
+
+func (m Uvarint) Bytes() []byte { return binary.AppendUvarint(nil, uint64(m)) }
+
+func (m Uvarint) String() string { return strconv.FormatUint(uint64(m), 10) }
+
+func (m Uvarint) WriteTo(w io.Writer) (int64, error) {
+	return writeTLV(w, UvarintType, m.Bytes())
+}
+
+func (m *Uvarint) ReadFrom(r io.Reader) (int64, error) {
+	value, n, err := readTLV(r, UvarintType)
+	if err != nil {
+		return n, err
+	}
+
+	v, o := binary.Uvarint(value)
+	if o <= 0 || o != len(value) {
+		return n, ErrInvalidSize
+	}
+	*m = Uvarint(v)
+	return n, nil
+}
+
+// Float64 TLV Payload Data Type
+// IEEE 754 배정밀도 비트를 그대로 big endian으로 기록
+
+type Float64 float64
+
+func (m Float64) Bytes() []byte {
+	return binary.BigEndian.AppendUint64(nil, math.Float64bits(float64(m)))
+}
+
+func (m Float64) String() string {
+	return strconv.FormatFloat(float64(m), 'g', -1, 64)
+}
+
+func (m Float64) WriteTo(w io.Writer) (int64, error) {
+	return writeTLV(w, Float64Type, m.Bytes())
+}
+
+func (m *Float64) ReadFrom(r io.Reader) (int64, error) {
+	value, n, err := readFixedTLV(r, Float64Type, 8)
+	if err != nil {
+		return n, err
+	}
+	*m = Float64(math.Float64frombits(binary.BigEndian.Uint64(value)))
+	return n, nil
+}
+
+// Bool TLV Payload Data Type
+
+type Bool bool
+
+func (m Bool) Bytes() []byte {
+	if m {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+func (m Bool) String() string { return strconv.FormatBool(bool(m)) }
+
+func (m Bool) WriteTo(w io.Writer) (int64, error) {
+	return writeTLV(w, BoolType, m.Bytes())
+}
+
+func (m *Bool) ReadFrom(r io.Reader) (int64, error) {
+	value, n, err := readFixedTLV(r, BoolType, 1)
+	if err != nil {
+		return n, err
+	}
+	if value[0] > 1 {
+		return n, fmt.Errorf("invalid Bool value: %d", value[0])
+	}
+	*m = value[0] == 1
+	return n, nil
+}
+
+// Timestamp TLV Payload Data Type
+// Unix epoch 기준 초(8byte) + 나노초(4byte). 시간대 정보는 기록하지 않으며 UTC로 디코딩됨
+
+type Timestamp time.Time
+
+func (m Timestamp) Time() time.Time { return time.Time(m) }
+
+func (m Timestamp) Bytes() []byte {
+	t := time.Time(m)
+	b := binary.BigEndian.AppendUint64(nil, uint64(t.Unix()))
+	return binary.BigEndian.AppendUint32(b, uint32(t.Nanosecond()))
+}
+
+func (m Timestamp) String() string {
+	return time.Time(m).UTC().Format(time.RFC3339Nano)
+}
+
+func (m Timestamp) WriteTo(w io.Writer) (int64, error) {
+	return writeTLV(w, TimestampType, m.Bytes())
+}
+
+func (m *Timestamp) ReadFrom(r io.Reader) (int64, error) {
+	value, n, err := readFixedTLV(r, TimestampType, 12)
+	if err != nil {
+		return n, err
+	}
+
+	sec := int64(binary.BigEndian.Uint64(value[:8]))
+	nsec := binary.BigEndian.Uint32(value[8:])
+	if nsec >= uint32(time.Second) {
+		return n, fmt.Errorf("invalid Timestamp nanoseconds: %d", nsec)
+	}
+
+	*m = Timestamp(time.Unix(sec, int64(nsec)).UTC())
+	return n, nil
+}
@@ -3,9 +3,11 @@ package ch04
 import (
 	"bytes"
 	"encoding/binary"
+	"io"
 	"net"
 	"reflect"
 	"testing"
+	"testing/iotest"
 )
 
 func TestPayloads(t *testing.T) {
@@ -80,4 +82,59 @@ func TestMaxPayloadSize(t *testing.T) {
     if err != ErrMaxPayloadSize {
         t.Fatalf("expected ErrMaxPayloadSize; actual: %v", err)
     }
-}
\ No newline at end of file
+}
+
+func TestPayloadShortReads(t *testing.T) {
+    b := Binary("Clear is better than clever.")
+    s := String("Errors are values.")
+
+    for _, p := range []Payload{&b, &s} {
+        var buf bytes.Buffer
+        _, err := p.WriteTo(&buf)
+        if err != nil {
+            t.Fatal(err)
+        }
+        size := buf.Len()
+
+        // 한 번의 Read에 1byte씩만 반환하는 reader에서도 payload 전체를 읽어야 함
+        actual, err := decode(iotest.OneByteReader(&buf))
+        if err != nil {
+            t.Fatal(err)
+        }
+        if !reflect.DeepEqual(p, actual) {
+            t.Errorf("value mismatch: %v != %v", p, actual)
+        }
+
+        // 데이터가 중간에 끊긴 경우 에러 반환
+        _, _ = p.WriteTo(&buf)
+        truncated := io.LimitReader(&buf, int64(size-1))
+        _, err = decode(iotest.HalfReader(truncated))
+        if err != io.ErrUnexpectedEOF {
+            t.Errorf("expected io.ErrUnexpectedEOF; actual: %v", err)
+        }
+    }
+}
+
+func TestPayloadExactMaxSize(t *testing.T) {
+    b := make(Binary, MaxPayloadSize)
+    b[len(b)-1] = 0xff
+
+    r, w := io.Pipe()
+    go func() {
+        _, err := b.WriteTo(w)
+        _ = w.CloseWithError(err)
+    }()
+
+    // pipe는 Write 단위로 Read가 나뉘므로 헤더와 데이터가 나뉘어 도착
+    var actual Binary
+    n, err := actual.ReadFrom(iotest.HalfReader(r))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if n != int64(MaxPayloadSize)+5 {
+        t.Errorf("expected %d bytes read; actual: %d", int64(MaxPayloadSize)+5, n)
+    }
+    if !bytes.Equal(b, actual) {
+        t.Error("payload mismatch")
+    }
+}
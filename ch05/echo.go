@@ -2,42 +2,298 @@ package ch05
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"net/netip"
+	"sync/atomic"
+	"time"
+
+	"github.com/huGgW/network-study-with-go/ch04/sockopt"
+	"github.com/huGgW/network-study-with-go/ch05/udpbatch"
 )
 
+// UDP 데이터그램의 최대 크기
+const maxUDPDatagramSize = 65507
+
 // 송신자가 받은 udp 패킷을 그대로 echoing해주는 서버
 func echoServerUDP(ctx context.Context, addr string) (net.Addr, error) {
-	s, err := net.ListenPacket("udp", addr) // UDP 연결 생성, (net.PacketConn, error) 반환
+	return echoServerUDPWithOptions(ctx, addr, sockopt.Options{})
+}
+
+// 소켓에 opts(SO_RCVBUF, IP_TOS 등)를 적용하는 echoServerUDP
+func echoServerUDPWithOptions(ctx context.Context, addr string, opts sockopt.Options) (net.Addr, error) {
+	s := &udpEchoServer{SocketOptions: opts}
+	return s.listenAndServe(ctx, addr)
+}
+
+// 받는 데이터그램의 크기와 송신자를 제한하는 UDP echo 서버
+//
+// UDP는 송신자 주소를 위조할 수 있으므로, 아무에게나 응답하면 위조된 주소(피해자)로
+// 트래픽을 보내는 reflection 공격에 이용될 수 있다. 허용된 주소에만, 주소마다 일정 속도 이하로 응답한다.
+type udpEchoServer struct {
+	MaxDatagramSize int     // 받을 수 있는 최대 데이터그램 크기. 이보다 크면 잘리므로 echo하지 않고 버림 (기본 65507)
+	Rate            float64 // 송신자 IP마다 초당 echo할 최대 패킷 수. 0이면 제한 없음
+	Burst           int     // 순간적으로 허용할 패킷 수 (기본 max(Rate, 1))
+
+	// 송신자 IP 필터. Allow가 비어 있지 않으면 Allow에 포함된 주소만 허용하고, Deny에 포함된 주소는 거부
+	Allow, Deny []netip.Prefix
+
+	SocketOptions sockopt.Options
+
+	// 1보다 크면 recvmmsg, sendmmsg로 데이터그램을 BatchSize개씩 읽고 씀 (udpbatch)
+	BatchSize int
+
+	echoed, truncated, denied, rateLimited, failed atomic.Uint64
+}
+
+// udpEchoServer 통계
+type udpEchoStats struct {
+	Echoed      uint64
+	Truncated   uint64 // MaxDatagramSize보다 커서 버림
+	Denied      uint64 // Allow, Deny로 거부
+	RateLimited uint64 // 송신자의 Rate를 초과하여 버림
+	Failed      uint64 // 전송 실패
+}
+
+// 버린 패킷 수
+func (s udpEchoStats) Dropped() uint64 {
+	return s.Truncated + s.Denied + s.RateLimited + s.Failed
+}
+
+func (s *udpEchoServer) stats() udpEchoStats {
+	return udpEchoStats{
+		Echoed:      s.echoed.Load(),
+		Truncated:   s.truncated.Load(),
+		Denied:      s.denied.Load(),
+		RateLimited: s.rateLimited.Load(),
+		Failed:      s.failed.Load(),
+	}
+}
+
+func (s *udpEchoServer) listenAndServe(ctx context.Context, addr string) (net.Addr, error) {
+	size := s.MaxDatagramSize
+	if size <= 0 {
+		size = maxUDPDatagramSize
+	}
+	if size > maxUDPDatagramSize {
+		return nil, fmt.Errorf("max datagram size %d exceeds %d", size, maxUDPDatagramSize)
+	}
+
+	pc, err := s.SocketOptions.ListenPacket(ctx, "udp", addr) // UDP 연결 생성, (net.PacketConn, error) 반환
 	if err != nil {
 		return nil, fmt.Errorf("binding to udp %s: %w", addr, err)
 	}
+	conn := pc.(*net.UDPConn)
 
 	go func() {
 		go func() {
-			<-ctx.Done() // Done 신호를 받을 때까지 blocking
-			_ = s.Close() // Done 신호 받은 후 해당 line 통해 server close
+			<-ctx.Done()     // Done 신호를 받을 때까지 blocking
+			_ = conn.Close() // Done 신호 받은 후 해당 line 통해 server close
 		}()
 
-		buf := make([]byte, 1024)
+		if s.BatchSize > 1 {
+			s.serveBatch(conn, size)
+		} else {
+			s.serve(conn, size)
+		}
+	}()
 
-		// 매 연결마다 새로운 연결 객체를 생성할 필요 없음.
-		for {
-		    // UDP는 HandShake 과정이 없기에 Accept 과정이 없음.
-		    // 입력받는 모든 메시지를 읽고, 세션 수립 등이 없어 
-		    //     패킷 안의 주소에 의존하여 노드를 구분할 수 있음.
-			n, clientAddr, err := s.ReadFrom(buf) // server <- client
-			if err != nil {
+	return conn.LocalAddr(), nil
+}
+
+func (s *udpEchoServer) serve(conn *net.UDPConn, size int) {
+	buf := make([]byte, size+1) // MSG_TRUNC를 지원하지 않는 플랫폼에서 잘림을 확인하기 위한 1byte
+	limiter := newRateLimiter(s.Rate, s.Burst)
+
+	// 매 연결마다 새로운 연결 객체를 생성할 필요 없음.
+	for {
+		// UDP는 HandShake 과정이 없기에 Accept 과정이 없음.
+		// 입력받는 모든 메시지를 읽고, 세션 수립 등이 없어
+		//     패킷 안의 주소에 의존하여 노드를 구분할 수 있음.
+		n, clientAddr, truncated, err := readUDP(conn, buf, size) // server <- client
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		if !s.accept(clientAddr.Addr(), truncated, limiter) {
+			continue
+		}
+
+		// UDP는 세션이 없기 때문에 매개변수를 통해 보낼 노드 주소를 특정해야됨
+		_, err = conn.WriteToUDPAddrPort(buf[:n], clientAddr) // server -> client
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
 				return
 			}
+			// 한 송신자에게 보내지 못했다고 서버를 멈추지 않음
+			s.failed.Add(1)
+			continue
+		}
+		s.echoed.Add(1)
+	}
+}
 
-			// UDP는 세션이 없기 때문에 매개변수를 통해 보낼 노드 주소를 특정해야됨
-			_, err = s.WriteTo(buf[:n], clientAddr) // server -> client
-			if err != nil {
+// 시스템 콜 한 번에 데이터그램을 여러 개 읽고, 허용된 데이터그램을 한 번에 echo
+func (s *udpEchoServer) serveBatch(conn *net.UDPConn, size int) {
+	bc := udpbatch.NewConn(conn)
+	ms := udpbatch.NewMessages(s.BatchSize, size+1)
+	out := make([]udpbatch.Message, s.BatchSize)
+	bufs := make([][]byte, s.BatchSize)
+	for i := range out {
+		out[i].Buffers = bufs[i : i+1]
+	}
+	limiter := newRateLimiter(s.Rate, s.Burst)
+
+	for {
+		n, err := bc.ReadBatch(ms)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
 				return
 			}
+			continue
 		}
-	}()
 
-	return s.LocalAddr(), nil
+		pending := 0
+		for _, m := range ms[:n] {
+			addr, ok := m.Addr.(*net.UDPAddr)
+			if !ok || !s.accept(addr.AddrPort().Addr(), truncatedMessage(m, size), limiter) {
+				continue
+			}
+			out[pending].Buffers[0] = m.Buffers[0][:m.N]
+			out[pending].Addr = m.Addr
+			pending++
+		}
+
+		for rest := out[:pending]; len(rest) > 0; {
+			sent, err := bc.WriteBatch(rest)
+			s.echoed.Add(uint64(sent))
+			rest = rest[sent:]
+			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+				if len(rest) > 0 {
+					// 보내지 못한 데이터그램은 건너뛰고 나머지 전송
+					s.failed.Add(1)
+					rest = rest[1:]
+				}
+			}
+		}
+	}
+}
+
+// 잘렸거나, 허용되지 않았거나, 송신 속도를 넘은 데이터그램이면 false
+func (s *udpEchoServer) accept(addr netip.Addr, truncated bool, limiter *rateLimiter) bool {
+	ip := addr.Unmap()
+	switch {
+	case truncated:
+		// 잘린 데이터를 echo하면 송신자는 다른 데이터를 받게 됨
+		s.truncated.Add(1)
+		return false
+	case !s.allowed(ip):
+		s.denied.Add(1)
+		return false
+	case !limiter.allow(ip, time.Now()):
+		s.rateLimited.Add(1)
+		return false
+	}
+	return true
+}
+
+func (s *udpEchoServer) allowed(ip netip.Addr) bool {
+	if len(s.Allow) > 0 && !containsAddr(s.Allow, ip) {
+		return false
+	}
+	return !containsAddr(s.Deny, ip)
+}
+
+func containsAddr(prefixes []netip.Prefix, ip netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// 송신자 IP별 token bucket
+//
+// 토큰은 초당 rate개씩 burst개까지 채워지고, 패킷마다 하나씩 사용한다.
+// bucket이 maxBuckets개로 가득 차면 새 송신자는 하나의 overflow bucket을 함께 사용한다.
+// 서버의 읽기 고루틴에서만 사용하므로 lock이 필요 없다.
+type rateLimiter struct {
+	rate       float64
+	burst      float64
+	buckets    map[netip.Addr]*bucket
+	maxBuckets int
+	overflow   *bucket // last가 0이면 처음 사용할 때 가득 참
+	swept      time.Time
+}
+
+// rateLimiter가 송신자별로 유지하는 최대 bucket 수
+const maxRateBuckets = 65536
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = max(int(rate), 1)
+	}
+	return &rateLimiter{
+		rate:       rate,
+		burst:      float64(burst),
+		buckets:    make(map[netip.Addr]*bucket),
+		maxBuckets: maxRateBuckets,
+		overflow:   &bucket{},
+	}
+}
+
+func (r *rateLimiter) allow(ip netip.Addr, now time.Time) bool {
+	if r == nil {
+		return true
+	}
+	r.sweep(now)
+
+	b := r.buckets[ip]
+	switch {
+	case b != nil:
+	case len(r.buckets) >= r.maxBuckets:
+		// 송신자 주소를 바꿔가며 보내도 bucket 수가 늘지 않도록 함께 제한
+		b = r.overflow
+	default:
+		b = &bucket{tokens: r.burst, last: now}
+		r.buckets[ip] = b
+	}
+
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*r.rate, r.burst)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// 가득 찰 만큼 오래된 bucket은 새로 만든 것과 같으므로 제거하여, 송신자 주소를 바꿔가며 보내도 메모리가 늘지 않도록 함
+func (r *rateLimiter) sweep(now time.Time) {
+	full := time.Duration(r.burst / r.rate * float64(time.Second))
+	if now.Sub(r.swept) < max(full, time.Second) {
+		return
+	}
+	r.swept = now
+
+	for ip, b := range r.buckets {
+		if now.Sub(b.last) >= full {
+			delete(r.buckets, ip)
+		}
+	}
 }
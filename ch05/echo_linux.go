@@ -0,0 +1,20 @@
+package ch05
+
+import (
+	"net"
+	"net/netip"
+
+	"github.com/huGgW/network-study-with-go/ch05/udpbatch"
+	"golang.org/x/sys/unix"
+)
+
+// 최대 size byte의 데이터그램 하나를 읽음. size보다 커서 잘렸으면 커널이 MSG_TRUNC로 알려줌
+func readUDP(conn *net.UDPConn, buf []byte, size int) (int, netip.AddrPort, bool, error) {
+	n, _, flags, addr, err := conn.ReadMsgUDPAddrPort(buf[:size], nil)
+	return n, addr, flags&unix.MSG_TRUNC != 0, err
+}
+
+// size+1 byte 버퍼로 ReadBatch한 데이터그램이 size보다 큰지 확인
+func truncatedMessage(m udpbatch.Message, size int) bool {
+	return m.N > size || m.Flags&unix.MSG_TRUNC != 0
+}
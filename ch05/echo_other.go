@@ -0,0 +1,25 @@
+//go:build !linux
+
+package ch05
+
+import (
+	"net"
+	"net/netip"
+
+	"github.com/huGgW/network-study-with-go/ch05/udpbatch"
+)
+
+// 최대 size byte의 데이터그램 하나를 읽음. buf는 size보다 1byte 커야 함
+// MSG_TRUNC를 확인할 수 없으므로 한 byte 더 읽어서 size보다 크면 잘린 것으로 봄
+func readUDP(conn *net.UDPConn, buf []byte, size int) (int, netip.AddrPort, bool, error) {
+	n, addr, err := conn.ReadFromUDPAddrPort(buf[:size+1])
+	if n > size {
+		return size, addr, true, err
+	}
+	return n, addr, false, err
+}
+
+// size+1 byte 버퍼로 ReadBatch한 데이터그램이 size보다 큰지 확인
+func truncatedMessage(m udpbatch.Message, size int) bool {
+	return m.N > size
+}
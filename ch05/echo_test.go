@@ -3,8 +3,12 @@ package ch05
 import (
 	"bytes"
 	"context"
+	"errors"
 	"net"
+	"net/netip"
+	"os"
 	"testing"
+	"time"
 )
 
 // Echo 서버 테스트
@@ -46,3 +50,189 @@ func TestEchoServerUDP(t *testing.T) {
         t.Errorf("expected reply %q; actual reply %q", msg, buf[:n])
     }
 }
+
+func startUDPEchoServer(t *testing.T, s *udpEchoServer) net.Addr {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	serverAddr, err := s.listenAndServe(ctx, "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return serverAddr
+}
+
+// 응답을 기다림. 응답이 없으면 nil
+func echo(t *testing.T, client net.PacketConn, serverAddr net.Addr, msg []byte) []byte {
+	_, err := client.WriteTo(msg, serverAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 2048)
+	_ = client.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	n, _, err := client.ReadFrom(buf)
+	if err != nil {
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return nil
+		}
+		t.Fatal(err)
+	}
+	return buf[:n]
+}
+
+// 최대 크기보다 큰 데이터그램은 잘린 채로 echo하지 않고 버림
+func TestEchoServerUDPTruncation(t *testing.T) {
+	s := &udpEchoServer{MaxDatagramSize: 1024}
+	serverAddr := startUDPEchoServer(t, s)
+
+	client, err := net.ListenPacket("udp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if reply := echo(t, client, serverAddr, make([]byte, 1025)); reply != nil {
+		t.Errorf("expected no reply; actual %d bytes", len(reply))
+	}
+	msg := bytes.Repeat([]byte("x"), 1024)
+	if reply := echo(t, client, serverAddr, msg); !bytes.Equal(msg, reply) {
+		t.Errorf("expected %d bytes reply; actual %d bytes", len(msg), len(reply))
+	}
+
+	stats := s.stats()
+	if stats.Truncated != 1 || stats.Echoed != 1 || stats.Dropped() != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestEchoServerUDPRateLimit(t *testing.T) {
+	s := &udpEchoServer{Rate: 10, Burst: 3}
+	serverAddr := startUDPEchoServer(t, s)
+
+	client, err := net.ListenPacket("udp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// burst만큼 응답한 뒤에는 버림
+	for i := 0; i < 5; i++ {
+		_, err = client.WriteTo([]byte("ping"), serverAddr)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	stats := s.stats()
+	if stats.Echoed != 3 || stats.RateLimited != 2 {
+		t.Errorf("expected 3 echoed and 2 rate limited; actual %+v", stats)
+	}
+
+	// 100ms마다 토큰 하나가 채워짐
+	time.Sleep(150 * time.Millisecond)
+	if reply := echo(t, client, serverAddr, []byte("ping")); reply == nil {
+		t.Error("expected reply after refill")
+	}
+}
+
+func TestEchoServerUDPFilter(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		s       *udpEchoServer
+		allowed bool
+	}{
+		{"allow", &udpEchoServer{Allow: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}}, true},
+		{"not allowed", &udpEchoServer{Allow: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}, false},
+		{"deny", &udpEchoServer{Deny: []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")}}, false},
+		{"allow but deny", &udpEchoServer{
+			Allow: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")},
+			Deny:  []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")},
+		}, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			serverAddr := startUDPEchoServer(t, c.s)
+
+			client, err := net.ListenPacket("udp", "127.0.0.1:")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+
+			reply := echo(t, client, serverAddr, []byte("ping"))
+			if allowed := reply != nil; allowed != c.allowed {
+				t.Errorf("expected allowed %t; actual %t", c.allowed, allowed)
+			}
+			if !c.allowed && c.s.stats().Denied != 1 {
+				t.Errorf("expected 1 denied; actual %+v", c.s.stats())
+			}
+		})
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	r := newRateLimiter(1, 2)
+	now := time.Now()
+
+	for i := 0; i < 100; i++ {
+		r.allow(netip.AddrFrom4([4]byte{10, 0, 0, byte(i)}), now)
+	}
+	if len(r.buckets) != 100 {
+		t.Fatalf("expected 100 buckets; actual %d", len(r.buckets))
+	}
+
+	// 가득 찬 bucket은 제거됨
+	r.allow(netip.MustParseAddr("10.0.1.0"), now.Add(3*time.Second))
+	if len(r.buckets) != 1 {
+		t.Errorf("expected 1 bucket after sweep; actual %d", len(r.buckets))
+	}
+}
+
+func TestRateLimiterMaxBuckets(t *testing.T) {
+	r := newRateLimiter(1, 2)
+	r.maxBuckets = 10
+	now := time.Now()
+
+	for i := 0; i < 100; i++ {
+		r.allow(netip.AddrFrom4([4]byte{10, 0, 0, byte(i)}), now)
+	}
+	if len(r.buckets) != r.maxBuckets {
+		t.Fatalf("expected %d buckets; actual %d", r.maxBuckets, len(r.buckets))
+	}
+
+	// 가득 찬 뒤의 송신자는 overflow bucket을 함께 사용
+	if r.allow(netip.MustParseAddr("10.0.1.0"), now) {
+		t.Error("expected shared overflow bucket to be exhausted")
+	}
+	// 이미 bucket이 있는 송신자는 영향받지 않음
+	if !r.allow(netip.AddrFrom4([4]byte{10, 0, 0, 0}), now) {
+		t.Error("expected existing bucket to allow")
+	}
+}
+
+// BatchSize를 설정하면 ReadBatch의 MSG_TRUNC로 잘린 데이터그램을 확인
+func TestEchoServerUDPBatch(t *testing.T) {
+	s := &udpEchoServer{MaxDatagramSize: 1024, BatchSize: 8}
+	serverAddr := startUDPEchoServer(t, s)
+
+	client, err := net.ListenPacket("udp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if reply := echo(t, client, serverAddr, make([]byte, 1025)); reply != nil {
+		t.Errorf("expected no reply; actual %d bytes", len(reply))
+	}
+	for _, msg := range [][]byte{[]byte("ping"), bytes.Repeat([]byte("x"), 1024)} {
+		if reply := echo(t, client, serverAddr, msg); !bytes.Equal(msg, reply) {
+			t.Errorf("expected %d bytes reply; actual %d bytes", len(msg), len(reply))
+		}
+	}
+
+	stats := s.stats()
+	if stats.Truncated != 1 || stats.Echoed != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
@@ -0,0 +1,98 @@
+package demux
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// deadline까지 상태 변화를 기다리는 조건 변수와 net.Conn의 read, write deadline
+//
+// sync.Cond는 대기를 중간에 멈출 수 없으므로, Broadcast할 때마다 channel을 닫아 대기 중인 고루틴을 깨운다.
+// deadline을 바꾸면 Broadcast하므로 대기 중인 Read, Write가 새 deadline을 확인한다.
+type Cond struct {
+	L sync.Locker
+
+	notify chan struct{} // Broadcast할 때마다 닫고, 다음 대기에서 새로 생성
+
+	readDeadline, writeDeadline time.Time
+}
+
+// 대기 중인 고루틴을 모두 깨움. L을 잡은 상태에서 호출
+func (c *Cond) Broadcast() {
+	if c.notify != nil {
+		close(c.notify)
+		c.notify = nil
+	}
+}
+
+// Broadcast되거나 deadline이 지날 때까지 L을 놓고 대기. L을 잡은 상태에서 호출
+// deadline이 0이면 Broadcast될 때까지 대기
+func (c *Cond) Wait(deadline time.Time) error {
+	if c.notify == nil {
+		c.notify = make(chan struct{})
+	}
+	notify := c.notify
+
+	c.L.Unlock()
+	defer c.L.Lock()
+
+	if deadline.IsZero() {
+		<-notify
+		return nil
+	}
+
+	d := time.Until(deadline)
+	if d <= 0 {
+		return os.ErrDeadlineExceeded
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-notify:
+		return nil
+	case <-t.C:
+		return os.ErrDeadlineExceeded
+	}
+}
+
+// read deadline까지 대기. L을 잡은 상태에서 호출
+func (c *Cond) WaitRead() error { return c.Wait(c.readDeadline) }
+
+// write deadline까지 대기. L을 잡은 상태에서 호출
+func (c *Cond) WaitWrite() error { return c.Wait(c.writeDeadline) }
+
+// read deadline이 지났으면 true. L을 잡은 상태에서 호출
+func (c *Cond) ReadExpired() bool { return expired(c.readDeadline) }
+
+// write deadline이 지났으면 true. L을 잡은 상태에서 호출
+func (c *Cond) WriteExpired() bool { return expired(c.writeDeadline) }
+
+func expired(deadline time.Time) bool {
+	return !deadline.IsZero() && !time.Now().Before(deadline)
+}
+
+func (c *Cond) SetDeadline(t time.Time) error {
+	c.L.Lock()
+	defer c.L.Unlock()
+	c.readDeadline, c.writeDeadline = t, t
+	c.Broadcast()
+	return nil
+}
+
+func (c *Cond) SetReadDeadline(t time.Time) error {
+	c.L.Lock()
+	defer c.L.Unlock()
+	c.readDeadline = t
+	c.Broadcast()
+	return nil
+}
+
+func (c *Cond) SetWriteDeadline(t time.Time) error {
+	c.L.Lock()
+	defer c.L.Unlock()
+	c.writeDeadline = t
+	c.Broadcast()
+	return nil
+}
@@ -0,0 +1,169 @@
+// udpmux, rudp의 Listener가 공유하는 상대 주소별 session 관리
+//
+// Table은 소켓에서 데이터그램을 읽어 전달하고, 새 session을 Accept 대기열에 넣으며,
+// Close 이후에는 마지막 session이 끝날 때 소켓을 닫는다.
+// 데이터그램을 해석하여 session을 만들지, session에 어떻게 전달할지는 사용하는 쪽에서 정한다.
+package demux
+
+import (
+	"net"
+	"sync"
+)
+
+// 상대 주소별 session 목록과 Accept 대기열
+//
+// S는 session의 포인터 타입이며, zero 값(nil)은 session이 없음을 뜻한다.
+type Table[S comparable] struct {
+	pc       net.PacketConn
+	teardown func(S, error) // Accept되지 않았거나 소켓 에러로 끝나는 session을 닫음
+
+	accept chan S
+	done   chan struct{}
+
+	mu       sync.Mutex
+	sessions map[string]S
+	closed   bool
+}
+
+// pc의 session table. Table이 pc를 닫는다.
+func NewTable[S comparable](pc net.PacketConn, backlog int, teardown func(S, error)) *Table[S] {
+	return &Table[S]{
+		pc:       pc,
+		teardown: teardown,
+		accept:   make(chan S, backlog),
+		done:     make(chan struct{}),
+		sessions: make(map[string]S),
+	}
+}
+
+// 최대 size byte의 데이터그램을 읽어 handle에 전달. b는 다음 데이터그램을 읽을 때 덮어쓴다.
+// 소켓 에러로 더 이상 읽을 수 없으면 모든 session을 닫고 반환
+func (t *Table[S]) Serve(size int, handle func(b []byte, addr net.Addr)) {
+	buf := make([]byte, size)
+	for {
+		n, addr, err := t.pc.ReadFrom(buf)
+		if err != nil {
+			t.shutdown(err)
+			return
+		}
+		handle(buf[:n], addr)
+	}
+}
+
+// addr의 session. 없으면 create로 만들어 Accept 대기열에 넣음
+// create가 zero 값을 반환하거나, Close되었거나, session이 maxSessions개(0이면 제한 없음)이거나,
+// 대기열이 가득 차면 zero 값. create는 table의 lock을 잡은 상태에서 호출된다.
+func (t *Table[S]) Session(addr net.Addr, maxSessions int, create func(key string) S) (s S, created bool) {
+	var zero S
+	key := addr.String()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if s := t.sessions[key]; s != zero {
+		return s, false
+	}
+	if t.closed || (maxSessions > 0 && len(t.sessions) >= maxSessions) {
+		return zero, false
+	}
+
+	s = create(key)
+	if s == zero {
+		return zero, false
+	}
+	select {
+	case t.accept <- s:
+	default:
+		return zero, false // 대기열이 가득 참
+	}
+	t.sessions[key] = s
+
+	return s, true
+}
+
+// 끝난 session을 제거. Close 이후 마지막 session이면 소켓을 닫음
+func (t *Table[S]) Remove(key string, s S) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.sessions[key] == s {
+		delete(t.sessions, key)
+	}
+	if t.closed && len(t.sessions) == 0 {
+		_ = t.pc.Close()
+	}
+}
+
+func (t *Table[S]) Accept() (S, error) {
+	select {
+	case s := <-t.accept:
+		return s, nil
+	case <-t.done:
+		var zero S
+		return zero, net.ErrClosed
+	}
+}
+
+// 새 session을 받지 않고, Accept되지 않은 session은 닫음
+// 이미 Accept한 session은 계속 동작하며, 소켓은 마지막 session이 Remove될 때 닫힌다.
+func (t *Table[S]) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return net.ErrClosed
+	}
+	t.closed = true
+	close(t.done)
+	if len(t.sessions) == 0 {
+		_ = t.pc.Close()
+	}
+	t.mu.Unlock()
+
+	for {
+		select {
+		case s := <-t.accept:
+			t.teardown(s, net.ErrClosed)
+		default:
+			return nil
+		}
+	}
+}
+
+func (t *Table[S]) shutdown(err error) {
+	t.mu.Lock()
+	if !t.closed {
+		t.closed = true
+		close(t.done)
+	}
+	sessions := t.snapshot()
+	t.mu.Unlock()
+
+	for _, s := range sessions {
+		t.teardown(s, err)
+	}
+}
+
+// Close되거나 소켓 에러가 나면 닫힘
+func (t *Table[S]) Done() <-chan struct{} { return t.done }
+
+// 현재 session 수
+func (t *Table[S]) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.sessions)
+}
+
+// 현재 session 목록의 복사본
+func (t *Table[S]) Sessions() []S {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.snapshot()
+}
+
+func (t *Table[S]) snapshot() []S {
+	sessions := make([]S, 0, len(t.sessions))
+	for _, s := range t.sessions {
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
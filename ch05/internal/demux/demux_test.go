@@ -0,0 +1,90 @@
+package demux
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+type session struct {
+	key string
+	err error
+}
+
+func TestTable(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := NewTable(pc, 1, func(s *session, err error) { s.err = err })
+
+	a := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1}
+	b := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 2}
+	create := func(key string) *session { return &session{key: key} }
+
+	s, created := table.Session(a, 0, create)
+	if s == nil || !created {
+		t.Fatalf("expected new session; actual %v, %t", s, created)
+	}
+	if actual, created := table.Session(a, 0, create); actual != s || created {
+		t.Errorf("expected existing session; actual %v, %t", actual, created)
+	}
+	// 대기열이 가득 차면 만들지 않음
+	if actual, _ := table.Session(b, 0, create); actual != nil {
+		t.Errorf("expected nil with full backlog; actual %v", actual)
+	}
+
+	// Accept되지 않은 session은 Close할 때 닫힘
+	if err := table.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(s.err, net.ErrClosed) {
+		t.Errorf("expected net.ErrClosed; actual %v", s.err)
+	}
+	if _, err := table.Accept(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("expected net.ErrClosed; actual %v", err)
+	}
+
+	// 마지막 session이 제거되면 소켓을 닫음
+	table.Remove(s.key, s)
+	if _, err := pc.WriteTo([]byte("x"), a); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("expected closed socket; actual %v", err)
+	}
+}
+
+func TestCondDeadline(t *testing.T) {
+	var mu sync.Mutex
+	c := Cond{L: &mu}
+	_ = c.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+
+	mu.Lock()
+	err := c.WaitRead()
+	expired := c.ReadExpired()
+	mu.Unlock()
+	if !errors.Is(err, os.ErrDeadlineExceeded) || !expired {
+		t.Fatalf("expected deadline exceeded; actual %v, %t", err, expired)
+	}
+
+	// deadline을 바꾸면 대기 중인 고루틴이 깨어남
+	_ = c.SetReadDeadline(time.Time{})
+	done := make(chan error)
+	go func() {
+		mu.Lock()
+		defer mu.Unlock()
+		done <- c.WaitRead()
+	}()
+	time.Sleep(10 * time.Millisecond)
+	_ = c.SetReadDeadline(time.Now().Add(time.Hour))
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected wakeup; actual %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiter was not woken by SetReadDeadline")
+	}
+}
@@ -0,0 +1,636 @@
+// UDP 위에서 순서와 전달을 보장하는 연결
+//
+// tftp.Server의 timeout 재전송은 한 번에 한 블록만 보내고 ACK를 기다리지만(stop-and-wait),
+// rudp는 TCP와 같은 방식으로 여러 segment를 동시에 보낸다.
+//   - segment마다 번호를 붙이고, 수신자는 다음에 받을 번호(누적 ACK)와 먼저 도착한 구간(SACK)을 알림
+//   - 송신자는 window 크기만큼 ACK 없이 전송하고, 측정한 RTT로 재전송 timeout(RTO)을 계산
+//   - 중복 ACK 3번이면 timeout을 기다리지 않고 SACK되지 않은 segment를 재전송 (fast retransmit)
+//   - 수신자는 중복 segment를 버리고, 읽지 않은 데이터만큼 window를 줄여 송신 속도를 제한 (flow control)
+//
+// 혼잡 제어는 하지 않으므로 공유 네트워크에서는 Window를 작게 설정해야 한다.
+package rudp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/huGgW/network-study-with-go/ch05/internal/demux"
+)
+
+var ErrTimeout = errors.New("rudp: peer not responding")
+
+// 연결 설정. 0인 값은 기본값 사용
+type Config struct {
+	Window     int           // ACK 없이 보낼 수 있는 최대 segment 수이자 수신 버퍼 크기 (기본 64, 최대 65535)
+	MaxPayload int           // segment의 최대 데이터 크기 (기본 1200byte: IP 단편화를 피하기 위해 MTU보다 작게)
+	InitialRTO time.Duration // RTT 측정 전의 재전송 timeout (기본 1초)
+	MinRTO     time.Duration // 기본 200ms
+	MaxRTO     time.Duration // 기본 10초
+	MaxRetries int           // 응답 없이 연속으로 재전송할 최대 횟수. 초과하면 ErrTimeout (기본 10)
+	Linger     time.Duration // Close 후 남은 데이터와 FIN이 ACK되기를 기다리는 시간 (기본 5초)
+	Backlog    int           // Listener에서 Accept를 기다리는 최대 연결 수 (기본 128)
+}
+
+func (c *Config) withDefaults() (Config, error) {
+	var cfg Config
+	if c != nil {
+		cfg = *c
+	}
+
+	if cfg.Window == 0 {
+		cfg.Window = 64
+	}
+	if cfg.MaxPayload == 0 {
+		cfg.MaxPayload = 1200
+	}
+	if cfg.InitialRTO == 0 {
+		cfg.InitialRTO = time.Second
+	}
+	if cfg.MinRTO == 0 {
+		cfg.MinRTO = 200 * time.Millisecond
+	}
+	if cfg.MaxRTO == 0 {
+		cfg.MaxRTO = 10 * time.Second
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 10
+	}
+	if cfg.Linger == 0 {
+		cfg.Linger = 5 * time.Second
+	}
+	if cfg.Backlog == 0 {
+		cfg.Backlog = 128
+	}
+
+	switch {
+	case cfg.Window < 1 || cfg.Window > 65535:
+		return cfg, errors.New("rudp: window must be 1 ~ 65535")
+	case cfg.MaxPayload < 1 || cfg.MaxPayload > maxPayloadLimit:
+		return cfg, errors.New("rudp: invalid max payload")
+	case cfg.InitialRTO < 0 || cfg.MinRTO < 0 || cfg.MaxRTO < cfg.MinRTO:
+		return cfg, errors.New("rudp: invalid RTO")
+	case cfg.MaxRetries < 0 || cfg.Linger < 0 || cfg.Backlog < 0:
+		return cfg, errors.New("rudp: invalid config")
+	}
+
+	return cfg, nil
+}
+
+// 연결 통계
+type Stats struct {
+	Sent          uint64 // 전송한 segment 수 (재전송 포함)
+	Retransmitted uint64 // 재전송한 segment 수
+	Received      uint64 // 받은 segment 수 (중복 포함)
+	Duplicates    uint64 // 이미 받아 버린 segment 수
+}
+
+type segment struct {
+	seq    uint32
+	data   []byte
+	fin    bool
+	sentAt time.Time
+
+	retransmitted bool // 재전송한 segment는 RTT 측정에서 제외 (Karn's algorithm)
+	sacked        bool // 수신자가 SACK으로 받았다고 알린 segment는 재전송하지 않음
+}
+
+// 신뢰성 있는 UDP 연결. net.Conn 구현
+//
+// Write한 데이터는 segment로 나뉘어 전송되며, Read는 메시지 경계 없이 순서대로 byte stream을 반환한다.
+type Conn struct {
+	cfg     Config
+	pc      net.PacketConn
+	raddr   net.Addr
+	onClose func() // 연결이 완전히 끝나면 호출
+
+	mu   sync.Mutex
+	cond demux.Cond // 연결 상태, 송수신 버퍼가 바뀌면 Broadcast
+
+	established bool
+	closed      bool  // Close 호출됨
+	dead        bool  // 연결이 끝남
+	err         error // 연결이 끝난 원인
+
+	// 송신: segs[0]이 sndUna번 segment
+	segs    []*segment
+	sndUna  uint32 // ACK되지 않은 가장 오래된 segment
+	sndNxt  uint32 // 처음 전송할 다음 segment
+	nextSeq uint32 // Write할 때 붙일 다음 번호
+	rwnd    int    // 수신자가 알린 window
+	dupAcks int
+
+	srtt, rttvar, rto time.Duration
+	hasRTT            bool
+	retries           int
+	timer             *time.Timer
+	timerAt           time.Time // 0이면 timer가 멈춘 상태
+
+	synSentAt        time.Time
+	synRetransmitted bool
+
+	// 수신
+	rcvNext    uint32              // 다음에 받을 segment
+	ooo        map[uint32]*segment // 순서보다 먼저 도착한 segment
+	readBuf    bytes.Buffer        // 순서대로 도착하여 Read를 기다리는 데이터
+	eof        bool                // 상대의 FIN까지 모두 받음
+	advertised int                 // 마지막으로 알린 window
+
+	stats Stats
+}
+
+func newConn(cfg Config, pc net.PacketConn, raddr net.Addr, onClose func()) *Conn {
+	c := &Conn{
+		cfg:     cfg,
+		pc:      pc,
+		raddr:   raddr,
+		onClose: onClose,
+		rwnd:    cfg.Window,
+		rto:     cfg.InitialRTO,
+		ooo:     make(map[uint32]*segment),
+	}
+	c.advertised = cfg.Window
+	c.cond.L = &c.mu
+	c.timer = time.AfterFunc(time.Hour, c.onTimer)
+	c.timer.Stop()
+
+	return c
+}
+
+// 주소를 해석하여 연결. 연결에 사용할 UDP 소켓을 새로 연다.
+func Dial(network, address string, cfg *Config) (*Conn, error) {
+	raddr, err := net.ResolveUDPAddr(network, address)
+	if err != nil {
+		return nil, err
+	}
+	pc, err := net.ListenUDP(network, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return Client(pc, raddr, cfg)
+}
+
+// pc로 raddr에 연결. 연결이 끝나면 pc를 닫는다.
+// pc에서 raddr이 아닌 주소로부터 온 패킷은 무시한다.
+func Client(pc net.PacketConn, raddr net.Addr, cfg *Config) (*Conn, error) {
+	config, err := cfg.withDefaults()
+	if err != nil {
+		_ = pc.Close()
+		return nil, err
+	}
+
+	c := newConn(config, pc, raddr, func() { _ = pc.Close() })
+	go c.readLoop()
+
+	c.mu.Lock()
+	c.synSentAt = time.Now()
+	c.send(&packet{typ: typeSyn})
+	c.startTimer()
+
+	// SYNACK(혹은 SYNACK이 유실된 경우 상대의 데이터)를 받을 때까지 SYN 재전송
+	for !c.established && !c.dead {
+		_ = c.cond.Wait(time.Time{})
+	}
+	established, err := c.established, c.err
+	c.mu.Unlock()
+
+	if !established {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Conn) readLoop() {
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, addr, err := c.pc.ReadFrom(buf)
+		if err != nil {
+			c.teardown(err)
+			return
+		}
+		if addr.String() != c.raddr.String() {
+			continue
+		}
+
+		var p packet
+		if p.UnmarshalBinary(buf[:n]) != nil {
+			continue
+		}
+		c.handle(&p)
+	}
+}
+
+// 연결을 끝내고 대기 중인 Read, Write를 깨움
+func (c *Conn) teardown(err error) {
+	c.mu.Lock()
+	if c.dead {
+		c.mu.Unlock()
+		return
+	}
+	c.dead = true
+	if c.err == nil {
+		c.err = err
+	}
+	c.stopTimer()
+	c.cond.Broadcast()
+	c.mu.Unlock()
+
+	if c.onClose != nil {
+		c.onClose()
+	}
+}
+
+// 패킷 전송. 유실은 재전송으로 처리하므로 전송 에러는 무시
+func (c *Conn) send(p *packet) {
+	b, err := p.MarshalBinary()
+	if err != nil {
+		return
+	}
+	_, _ = c.pc.WriteTo(b, c.raddr)
+}
+
+func (c *Conn) handle(p *packet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.dead {
+		return
+	}
+
+	switch p.typ {
+	case typeSyn:
+		// client가 SYNACK을 받지 못해 SYN을 재전송
+		c.send(&packet{typ: typeSynAck})
+	case typeSynAck:
+		c.establish()
+	case typeData, typeFin:
+		c.establish()
+		c.receive(p)
+	case typeAck:
+		c.establish()
+		c.acknowledge(p)
+	}
+}
+
+func (c *Conn) establish() {
+	if c.established {
+		return
+	}
+	c.established = true
+	if !c.synRetransmitted {
+		c.sampleRTT(time.Since(c.synSentAt))
+	}
+	c.retries = 0
+	c.stopTimer()
+	c.cond.Broadcast()
+}
+
+// 수신 버퍼에 남은 공간 (segment 단위)
+func (c *Conn) rcvWindow() int {
+	unread := (c.readBuf.Len() + c.cfg.MaxPayload - 1) / c.cfg.MaxPayload
+	return max(c.cfg.Window-unread, 0)
+}
+
+func (c *Conn) receive(p *packet) {
+	c.stats.Received++
+
+	off := int32(p.seq - c.rcvNext)
+	switch {
+	case off < 0 || c.ooo[p.seq] != nil:
+		// 이미 받은 segment. ACK가 유실되어 재전송된 경우이므로 ACK만 다시 보냄
+		c.stats.Duplicates++
+	case int(off) >= c.rcvWindow():
+		// 수신 버퍼 밖의 segment는 버림. 송신자는 ACK의 window를 보고 다시 보냄
+	default:
+		c.ooo[p.seq] = &segment{seq: p.seq, data: p.payload, fin: p.typ == typeFin}
+
+		// 순서대로 이어지는 segment를 읽을 수 있도록 전달
+		for s := c.ooo[c.rcvNext]; s != nil; s = c.ooo[c.rcvNext] {
+			delete(c.ooo, c.rcvNext)
+			c.rcvNext++
+			if s.fin {
+				c.eof = true
+			} else if !c.closed {
+				// Close한 뒤에는 읽을 수 없으므로 버리고 window를 유지
+				c.readBuf.Write(s.data)
+			}
+			c.cond.Broadcast()
+		}
+	}
+
+	c.sendAck()
+}
+
+func (c *Conn) sendAck() {
+	// 먼저 도착한 segment를 연속 구간으로 묶어 SACK으로 알림
+	seqs := make([]uint32, 0, len(c.ooo))
+	for seq := range c.ooo {
+		seqs = append(seqs, seq)
+	}
+	slices.SortFunc(seqs, func(a, b uint32) int { return int(int32(a - b)) })
+
+	var sack []sackBlock
+	for _, seq := range seqs {
+		if n := len(sack); n > 0 && sack[n-1].end == seq {
+			sack[n-1].end++
+			continue
+		}
+		if len(sack) == maxSackBlocks {
+			break
+		}
+		sack = append(sack, sackBlock{start: seq, end: seq + 1})
+	}
+
+	c.advertised = c.rcvWindow()
+	c.send(&packet{typ: typeAck, ack: c.rcvNext, wnd: uint16(c.advertised), sack: sack})
+}
+
+func (c *Conn) acknowledge(p *packet) {
+	// 응답이 있으므로 상대는 살아 있음 (window가 0이어도)
+	c.retries = 0
+	windowUpdate := int(p.wnd) != c.rwnd
+	c.rwnd = int(p.wnd)
+
+	inFlight := int32(c.sndNxt - c.sndUna)
+	acked := int32(p.ack - c.sndUna)
+	switch {
+	case acked > 0 && acked <= inFlight:
+		last := c.segs[acked-1]
+		if !last.retransmitted {
+			c.sampleRTT(time.Since(last.sentAt))
+		}
+
+		c.segs = c.segs[acked:]
+		c.sndUna = p.ack
+		c.dupAcks = 0
+		c.rto = c.calcRTO() // 재전송으로 늘어난 timeout 복구
+
+		if c.sndNxt == c.sndUna {
+			c.stopTimer()
+		} else {
+			c.restartTimer()
+		}
+		c.cond.Broadcast() // window가 비어 대기 중인 Write, Close를 깨움
+	case acked == 0 && inFlight > 0 && !windowUpdate:
+		// window만 바뀐 ACK는 유실의 신호가 아님
+		c.dupAcks++
+	}
+
+	for _, b := range p.sack {
+		for seq := b.start; seq != b.end; seq++ {
+			i := int32(seq - c.sndUna)
+			if i >= 0 && i < int32(c.sndNxt-c.sndUna) {
+				c.segs[i].sacked = true
+			}
+		}
+	}
+
+	if c.dupAcks == 3 {
+		c.fastRetransmit()
+	}
+	c.transmit()
+}
+
+// 마지막으로 SACK된 segment 이전의 SACK되지 않은 segment는 유실된 것으로 보고 재전송
+// SACK이 없으면 가장 오래된 segment만 재전송
+func (c *Conn) fastRetransmit() {
+	inFlight := int(c.sndNxt - c.sndUna)
+	last := 0
+	for i := 0; i < inFlight; i++ {
+		if c.segs[i].sacked {
+			last = i
+		}
+	}
+
+	for i := 0; i <= last; i++ {
+		if s := c.segs[i]; !s.sacked {
+			c.retransmit(s)
+		}
+	}
+}
+
+func (c *Conn) retransmit(s *segment) {
+	s.retransmitted = true
+	c.stats.Retransmitted++
+	c.sendSegment(s)
+}
+
+func (c *Conn) sendSegment(s *segment) {
+	typ := typeData
+	if s.fin {
+		typ = typeFin
+	}
+	s.sentAt = time.Now()
+	c.stats.Sent++
+	c.send(&packet{typ: typ, seq: s.seq, payload: s.data})
+}
+
+// window 안에서 아직 보내지 않은 segment 전송
+func (c *Conn) transmit() {
+	// 수신자의 window가 0이어도 하나는 보내 window가 열렸는지 확인 (zero window probe)
+	limit := max(min(c.cfg.Window, c.rwnd), 1)
+
+	for c.sndNxt != c.nextSeq && int(c.sndNxt-c.sndUna) < limit {
+		c.sendSegment(c.segs[c.sndNxt-c.sndUna])
+		c.sndNxt++
+	}
+
+	if c.sndNxt != c.sndUna {
+		c.startTimer()
+	}
+}
+
+// RFC 6298의 RTT 추정
+func (c *Conn) sampleRTT(r time.Duration) {
+	if !c.hasRTT {
+		c.srtt, c.rttvar, c.hasRTT = r, r/2, true
+	} else {
+		diff := c.srtt - r
+		if diff < 0 {
+			diff = -diff
+		}
+		c.rttvar = (3*c.rttvar + diff) / 4
+		c.srtt = (7*c.srtt + r) / 8
+	}
+	c.rto = c.calcRTO()
+}
+
+func (c *Conn) calcRTO() time.Duration {
+	if !c.hasRTT {
+		return c.cfg.InitialRTO
+	}
+	rto := c.srtt + max(4*c.rttvar, time.Millisecond)
+	return min(max(rto, c.cfg.MinRTO), c.cfg.MaxRTO)
+}
+
+// timer가 멈춘 경우에만 시작
+func (c *Conn) startTimer() {
+	if c.timerAt.IsZero() {
+		c.restartTimer()
+	}
+}
+
+func (c *Conn) restartTimer() {
+	c.timerAt = time.Now().Add(c.rto)
+	c.timer.Reset(c.rto)
+}
+
+func (c *Conn) stopTimer() {
+	c.timerAt = time.Time{}
+	c.timer.Stop()
+}
+
+// 재전송 timeout
+func (c *Conn) onTimer() {
+	c.mu.Lock()
+	if c.dead || c.timerAt.IsZero() {
+		c.mu.Unlock()
+		return
+	}
+	if d := time.Until(c.timerAt); d > 0 {
+		// Reset 전에 실행이 시작된 이전 timer
+		c.timer.Reset(d)
+		c.mu.Unlock()
+		return
+	}
+	c.timerAt = time.Time{}
+
+	c.retries++
+	if c.retries > c.cfg.MaxRetries {
+		c.mu.Unlock()
+		c.teardown(ErrTimeout)
+		return
+	}
+	c.rto = min(c.rto*2, c.cfg.MaxRTO) // exponential backoff
+
+	if !c.established {
+		c.synRetransmitted = true
+		c.send(&packet{typ: typeSyn})
+	} else {
+		for _, s := range c.segs[:c.sndNxt-c.sndUna] {
+			if !s.sacked {
+				c.retransmit(s)
+			}
+		}
+	}
+	c.startTimer()
+	c.mu.Unlock()
+}
+
+func (c *Conn) Read(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for {
+		switch {
+		case c.closed:
+			return 0, net.ErrClosed
+		case c.readBuf.Len() > 0:
+			n, _ := c.readBuf.Read(p)
+			// window가 닫혀 송신자가 기다리고 있으면 열렸음을 알림
+			if c.advertised == 0 && c.rcvWindow() > 0 && !c.dead {
+				c.sendAck()
+			}
+			return n, nil
+		case c.eof:
+			return 0, io.EOF
+		case c.dead:
+			return 0, c.err
+		case c.cond.ReadExpired():
+			return 0, os.ErrDeadlineExceeded
+		}
+
+		if err := c.cond.WaitRead(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// 데이터를 송신 버퍼에 넣고 반환. 버퍼(Window segment)가 가득 차면 ACK를 받을 때까지 대기
+func (c *Conn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for n < len(p) {
+		switch {
+		case c.closed:
+			return n, net.ErrClosed
+		case c.dead:
+			return n, c.err
+		case c.cond.WriteExpired():
+			return n, os.ErrDeadlineExceeded
+		}
+
+		if int(c.nextSeq-c.sndUna) >= c.cfg.Window {
+			if err := c.cond.WaitWrite(); err != nil {
+				return n, err
+			}
+			continue
+		}
+
+		size := min(len(p)-n, c.cfg.MaxPayload)
+		c.segs = append(c.segs, &segment{seq: c.nextSeq, data: bytes.Clone(p[n : n+size])})
+		c.nextSeq++
+		n += size
+		c.transmit()
+	}
+
+	return n, nil
+}
+
+// FIN을 보내고 바로 반환. 남은 데이터와 FIN은 Linger 동안 백그라운드에서 재전송한다.
+// 상대가 FIN을 보내기 전까지는 상대의 데이터에 계속 ACK한다.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return net.ErrClosed
+	}
+	c.closed = true
+	c.cond.Broadcast() // 대기 중인 Read, Write가 net.ErrClosed를 반환하도록
+
+	if !c.dead {
+		c.segs = append(c.segs, &segment{seq: c.nextSeq, fin: true})
+		c.nextSeq++
+		c.transmit()
+	}
+	c.mu.Unlock()
+
+	go c.linger()
+	return nil
+}
+
+func (c *Conn) linger() {
+	deadline := time.Now().Add(c.cfg.Linger)
+
+	c.mu.Lock()
+	// 보낸 FIN이 ACK되고 상대의 FIN도 받을 때까지
+	for !c.dead && !(c.sndUna == c.nextSeq && c.eof) {
+		if c.cond.Wait(deadline) != nil {
+			break
+		}
+	}
+	c.mu.Unlock()
+
+	c.teardown(nil)
+}
+
+func (c *Conn) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+func (c *Conn) LocalAddr() net.Addr  { return c.pc.LocalAddr() }
+func (c *Conn) RemoteAddr() net.Addr { return c.raddr }
+
+func (c *Conn) SetDeadline(t time.Time) error      { return c.cond.SetDeadline(t) }
+func (c *Conn) SetReadDeadline(t time.Time) error  { return c.cond.SetReadDeadline(t) }
+func (c *Conn) SetWriteDeadline(t time.Time) error { return c.cond.SetWriteDeadline(t) }
@@ -0,0 +1,89 @@
+package rudp
+
+import (
+	"net"
+
+	"github.com/huGgW/network-study-with-go/ch05/internal/demux"
+)
+
+// 하나의 UDP 소켓으로 여러 rudp 연결을 받는 listener. net.Listener 구현
+//
+// 보낸 주소로 연결을 구분하지만, 새 연결은 SYN을 받았을 때만 만들고 형식이 맞지 않는 패킷은 버린다.
+// 이미 끝난 연결의 FIN에는 ACK로 응답하여 상대가 Linger 동안 FIN을 재전송하지 않도록 한다.
+type Listener struct {
+	cfg   Config
+	pc    net.PacketConn
+	table *demux.Table[*Conn]
+}
+
+func Listen(network, address string, cfg *Config) (*Listener, error) {
+	pc, err := net.ListenPacket(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewListener(pc, cfg)
+}
+
+// pc에서 연결을 받는 listener. 설정이 잘못되었어도 pc를 닫는다.
+func NewListener(pc net.PacketConn, cfg *Config) (*Listener, error) {
+	config, err := cfg.withDefaults()
+	if err != nil {
+		_ = pc.Close()
+		return nil, err
+	}
+
+	l := &Listener{cfg: config, pc: pc}
+	l.table = demux.NewTable(pc, config.Backlog, (*Conn).teardown)
+	go l.table.Serve(maxDatagramSize, l.handle)
+
+	return l, nil
+}
+
+func (l *Listener) handle(b []byte, addr net.Addr) {
+	var p packet
+	if p.UnmarshalBinary(b) != nil {
+		return
+	}
+
+	// backlog가 가득 차면 SYN을 무시하여 client가 재전송하도록 함
+	c, _ := l.table.Session(addr, 0, func(key string) *Conn {
+		if p.typ != typeSyn {
+			return nil
+		}
+		var c *Conn
+		c = newConn(l.cfg, l.pc, addr, func() { l.table.Remove(key, c) })
+		c.established = true
+		return c
+	})
+
+	switch {
+	case c != nil:
+		c.handle(&p)
+	case p.typ == typeFin:
+		// 이미 끝난 연결. 상대가 FIN을 계속 재전송하지 않도록 ACK
+		l.send(&packet{typ: typeAck, ack: p.seq + 1, wnd: uint16(l.cfg.Window)}, addr)
+	}
+}
+
+func (l *Listener) send(p *packet, addr net.Addr) {
+	b, err := p.MarshalBinary()
+	if err != nil {
+		return
+	}
+	_, _ = l.pc.WriteTo(b, addr)
+}
+
+func (l *Listener) Accept() (net.Conn, error) {
+	c, err := l.table.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// 새 연결을 받지 않고, Accept되지 않은 연결은 끊음
+// 이미 Accept한 연결은 Close 후 Linger가 끝날 때까지 남은 데이터와 FIN을 재전송한다.
+func (l *Listener) Close() error { return l.table.Close() }
+
+func (l *Listener) Addr() net.Addr { return l.pc.LocalAddr() }
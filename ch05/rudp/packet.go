@@ -0,0 +1,119 @@
+package rudp
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// 패킷 종류
+type packetType uint8
+
+const (
+	typeSyn    packetType = iota + 1 // 연결 요청
+	typeSynAck                       // 연결 승인
+	typeData                         // 데이터 segment
+	typeFin                          // 송신 종료. 데이터와 같이 순서대로 전달되고 ACK됨
+	typeAck                          // 누적 ACK + 선택적 ACK(SACK)
+)
+
+const (
+	// 모든 패킷의 공통 헤더: type(1) + seq(4) + ack(4) + window(2)
+	headerSize = 11
+
+	// ACK 하나에 담는 최대 SACK 구간 수 (count(1) + 구간마다 start(4) + end(4))
+	maxSackBlocks = 4
+
+	// UDP 데이터그램의 최대 크기
+	maxDatagramSize = 65507
+
+	// 헤더를 제외한 segment의 최대 데이터 크기
+	maxPayloadLimit = maxDatagramSize - headerSize
+)
+
+var ErrInvalidPacket = errors.New("rudp: invalid packet")
+
+// 수신자가 받은 segment 구간 [start, end)
+type sackBlock struct {
+	start, end uint32
+}
+
+type packet struct {
+	typ packetType
+	seq uint32 // typeData, typeFin의 segment 번호
+	ack uint32 // typeAck: 다음에 받을 segment 번호 (이전 segment는 모두 받음)
+	wnd uint16 // typeAck: 수신자가 더 받을 수 있는 segment 수
+
+	sack    []sackBlock // typeAck
+	payload []byte      // typeData
+}
+
+// Implements encoding.BinaryMarshaler
+func (p *packet) MarshalBinary() ([]byte, error) {
+	size := headerSize + len(p.payload)
+	if p.typ == typeAck {
+		size += 1 + len(p.sack)*8
+	}
+	if len(p.sack) > maxSackBlocks || size > maxDatagramSize {
+		return nil, ErrInvalidPacket
+	}
+
+	b := make([]byte, headerSize, size)
+	b[0] = byte(p.typ)
+	binary.BigEndian.PutUint32(b[1:], p.seq)
+	binary.BigEndian.PutUint32(b[5:], p.ack)
+	binary.BigEndian.PutUint16(b[9:], p.wnd)
+
+	if p.typ == typeAck {
+		b = append(b, byte(len(p.sack)))
+		for _, s := range p.sack {
+			b = binary.BigEndian.AppendUint32(b, s.start)
+			b = binary.BigEndian.AppendUint32(b, s.end)
+		}
+	}
+
+	return append(b, p.payload...), nil
+}
+
+// Implements encoding.BinaryUnmarshaler
+// payload는 b를 재사용할 수 있도록 복사한다.
+func (p *packet) UnmarshalBinary(b []byte) error {
+	if len(b) < headerSize {
+		return ErrInvalidPacket
+	}
+
+	p.typ = packetType(b[0])
+	if p.typ < typeSyn || p.typ > typeAck {
+		return ErrInvalidPacket
+	}
+	p.seq = binary.BigEndian.Uint32(b[1:])
+	p.ack = binary.BigEndian.Uint32(b[5:])
+	p.wnd = binary.BigEndian.Uint16(b[9:])
+	b = b[headerSize:]
+
+	p.sack, p.payload = nil, nil
+	switch p.typ {
+	case typeAck:
+		if len(b) < 1 {
+			return ErrInvalidPacket
+		}
+		n := int(b[0])
+		if n > maxSackBlocks || len(b) != 1+n*8 {
+			return ErrInvalidPacket
+		}
+		for i := 0; i < n; i++ {
+			o := 1 + i*8
+			p.sack = append(p.sack, sackBlock{
+				start: binary.BigEndian.Uint32(b[o:]),
+				end:   binary.BigEndian.Uint32(b[o+4:]),
+			})
+		}
+	case typeData:
+		p.payload = append([]byte(nil), b...)
+	default:
+		if len(b) != 0 {
+			return ErrInvalidPacket
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,267 @@
+package rudp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 보내는 패킷을 일정 확률로 버리거나 두 번 보내는 PacketConn
+type lossyConn struct {
+	net.PacketConn
+
+	mu        sync.Mutex
+	rand      *rand.Rand
+	loss, dup float64
+	drop      bool // true면 모든 패킷을 버림
+}
+
+func newLossyConn(t *testing.T, loss, dup float64, seed uint64) *lossyConn {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &lossyConn{
+		PacketConn: pc,
+		rand:       rand.New(rand.NewPCG(seed, seed)),
+		loss:       loss,
+		dup:        dup,
+	}
+}
+
+func (c *lossyConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	c.mu.Lock()
+	drop := c.drop || c.rand.Float64() < c.loss
+	dup := c.rand.Float64() < c.dup
+	c.mu.Unlock()
+
+	if drop {
+		return len(b), nil
+	}
+	if dup {
+		_, _ = c.PacketConn.WriteTo(b, addr)
+	}
+	return c.PacketConn.WriteTo(b, addr)
+}
+
+func (c *lossyConn) setDrop(drop bool) {
+	c.mu.Lock()
+	c.drop = drop
+	c.mu.Unlock()
+}
+
+// 테스트에서 빨리 재전송하도록 짧은 timeout 사용
+func testConfig() *Config {
+	return &Config{
+		InitialRTO: 50 * time.Millisecond,
+		MinRTO:     10 * time.Millisecond,
+		MaxRTO:     200 * time.Millisecond,
+		MaxRetries: 20,
+		Linger:     time.Second,
+	}
+}
+
+func listenLossy(t *testing.T, loss, dup float64, cfg *Config) (*Listener, *lossyConn) {
+	pc := newLossyConn(t, loss, dup, 1)
+	l, err := NewListener(pc, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	return l, pc
+}
+
+func dialLossy(t *testing.T, addr net.Addr, loss, dup float64, cfg *Config) (*Conn, *lossyConn) {
+	pc := newLossyConn(t, loss, dup, 2)
+	c, err := Client(pc, addr, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c, pc
+}
+
+func TestPacketMarshal(t *testing.T) {
+	for _, p := range []packet{
+		{typ: typeSyn},
+		{typ: typeData, seq: 7, payload: []byte("hello")},
+		{typ: typeFin, seq: 1<<32 - 1},
+		{typ: typeAck, ack: 3, wnd: 64, sack: []sackBlock{{5, 7}, {9, 10}}},
+	} {
+		b, err := p.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual packet
+		err = actual.UnmarshalBinary(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual.typ != p.typ || actual.seq != p.seq || actual.ack != p.ack || actual.wnd != p.wnd ||
+			!bytes.Equal(actual.payload, p.payload) || len(actual.sack) != len(p.sack) {
+			t.Errorf("expected %+v; actual %+v", p, actual)
+		}
+	}
+
+	for _, b := range [][]byte{
+		{byte(typeData), 0, 0},
+		{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		{byte(typeAck), 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0},
+		{byte(typeSyn), 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+	} {
+		var p packet
+		if err := p.UnmarshalBinary(b); err != ErrInvalidPacket {
+			t.Errorf("%v: expected ErrInvalidPacket; actual %v", b, err)
+		}
+	}
+}
+
+// 양방향 20% 유실, 5% 중복 환경에서 1MiB를 보내고 그대로 돌려받음
+func TestTransferWithLoss(t *testing.T) {
+	l, _ := listenLossy(t, 0.2, 0.05, testConfig())
+
+	data := make([]byte, 1<<20)
+	r := rand.New(rand.NewPCG(3, 3))
+	for i := range data {
+		data[i] = byte(r.Uint32())
+	}
+
+	serverStats := make(chan Stats, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		// 받은 데이터를 그대로 돌려보내고 client가 닫으면 종료
+		_, err = io.Copy(conn, conn)
+		if err != nil {
+			t.Error(err)
+		}
+		serverStats <- conn.(*Conn).Stats()
+		_ = conn.Close()
+	}()
+
+	client, _ := dialLossy(t, l.Addr(), 0.2, 0.05, testConfig())
+
+	go func() {
+		_, err := client.Write(data)
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+
+	echoed := make([]byte, len(data))
+	_, err := io.ReadFull(client, echoed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sha256.Sum256(echoed) != sha256.Sum256(data) {
+		t.Fatal("echoed data differs")
+	}
+	_ = client.Close()
+
+	stats := <-serverStats
+	if stats.Retransmitted == 0 || stats.Duplicates == 0 {
+		t.Errorf("expected retransmissions and duplicates; actual %+v", stats)
+	}
+	t.Logf("client %+v", client.Stats())
+	t.Logf("server %+v", stats)
+}
+
+// 읽지 않는 수신자에게는 window만큼만 보내고, 읽기 시작하면 나머지를 보냄
+func TestFlowControl(t *testing.T) {
+	cfg := testConfig()
+	cfg.Window = 4
+	cfg.MaxPayload = 100
+	l, _ := listenLossy(t, 0, 0, cfg)
+	client, _ := dialLossy(t, l.Addr(), 0, 0, cfg)
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// 수신 버퍼 4개 + 송신 버퍼 4개
+	_ = client.SetWriteDeadline(time.Now().Add(300 * time.Millisecond))
+	n, err := client.Write(make([]byte, 1000))
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded; actual %v", err)
+	}
+	if n != 800 {
+		t.Errorf("expected 800 bytes buffered; actual %d", n)
+	}
+
+	// 수신자가 읽기 시작하면 남은 데이터도 전달
+	_ = client.SetWriteDeadline(time.Time{})
+	go func() { _, _ = client.Write(make([]byte, 200)) }()
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = io.ReadFull(conn, make([]byte, 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadDeadline(t *testing.T) {
+	l, _ := listenLossy(t, 0, 0, testConfig())
+	client, _ := dialLossy(t, l.Addr(), 0, 0, testConfig())
+
+	_ = client.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	_, err := client.Read(make([]byte, 1))
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("expected deadline exceeded; actual %v", err)
+	}
+}
+
+// 상대가 응답하지 않으면 재전송을 MaxRetries번 반복한 뒤 ErrTimeout
+func TestPeerTimeout(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxRetries = 3
+	l, serverPC := listenLossy(t, 0, 0, cfg)
+	client, _ := dialLossy(t, l.Addr(), 0, 0, cfg)
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// 서버의 ACK가 모두 유실됨
+	serverPC.setDrop(true)
+	_, err = client.Write([]byte("ping"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = client.Read(make([]byte, 1))
+	if err != ErrTimeout {
+		t.Errorf("expected ErrTimeout; actual %v", err)
+	}
+}
+
+func TestDialTimeout(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxRetries = 2
+
+	// 응답하지 않는 주소
+	pc, err := net.ListenPacket("udp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	_, err = Dial("udp", pc.LocalAddr().String(), cfg)
+	if err != ErrTimeout {
+		t.Errorf("expected ErrTimeout; actual %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+// 여러 데이터그램을 한 번의 시스템 콜로 읽고 쓰는 PacketConn
+//
+// ReadFrom, WriteTo는 데이터그램마다 시스템 콜을 호출하므로, 작은 패킷이 많으면 시스템 콜 비용이 대부분을 차지한다.
+// Linux에서는 recvmmsg, sendmmsg(golang.org/x/net/ipv4, ipv6의 ReadBatch, WriteBatch)로 여러 데이터그램을
+// 한 번에 처리하고, 다른 플랫폼이나 UDP가 아닌 소켓(unixgram 등)에서는 ReadFrom, WriteTo로 하나씩 처리한다.
+package udpbatch
+
+import (
+	"net"
+
+	"golang.org/x/net/ipv4"
+)
+
+// 데이터그램 하나. ipv4.Message와 ipv6.Message는 같은 타입
+//
+// 읽을 때는 Buffers에 받을 공간을 두면 N에 받은 크기, Addr에 송신자 주소가 채워지고,
+// 쓸 때는 Buffers의 데이터를 Addr로 보낸다.
+type Message = ipv4.Message
+
+// 데이터그램을 여러 개씩 읽고 쓸 수 있는 net.PacketConn
+type Conn interface {
+	net.PacketConn
+
+	// ms에 데이터그램을 최대 len(ms)개 읽고 읽은 수를 반환. 하나 이상 도착할 때까지 대기
+	ReadBatch(ms []Message) (int, error)
+
+	// ms의 데이터그램을 앞에서부터 보내고 보낸 수를 반환. 모두 보내지 못할 수 있음
+	WriteBatch(ms []Message) (int, error)
+}
+
+// pc를 Conn으로 감쌈. pc가 UDP 소켓이면 플랫폼이 지원하는 batch I/O를 사용
+func NewConn(pc net.PacketConn) Conn {
+	if uc, ok := pc.(*net.UDPConn); ok {
+		if c := newBatchConn(uc); c != nil {
+			return c
+		}
+	}
+	return &singleConn{pc}
+}
+
+// 데이터그램마다 size byte의 버퍼를 가진 Message n개
+func NewMessages(n, size int) []Message {
+	buf := make([]byte, n*size)
+	ms := make([]Message, n)
+	for i := range ms {
+		ms[i].Buffers = [][]byte{buf[i*size : (i+1)*size : (i+1)*size]}
+	}
+	return ms
+}
+
+// ms를 모두 보낼 때까지 WriteBatch를 반복
+func WriteAll(c Conn, ms []Message) error {
+	for len(ms) > 0 {
+		n, err := c.WriteBatch(ms)
+		if err != nil {
+			return err
+		}
+		ms = ms[n:]
+	}
+	return nil
+}
+
+// 데이터그램을 하나씩 처리하는 Conn. Message의 Buffers 중 첫 번째 버퍼만 사용
+type singleConn struct {
+	net.PacketConn
+}
+
+func (c *singleConn) ReadBatch(ms []Message) (int, error) {
+	if len(ms) == 0 {
+		return 0, nil
+	}
+
+	n, addr, err := c.ReadFrom(ms[0].Buffers[0])
+	if err != nil {
+		return 0, err
+	}
+	ms[0].N, ms[0].Addr, ms[0].Flags = n, addr, 0
+	return 1, nil
+}
+
+func (c *singleConn) WriteBatch(ms []Message) (int, error) {
+	for i := range ms {
+		n, err := c.WriteTo(ms[i].Buffers[0], ms[i].Addr)
+		if err != nil {
+			return i, err
+		}
+		ms[i].N = n
+	}
+	return len(ms), nil
+}
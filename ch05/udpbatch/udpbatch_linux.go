@@ -0,0 +1,44 @@
+package udpbatch
+
+import (
+	"net"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// ipv4.PacketConn, ipv6.PacketConn의 공통 메서드
+type batchReadWriter interface {
+	ReadBatch(ms []Message, flags int) (int, error)
+	WriteBatch(ms []Message, flags int) (int, error)
+}
+
+// recvmmsg, sendmmsg를 사용하는 Conn
+type batchConn struct {
+	*net.UDPConn
+	rw batchReadWriter
+}
+
+func newBatchConn(c *net.UDPConn) Conn {
+	addr, ok := c.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		return nil
+	}
+
+	// 소켓의 address family에 맞는 패키지 사용. "udp"로 [::]에 bind하면 IPv4도 받는 IPv6 소켓
+	var rw batchReadWriter
+	if addr.IP.To4() != nil {
+		rw = ipv4.NewPacketConn(c)
+	} else {
+		rw = ipv6.NewPacketConn(c)
+	}
+	return &batchConn{UDPConn: c, rw: rw}
+}
+
+func (c *batchConn) ReadBatch(ms []Message) (int, error) {
+	return c.rw.ReadBatch(ms, 0)
+}
+
+func (c *batchConn) WriteBatch(ms []Message) (int, error) {
+	return c.rw.WriteBatch(ms, 0)
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package udpbatch
+
+import "net"
+
+// x/net은 Linux에서만 여러 데이터그램을 한 번에 처리하므로 다른 플랫폼에서는 singleConn 사용
+func newBatchConn(*net.UDPConn) Conn {
+	return nil
+}
@@ -0,0 +1,86 @@
+package udpbatch
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testBatch(t *testing.T, network, serverAddr, clientAddr string) {
+	server, err := net.ListenPacket(network, serverAddr)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer server.Close()
+	client, err := net.ListenPacket(network, clientAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	sc, cc := NewConn(server), NewConn(client)
+
+	const count = 8
+	out := make([]Message, count)
+	for i := range out {
+		out[i].Buffers = [][]byte{[]byte(fmt.Sprintf("msg %d", i))}
+		out[i].Addr = server.LocalAddr()
+	}
+	err = WriteAll(cc, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ms := NewMessages(count, 16)
+	received := 0
+	_ = server.SetReadDeadline(time.Now().Add(time.Second))
+	for received < count {
+		n, err := sc.ReadBatch(ms[received:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range ms[received : received+n] {
+			expected := fmt.Sprintf("msg %d", received)
+			if actual := string(m.Buffers[0][:m.N]); actual != expected {
+				t.Errorf("expected %q; actual %q", expected, actual)
+			}
+			if m.Addr.String() != client.LocalAddr().String() {
+				t.Errorf("expected from %s; actual %s", client.LocalAddr(), m.Addr)
+			}
+			received++
+		}
+	}
+}
+
+func TestBatchUDP4(t *testing.T) {
+	testBatch(t, "udp", "127.0.0.1:", "127.0.0.1:")
+}
+
+func TestBatchUDP6(t *testing.T) {
+	testBatch(t, "udp", "[::1]:", "[::1]:")
+}
+
+// UDP가 아닌 소켓은 하나씩 처리
+func TestBatchUnixgram(t *testing.T) {
+	dir, err := os.MkdirTemp("", "udpbatch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testBatch(t, "unixgram", filepath.Join(dir, "server.sock"), filepath.Join(dir, "client.sock"))
+}
+
+func TestNewMessages(t *testing.T) {
+	ms := NewMessages(3, 4)
+	ms[0].Buffers[0] = append(ms[0].Buffers[0], 'x') // 용량을 넘으면 다른 Message의 버퍼를 덮어쓰지 않음
+
+	for i, m := range ms[1:] {
+		if len(m.Buffers) != 1 || len(m.Buffers[0]) != 4 || m.Buffers[0][0] != 0 {
+			t.Errorf("%d: unexpected buffers %v", i+1, m.Buffers)
+		}
+	}
+}
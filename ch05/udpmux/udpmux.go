@@ -0,0 +1,287 @@
+// 하나의 UDP 소켓을 상대 주소별 연결로 나누는 listener
+//
+// echoServerUDP처럼 ReadFrom 루프 하나로 모든 상대를 처리하면 상대별 상태를 직접 관리해야 한다.
+// Listener는 처음 보는 주소에서 데이터그램이 오면 새 Conn을 만들어 Accept로 반환하고,
+// 이후 그 주소에서 온 데이터그램은 해당 Conn의 Read로 전달하므로 TCP 서버처럼 연결마다 고루틴을 둘 수 있다.
+//
+// UDP이므로 전달과 순서는 보장하지 않는다. Read는 데이터그램 하나를 반환하고, 버퍼보다 큰 부분은 버린다.
+// 유실과 순서를 처리한 byte stream이 필요하면 rudp를 사용한다.
+package udpmux
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/huGgW/network-study-with-go/ch05/internal/demux"
+)
+
+// 일정 시간 데이터그램을 주고받지 않아 닫힌 연결의 Read, Write 에러
+var ErrIdleTimeout = errors.New("udpmux: session idle timeout")
+
+// Listener 설정. 0인 값은 기본값 사용
+type Config struct {
+	IdleTimeout     time.Duration // 데이터그램을 주고받지 않으면 연결을 닫는 시간 (기본 30초)
+	MaxSessions     int           // 동시에 유지할 최대 연결 수. 초과하면 새 주소의 데이터그램을 버림 (기본 1024)
+	Backlog         int           // Accept를 기다리는 최대 연결 수. 초과하면 새 주소의 데이터그램을 버림 (기본 128)
+	QueueSize       int           // 연결마다 Read를 기다리는 최대 데이터그램 수 (기본 64)
+	MaxDatagramSize int           // 받을 수 있는 최대 데이터그램 크기 (기본 65507)
+
+	// true면 연결의 큐가 가득 찼을 때 데이터그램을 버리지 않고 Read할 때까지 소켓 읽기를 멈춤
+	// 느린 연결 하나가 다른 모든 연결을 막을 수 있으나, 초과한 데이터그램은 커널의 수신 버퍼에서 대기한다.
+	Block bool
+}
+
+func (c *Config) withDefaults() Config {
+	var cfg Config
+	if c != nil {
+		cfg = *c
+	}
+
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = 30 * time.Second
+	}
+	if cfg.MaxSessions <= 0 {
+		cfg.MaxSessions = 1024
+	}
+	if cfg.Backlog <= 0 {
+		cfg.Backlog = 128
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 64
+	}
+	if cfg.MaxDatagramSize <= 0 {
+		cfg.MaxDatagramSize = 65507
+	}
+
+	return cfg
+}
+
+// Listener 통계
+type Stats struct {
+	Sessions int    // 현재 연결 수
+	Accepted uint64 // 생성된 연결 수
+	Expired  uint64 // IdleTimeout으로 닫힌 연결 수
+	Rejected uint64 // MaxSessions, Backlog를 초과하여 버린 새 주소의 데이터그램 수
+	Dropped  uint64 // 연결의 큐가 가득 차서 버린 데이터그램 수
+}
+
+// 데이터그램을 상대 주소별 Conn의 큐로 나누는 listener. net.Listener 구현
+//
+// 처음 보는 주소에서 온 데이터그램은 내용과 상관없이 새 연결을 만든다.
+// IdleTimeout 동안 데이터그램을 주고받지 않은 연결은 닫는다.
+type Listener struct {
+	cfg   Config
+	pc    net.PacketConn
+	table *demux.Table[*Conn]
+
+	accepted, expired, rejected, dropped atomic.Uint64
+}
+
+func Listen(network, address string, cfg *Config) (*Listener, error) {
+	pc, err := net.ListenPacket(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewListener(pc, cfg), nil
+}
+
+// pc에서 연결을 받는 listener. Listener가 pc를 닫는다.
+func NewListener(pc net.PacketConn, cfg *Config) *Listener {
+	config := cfg.withDefaults()
+	l := &Listener{
+		cfg:   config,
+		pc:    pc,
+		table: demux.NewTable(pc, config.Backlog, func(c *Conn, err error) { c.close(err) }),
+	}
+	go l.table.Serve(config.MaxDatagramSize, l.handle)
+	go l.expireLoop()
+
+	return l
+}
+
+func (l *Listener) handle(b []byte, addr net.Addr) {
+	c, created := l.table.Session(addr, l.cfg.MaxSessions, func(key string) *Conn {
+		c := &Conn{l: l, key: key, raddr: addr}
+		c.cond.L = &c.mu
+		c.touch()
+		return c
+	})
+	if c == nil {
+		l.rejected.Add(1)
+		return
+	}
+	if created {
+		l.accepted.Add(1)
+	}
+	if !c.enqueue(append([]byte(nil), b...), l.cfg.Block) {
+		l.dropped.Add(1)
+	}
+}
+
+// IdleTimeout보다 오래 데이터그램을 주고받지 않은 연결을 닫음
+func (l *Listener) expireLoop() {
+	t := time.NewTicker(max(l.cfg.IdleTimeout/4, 10*time.Millisecond))
+	defer t.Stop()
+
+	for {
+		select {
+		case <-l.table.Done():
+			// Close 이후에도 Accept된 연결이 남아 있으면 계속 만료시켜야 소켓이 닫힘
+			if l.Len() == 0 {
+				return
+			}
+		default:
+		}
+		<-t.C
+
+		deadline := time.Now().Add(-l.cfg.IdleTimeout).UnixNano()
+		for _, c := range l.table.Sessions() {
+			if c.active.Load() < deadline && c.close(ErrIdleTimeout) {
+				l.expired.Add(1)
+			}
+		}
+	}
+}
+
+func (l *Listener) Accept() (net.Conn, error) {
+	c, err := l.table.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// 새 연결을 받지 않고, Accept되지 않은 연결은 닫음
+// 이미 Accept한 연결은 IdleTimeout이 지나거나 Close할 때까지 동작한다.
+func (l *Listener) Close() error { return l.table.Close() }
+
+func (l *Listener) Addr() net.Addr { return l.pc.LocalAddr() }
+
+// 현재 연결 수
+func (l *Listener) Len() int { return l.table.Len() }
+
+func (l *Listener) Stats() Stats {
+	return Stats{
+		Sessions: l.Len(),
+		Accepted: l.accepted.Load(),
+		Expired:  l.expired.Load(),
+		Rejected: l.rejected.Load(),
+		Dropped:  l.dropped.Load(),
+	}
+}
+
+// 상대 주소 하나와 주고받는 연결. net.Conn 구현
+type Conn struct {
+	l     *Listener
+	key   string
+	raddr net.Addr
+
+	active atomic.Int64 // 마지막으로 데이터그램을 주고받은 시각 (UnixNano)
+
+	mu     sync.Mutex
+	cond   demux.Cond // 큐와 닫힌 상태가 바뀌면 Broadcast
+	queue  [][]byte
+	closed bool
+	err    error // 닫힌 원인
+}
+
+func (c *Conn) touch() { c.active.Store(time.Now().UnixNano()) }
+
+// 받은 데이터그램을 큐에 넣음. 버렸으면 false
+func (c *Conn) enqueue(b []byte, block bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.queue) >= c.l.cfg.QueueSize {
+		if !block || c.closed {
+			return false
+		}
+		_ = c.cond.Wait(time.Time{})
+	}
+	if c.closed {
+		return false
+	}
+
+	c.queue = append(c.queue, b)
+	c.touch()
+	c.cond.Broadcast()
+	return true
+}
+
+// 데이터그램 하나를 읽음. p보다 큰 데이터그램은 잘림
+func (c *Conn) Read(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for {
+		switch {
+		case len(c.queue) > 0:
+			n := copy(p, c.queue[0])
+			c.queue[0] = nil
+			c.queue = c.queue[1:]
+			c.cond.Broadcast() // Block이면 큐가 비기를 기다리는 Listener의 읽기를 깨움
+			return n, nil
+		case c.closed:
+			return 0, c.err
+		case c.cond.ReadExpired():
+			return 0, os.ErrDeadlineExceeded
+		}
+
+		if err := c.cond.WaitRead(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// p를 데이터그램 하나로 상대에게 전송
+func (c *Conn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	closed, err, expired := c.closed, c.err, c.cond.WriteExpired()
+	c.mu.Unlock()
+
+	switch {
+	case closed:
+		return 0, err
+	case expired:
+		return 0, os.ErrDeadlineExceeded
+	}
+
+	c.touch()
+	return c.l.pc.WriteTo(p, c.raddr)
+}
+
+func (c *Conn) Close() error {
+	if !c.close(net.ErrClosed) {
+		return net.ErrClosed
+	}
+	return nil
+}
+
+// 연결을 닫고 Listener에서 제거. 이미 닫혔으면 false
+func (c *Conn) close(err error) bool {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return false
+	}
+	c.closed = true
+	c.err = err
+	c.queue = nil
+	c.cond.Broadcast()
+	c.mu.Unlock()
+
+	c.l.table.Remove(c.key, c)
+	return true
+}
+
+func (c *Conn) LocalAddr() net.Addr  { return c.l.pc.LocalAddr() }
+func (c *Conn) RemoteAddr() net.Addr { return c.raddr }
+
+func (c *Conn) SetDeadline(t time.Time) error      { return c.cond.SetDeadline(t) }
+func (c *Conn) SetReadDeadline(t time.Time) error  { return c.cond.SetReadDeadline(t) }
+func (c *Conn) SetWriteDeadline(t time.Time) error { return c.cond.SetWriteDeadline(t) }
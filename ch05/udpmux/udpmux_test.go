@@ -0,0 +1,264 @@
+package udpmux
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func listen(t *testing.T, cfg *Config) *Listener {
+	l, err := Listen("udp", "127.0.0.1:", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	return l
+}
+
+func dial(t *testing.T, l *Listener) net.Conn {
+	client, err := net.Dial("udp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func write(t *testing.T, conn net.Conn, msg string) {
+	_, err := conn.Write([]byte(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func read(t *testing.T, conn net.Conn) string {
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf[:n])
+}
+
+// TCP 서버처럼 연결마다 고루틴을 두는 echo 서버
+func TestEchoPerPeer(t *testing.T) {
+	l := listen(t, nil)
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 1024)
+				for {
+					n, err := conn.Read(buf)
+					if err != nil {
+						return
+					}
+					// 연결마다 상태를 가질 수 있음
+					_, _ = conn.Write(append([]byte(conn.RemoteAddr().String()+" "), buf[:n]...))
+				}
+			}()
+		}
+	}()
+
+	for i := 0; i < 3; i++ {
+		client := dial(t, l)
+		for _, msg := range []string{"ping", "pong"} {
+			write(t, client, msg)
+			expected := client.LocalAddr().String() + " " + msg
+			if actual := read(t, client); actual != expected {
+				t.Errorf("expected %q; actual %q", expected, actual)
+			}
+		}
+	}
+
+	if s := l.Stats(); s.Sessions != 3 || s.Accepted != 3 {
+		t.Errorf("expected 3 sessions; actual %+v", s)
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	l := listen(t, &Config{IdleTimeout: 50 * time.Millisecond})
+	client := dial(t, l)
+
+	write(t, client, "ping")
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := read(t, conn); actual != "ping" {
+		t.Fatalf("expected ping; actual %q", actual)
+	}
+
+	// 아무것도 주고받지 않으면 연결이 닫힘
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	if err != ErrIdleTimeout {
+		t.Fatalf("expected ErrIdleTimeout; actual %v", err)
+	}
+	if s := l.Stats(); s.Sessions != 0 || s.Expired != 1 {
+		t.Errorf("expected expired session; actual %+v", s)
+	}
+
+	// 같은 주소에서 다시 보내면 새 연결
+	write(t, client, "again")
+	conn, err = l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := read(t, conn); actual != "again" {
+		t.Errorf("expected again; actual %q", actual)
+	}
+}
+
+func TestMaxSessions(t *testing.T) {
+	l := listen(t, &Config{MaxSessions: 2})
+
+	for i := 0; i < 2; i++ {
+		write(t, dial(t, l), "hello")
+		_, err := l.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(t, dial(t, l), "rejected")
+	time.Sleep(50 * time.Millisecond)
+	if s := l.Stats(); s.Sessions != 2 || s.Rejected != 1 {
+		t.Errorf("expected 1 rejected; actual %+v", s)
+	}
+}
+
+func TestBacklog(t *testing.T) {
+	l := listen(t, &Config{Backlog: 1})
+
+	write(t, dial(t, l), "first")
+	write(t, dial(t, l), "second") // Accept하지 않아 backlog 초과
+	time.Sleep(50 * time.Millisecond)
+
+	if s := l.Stats(); s.Accepted != 1 || s.Rejected != 1 {
+		t.Errorf("expected 1 accepted and 1 rejected; actual %+v", s)
+	}
+}
+
+// 큐가 가득 차면 버림
+func TestQueueDrop(t *testing.T) {
+	l := listen(t, &Config{QueueSize: 2})
+	client := dial(t, l)
+
+	for _, msg := range []string{"1", "2", "3"} {
+		write(t, client, msg)
+	}
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	for _, expected := range []string{"1", "2"} {
+		if actual := read(t, conn); actual != expected {
+			t.Errorf("expected %q; actual %q", expected, actual)
+		}
+	}
+	if s := l.Stats(); s.Dropped != 1 {
+		t.Errorf("expected 1 dropped; actual %+v", s)
+	}
+}
+
+// Block이면 Read할 때까지 기다리므로 버리지 않음
+func TestQueueBlock(t *testing.T) {
+	l := listen(t, &Config{QueueSize: 1, Block: true})
+	client := dial(t, l)
+
+	for _, msg := range []string{"1", "2", "3"} {
+		write(t, client, msg)
+	}
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	for _, expected := range []string{"1", "2", "3"} {
+		if actual := read(t, conn); actual != expected {
+			t.Errorf("expected %q; actual %q", expected, actual)
+		}
+	}
+	if s := l.Stats(); s.Dropped != 0 {
+		t.Errorf("expected no drops; actual %+v", s)
+	}
+}
+
+// Listener를 닫아도 Accept한 연결은 동작하고, 마지막 연결이 닫히면 소켓도 닫힘
+func TestListenerClose(t *testing.T) {
+	l := listen(t, nil)
+	client := dial(t, l)
+
+	write(t, client, "ping")
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = read(t, conn)
+
+	_ = l.Close()
+	if _, err := l.Accept(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("expected net.ErrClosed; actual %v", err)
+	}
+
+	write(t, client, "still open")
+	if actual := read(t, conn); actual != "still open" {
+		t.Errorf("expected %q; actual %q", "still open", actual)
+	}
+	write(t, conn, "pong")
+	if actual := read(t, client); actual != "pong" {
+		t.Errorf("expected pong; actual %q", actual)
+	}
+
+	_ = conn.Close()
+	_, _, err = l.pc.ReadFrom(make([]byte, 1))
+	if !errors.Is(err, net.ErrClosed) {
+		t.Errorf("expected socket closed; actual %v", err)
+	}
+}
+
+func TestRead(t *testing.T) {
+	l := listen(t, nil)
+	client := dial(t, l)
+	write(t, client, "ping")
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = read(t, conn)
+
+	_ = conn.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+	_, err = conn.Read(make([]byte, 1))
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("expected deadline exceeded; actual %v", err)
+	}
+
+	// 버퍼보다 큰 데이터그램은 잘리고 나머지는 버려짐
+	write(t, client, "0123456789")
+	write(t, client, "next")
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 4)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf[:n], []byte("0123")) {
+		t.Errorf("expected %q; actual %q", "0123", buf[:n])
+	}
+	if actual := read(t, conn); actual != "next" {
+		t.Errorf("expected next; actual %q", actual)
+	}
+}
@@ -2,8 +2,12 @@ package echo
 
 import (
 	"context"
+	"errors"
 	"net"
 	"os"
+
+	"github.com/huGgW/network-study-with-go/ch04/sockopt"
+	"github.com/huGgW/network-study-with-go/ch05/udpbatch"
 )
 
 // 스트림 기반의 네트워크 타입을 네트워크 문자열로 전달받아
@@ -13,80 +17,184 @@ import (
 // 네트워크 타입이 unix, unixpacket의 경우 주소는 존재하지 않는 파일 경로.
 func streamingEchoServer(
 	ctx context.Context, network string, addr string,
+) (net.Addr, error) {
+	return streamingEchoServerWithOptions(ctx, network, addr, sockopt.Options{})
+}
+
+// listen 소켓에 opts를 적용하는 streamingEchoServer
+func streamingEchoServerWithOptions(
+	ctx context.Context, network string, addr string, opts sockopt.Options,
 ) (net.Addr, error) {
     // net.Listen 혹은 net.ListenUnix를 사용하는 경우, close 시 소켓 파일 제거해줌.
-	s, err := net.Listen(network, addr)
+	s, err := opts.Listen(ctx, network, addr)
 	if err != nil {
 		return nil, err
 	}
 
 	go func() {
-		go func() {
-			<-ctx.Done() // context를 취소하면 서버가 종료되도록
-			_ = s.Close()
-		}()
+		<-ctx.Done() // context를 취소하면 서버가 종료되도록
+		_ = s.Close()
+	}()
+	go serveStreamingEcho(s)
 
-		for {
-			conn, err := s.Accept() // 연결 수립
-			if err != nil {
-				return
-			}
+	return s.Addr(), nil
+}
 
-			go func() {
-				defer func() { conn.Close() }()
+// SO_REUSEPORT로 같은 주소에 shards개의 listener를 열고 listener마다 accept 루프를 실행
+// 커널이 연결을 shard에 분산하므로 accept 루프 하나가 병목이 되지 않는다.
+// 반환된 Shards로 주소와 shard별 연결 수를 확인할 수 있으며, ctx를 취소하면 모든 listener가 닫힌다.
+func shardedStreamingEchoServer(
+	ctx context.Context, network string, addr string, shards int, opts sockopt.Options,
+) (*sockopt.Shards, error) {
+	s, err := opts.ListenShards(ctx, network, addr, shards)
+	if err != nil {
+		return nil, err
+	}
 
-				for {
-					buf := make([]byte, 1024)
-					n, err := conn.Read(buf) // Read
-					if err != nil {
-						return
-					}
+	go func() {
+		<-ctx.Done()
+		_ = s.Close()
+	}()
+	for _, l := range s.Listeners {
+		go serveStreamingEcho(l)
+	}
 
-					_, err = conn.Write(buf[:n]) // Write
-					if err != nil {
-						return
-					}
-				}
-			}()
+	return s, nil
+}
+
+// listener가 닫힐 때까지 연결을 받아 echo
+func serveStreamingEcho(s net.Listener) {
+	for {
+		conn, err := s.Accept() // 연결 수립
+		if err != nil {
+			return
 		}
-	}()
 
-	return s.Addr(), nil
+		go func() {
+			defer func() { conn.Close() }()
+
+			for {
+				buf := make([]byte, 1024)
+				n, err := conn.Read(buf) // Read
+				if err != nil {
+					return
+				}
+
+				_, err = conn.Write(buf[:n]) // Write
+				if err != nil {
+					return
+				}
+			}
+		}()
+	}
 }
 
 // 데이터그램 기반 네트워크 타입을 이용한 echo server
 func datagramEchoServer(
 	ctx context.Context, network string, addr string,
 ) (net.Addr, error) {
+	return datagramEchoServerWithOptions(ctx, network, addr, sockopt.Options{})
+}
+
+// 소켓에 opts를 적용하는 datagramEchoServer
+func datagramEchoServerWithOptions(
+	ctx context.Context, network string, addr string, opts sockopt.Options,
+) (net.Addr, error) {
+	s, err := listenDatagram(ctx, network, addr, opts)
+	if err != nil {
+		return nil, err
+	}
+	go serveDatagramEcho(s)
+
+	return s.LocalAddr(), nil
+}
+
+// 데이터그램을 batchSize개씩 읽고 쓰는 datagramEchoServer
+// UDP 소켓이면 Linux에서 recvmmsg, sendmmsg로 시스템 콜 한 번에 여러 데이터그램을 처리한다.
+func batchDatagramEchoServer(
+	ctx context.Context, network string, addr string, batchSize int, opts sockopt.Options,
+) (net.Addr, error) {
+	s, err := listenDatagram(ctx, network, addr, opts)
+	if err != nil {
+		return nil, err
+	}
+	go serveBatchDatagramEcho(udpbatch.NewConn(s), batchSize)
+
+	return s.LocalAddr(), nil
+}
+
+// ctx를 취소하면 닫히는 데이터그램 소켓
+func listenDatagram(
+	ctx context.Context, network string, addr string, opts sockopt.Options,
+) (net.PacketConn, error) {
     // net.ListenPacket은 close시 소켓 파일을 따로 제거하지 않음.
-	s, err := net.ListenPacket(network, addr)
+	s, err := opts.ListenPacket(ctx, network, addr)
 	if err != nil {
 		return nil, err
 	}
 
 	go func() {
-		go func() {
-			<-ctx.Done()
-			s.Close()
-			if network == "unixgram" {
-			    // unix domain socket인 경우 수동으로 해당 파일을 제거해주어야 함.
-				os.Remove(addr)
-			}
-		}()
+		<-ctx.Done()
+		s.Close()
+		if network == "unixgram" {
+		    // unix domain socket인 경우 수동으로 해당 파일을 제거해주어야 함.
+			os.Remove(addr)
+		}
+	}()
 
-		buf := make([]byte, 1024)
-		for {
-			n, clientAddr, err := s.ReadFrom(buf)
-			if err != nil {
+	return s, nil
+}
+
+// 소켓이 닫힐 때까지 받은 데이터그램을 그대로 돌려보냄
+func serveDatagramEcho(s net.PacketConn) {
+	buf := make([]byte, 1024)
+	for {
+		n, clientAddr, err := s.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		_, err = s.WriteTo(buf[:n], clientAddr)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// 받은 데이터그램을 모아서 한 번에 돌려보냄
+// 보내지 못한 데이터그램은 건너뛰고, 소켓이 닫힐 때까지 계속 동작
+func serveBatchDatagramEcho(s udpbatch.Conn, batchSize int) {
+	ms := udpbatch.NewMessages(batchSize, 1024)
+	out := make([]udpbatch.Message, batchSize)
+	for i := range out {
+		out[i].Buffers = make([][]byte, 1)
+	}
+
+	for {
+		n, err := s.ReadBatch(ms)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
 				return
 			}
+			continue
+		}
 
-			_, err = s.WriteTo(buf[:n], clientAddr)
+		for i, m := range ms[:n] {
+			out[i].Buffers[0] = m.Buffers[0][:m.N]
+			out[i].Addr = m.Addr
+		}
+		for rest := out[:n]; len(rest) > 0; {
+			sent, err := s.WriteBatch(rest)
+			rest = rest[sent:]
 			if err != nil {
-				return
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+				if len(rest) > 0 {
+					// 상대 소켓이 사라진 경우 등, 보내지 못한 데이터그램은 건너뛰고 나머지 전송
+					rest = rest[1:]
+				}
 			}
 		}
-	}()
-
-	return s.LocalAddr(), nil
+	}
 }
@@ -0,0 +1,204 @@
+package echo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/huGgW/network-study-with-go/ch04/sockopt"
+	"github.com/huGgW/network-study-with-go/ch05/udpbatch"
+)
+
+// 커널 수신 버퍼가 넘쳐 버려지는 패킷을 줄이기 위해 버퍼를 키움
+var benchOptions = sockopt.Options{RecvBuffer: 4 << 20, SendBuffer: 4 << 20}
+
+func testBatchEcho(t *testing.T, network, serverAddr, clientAddr string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, err := batchDatagramEchoServer(ctx, network, serverAddr, 8, sockopt.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pc, err := net.ListenPacket(network, clientAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	client := udpbatch.NewConn(pc)
+
+	const count = 20
+	out := make([]udpbatch.Message, count)
+	for i := range out {
+		out[i].Buffers = [][]byte{[]byte(fmt.Sprintf("ping %d", i))}
+		out[i].Addr = addr
+	}
+	err = udpbatch.WriteAll(client, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ms := udpbatch.NewMessages(count, 64)
+	received := 0
+	_ = pc.SetReadDeadline(time.Now().Add(time.Second))
+	for received < count {
+		n, err := client.ReadBatch(ms[received:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range ms[received : received+n] {
+			expected := fmt.Sprintf("ping %d", received)
+			if actual := string(m.Buffers[0][:m.N]); actual != expected {
+				t.Errorf("expected %q; actual %q", expected, actual)
+			}
+			received++
+		}
+	}
+}
+
+func TestBatchDatagramEchoServerUDP(t *testing.T) {
+	testBatchEcho(t, "udp", "127.0.0.1:", "127.0.0.1:")
+}
+
+// 첫 WriteBatch가 실패하는 Conn
+type failOnceConn struct {
+	udpbatch.Conn
+	failed atomic.Bool
+}
+
+func (c *failOnceConn) WriteBatch(ms []udpbatch.Message) (int, error) {
+	if c.failed.CompareAndSwap(false, true) {
+		return 0, errors.New("unreachable")
+	}
+	return c.Conn.WriteBatch(ms)
+}
+
+// 보내지 못한 데이터그램은 건너뛰고 계속 echo
+func TestBatchDatagramEchoSkipsFailedWrite(t *testing.T) {
+	s, err := net.ListenPacket("udp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	go serveBatchDatagramEcho(&failOnceConn{Conn: udpbatch.NewConn(s)}, 8)
+
+	client, err := net.ListenPacket("udp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	for _, msg := range []string{"dropped", "ping"} {
+		if _, err := client.WriteTo([]byte(msg), s.LocalAddr()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buf := make([]byte, 64)
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := client.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := string(buf[:n]); actual != "ping" {
+		t.Errorf("expected %q; actual %q", "ping", actual)
+	}
+}
+
+// 클라이언트마다 window개의 패킷을 한 번에 보내고 돌아온 패킷 수로 초당 처리량(pkts/s)을 측정
+// 클라이언트는 항상 batch I/O를 사용하므로 서버의 처리 방식만 비교된다.
+func benchmarkDatagramEcho(b *testing.B, addr net.Addr) {
+	const window = 32
+	msg := make([]byte, 64)
+
+	var echoed, lost atomic.Int64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		pc, err := benchOptions.ListenPacket(context.Background(), "udp", "127.0.0.1:")
+		if err != nil {
+			b.Error(err)
+			return
+		}
+		defer pc.Close()
+		client := udpbatch.NewConn(pc)
+
+		out := make([]udpbatch.Message, window)
+		for i := range out {
+			out[i].Buffers = [][]byte{msg}
+			out[i].Addr = addr
+		}
+		in := udpbatch.NewMessages(window, len(msg))
+
+		for {
+			n := 0
+			for n < window && pb.Next() {
+				n++
+			}
+			if n == 0 {
+				return
+			}
+
+			err := udpbatch.WriteAll(client, out[:n])
+			if err != nil {
+				b.Error(err)
+				return
+			}
+
+			// 유실된 패킷은 기다리지 않음
+			_ = pc.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+			received := 0
+			for received < n {
+				r, err := client.ReadBatch(in[:n-received])
+				if err != nil {
+					if !errors.Is(err, os.ErrDeadlineExceeded) {
+						b.Error(err)
+						return
+					}
+					break
+				}
+				received += r
+			}
+			echoed.Add(int64(received))
+			lost.Add(int64(n - received))
+		}
+	})
+	b.StopTimer()
+
+	b.ReportMetric(float64(echoed.Load())/b.Elapsed().Seconds(), "pkts/s")
+	b.ReportMetric(float64(lost.Load())/float64(b.N), "loss")
+}
+
+func BenchmarkDatagramEcho(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, err := datagramEchoServerWithOptions(ctx, "udp", "127.0.0.1:", benchOptions)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	benchmarkDatagramEcho(b, addr)
+}
+
+func BenchmarkBatchDatagramEcho(b *testing.B) {
+	for _, size := range []int{8, 32, 64} {
+		b.Run(fmt.Sprintf("batch=%d", size), func(b *testing.B) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			addr, err := batchDatagramEchoServer(ctx, "udp", "127.0.0.1:", size, benchOptions)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			benchmarkDatagramEcho(b, addr)
+		})
+	}
+}
@@ -87,3 +87,14 @@ func TestEchoServerUnixDatagram(t *testing.T) {
 		}
 	}
 }
+
+// UDP가 아닌 소켓은 하나씩 처리하지만 같은 방식으로 동작
+func TestBatchDatagramEchoServerUnixgram(t *testing.T) {
+	dir, err := os.MkdirTemp("", "echo_batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testBatchEcho(t, "unixgram", filepath.Join(dir, "s.sock"), filepath.Join(dir, "c.sock"))
+}
@@ -0,0 +1,117 @@
+package echo
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/huGgW/network-study-with-go/ch04/sockopt"
+)
+
+func echoOnce(tb testing.TB, addr string, msg, buf []byte) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer conn.Close()
+
+	_, err = conn.Write(msg)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	_, err = io.ReadFull(conn, buf)
+	if err != nil {
+		tb.Fatal(err)
+	}
+}
+
+func TestShardedEchoServer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, err := shardedStreamingEchoServer(ctx, "tcp", "127.0.0.1:", 4, sockopt.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := []byte("ping")
+	buf := make([]byte, len(msg))
+	const conns = 32
+	for i := 0; i < conns; i++ {
+		echoOnce(t, s.Addr().String(), msg, buf)
+		if !bytes.Equal(msg, buf) {
+			t.Fatalf("expected %q; actual %q", msg, buf)
+		}
+	}
+
+	var total uint64
+	used := 0
+	for _, c := range s.Counts() {
+		total += c
+		if c > 0 {
+			used++
+		}
+	}
+	if total != conns {
+		t.Errorf("expected %d connections; actual %d (%v)", conns, total, s.Counts())
+	}
+	if used < 2 {
+		t.Errorf("expected connections on multiple shards; actual %v", s.Counts())
+	}
+
+	// context를 취소하면 모든 shard가 닫힘
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, err = net.Dial("tcp", s.Addr().String())
+		if err != nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err == nil {
+		t.Error("expected dial to fail after cancel")
+	}
+}
+
+// 매 연결마다 handshake, echo, close를 반복하여 accept 경로의 처리량을 비교
+func benchmarkEcho(b *testing.B, addr string) {
+	msg := []byte("ping")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		buf := make([]byte, len(msg))
+		for pb.Next() {
+			echoOnce(b, addr, msg, buf)
+		}
+	})
+}
+
+func BenchmarkStreamingEcho(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, err := streamingEchoServer(ctx, "tcp", "127.0.0.1:")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	benchmarkEcho(b, addr.String())
+}
+
+func BenchmarkShardedStreamingEcho(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, err := shardedStreamingEchoServer(ctx, "tcp", "127.0.0.1:", max(runtime.GOMAXPROCS(0), 2), sockopt.Options{})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	benchmarkEcho(b, s.Addr().String())
+}
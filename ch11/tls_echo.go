@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"net"
 	"time"
+
+	"github.com/huGgW/network-study-with-go/ch04/sockopt"
 )
 
 type Server struct {
@@ -15,6 +17,14 @@ type Server struct {
 	addr      string
 	maxIdle   time.Duration
 	tlsConfig *tls.Config
+
+	// ListenAndServeTLS의 listen 소켓 옵션
+	SocketOptions sockopt.Options
+
+	// 1보다 크면 SO_REUSEPORT로 같은 주소에 Shards개의 listener를 열고
+	// listener마다 accept 루프를 실행하여 커널이 연결을 분산하도록 함
+	Shards int
+	shards *sockopt.Shards
 }
 
 func NewTLSServer(
@@ -38,27 +48,59 @@ func (s *Server) Ready() {
 	}
 }
 
+// shard별로 받은 연결 수. Shards를 사용하지 않으면 nil
+// Ready 이후에 호출해야 한다.
+func (s *Server) ShardCounts() []uint64 {
+	if s.shards == nil {
+		return nil
+	}
+	return s.shards.Counts()
+}
+
 func (s *Server) ListenAndServeTLS(certFn, keyFn string) error {
 	if s.addr == "" {
 		s.addr = "localhost:443"
 	}
 
-	l, err := net.Listen("tcp", s.addr)
-	if err != nil {
-		return fmt.Errorf("binding to tcp %s: %w", s.addr, err)
+	ctx := s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var listeners []net.Listener
+	if s.Shards > 1 {
+		shards, err := s.SocketOptions.ListenShards(ctx, "tcp", s.addr, s.Shards)
+		if err != nil {
+			return fmt.Errorf("binding to tcp %s: %w", s.addr, err)
+		}
+		s.shards = shards
+		listeners = shards.Listeners
+	} else {
+		l, err := s.SocketOptions.Listen(ctx, "tcp", s.addr)
+		if err != nil {
+			return fmt.Errorf("binding to tcp %s: %w", s.addr, err)
+		}
+		listeners = []net.Listener{l}
 	}
 
 	if s.ctx != nil {
 		go func() {
 			<-s.ctx.Done()
-			_ = l.Close()
+			for _, l := range listeners {
+				_ = l.Close()
+			}
 		}()
 	}
 
-	return s.ServeTLS(l, certFn, keyFn)
+	return s.serveTLS(listeners, certFn, keyFn)
 }
 
 func (s Server) ServeTLS(l net.Listener, certFn, keyFn string) error {
+	return s.serveTLS([]net.Listener{l}, certFn, keyFn)
+}
+
+// 모든 listener에서 연결을 받아 echo. 모든 accept 루프가 끝나면 첫 에러 반환
+func (s Server) serveTLS(listeners []net.Listener, certFn, keyFn string) error {
 	if s.tlsConfig == nil {
 		s.tlsConfig = &tls.Config{
 			CurvePreferences:         []tls.CurveID{tls.CurveP256},
@@ -78,11 +120,32 @@ func (s Server) ServeTLS(l net.Listener, certFn, keyFn string) error {
 	}
 
     // tls.NewListener는 listener를 받아 TLS를 인지하도록 하는 연결 객체를 반환
-    tlsListener := tls.NewListener(l, s.tlsConfig)
+	tlsListeners := make([]net.Listener, len(listeners))
+	for i, l := range listeners {
+		tlsListeners[i] = tls.NewListener(l, s.tlsConfig)
+	}
     if s.ready != nil {
         close(s.ready)
     }
 
+	if len(tlsListeners) == 1 {
+		return s.serve(tlsListeners[0])
+	}
+
+	errs := make(chan error, len(tlsListeners))
+	for _, l := range tlsListeners {
+		go func(l net.Listener) { errs <- s.serve(l) }(l)
+	}
+	var err error
+	for range tlsListeners {
+		if sErr := <-errs; err == nil {
+			err = sErr
+		}
+	}
+	return err
+}
+
+func (s Server) serve(tlsListener net.Listener) error {
     for {
         conn, err := tlsListener.Accept()
         if err != nil {
@@ -0,0 +1,135 @@
+package ch11
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// 비어있는 localhost 포트
+func freeAddr(tb testing.TB) string {
+	tb.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer l.Close()
+
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	return net.JoinHostPort("localhost", port)
+}
+
+// shards개의 listener로 실행한 서버의 주소와 client 설정 반환
+func startShardedServer(tb testing.TB, ctx context.Context, shards int) (*Server, string, *tls.Config) {
+	tb.Helper()
+
+	addr := freeAddr(tb)
+	server := NewTLSServer(ctx, addr, 0, nil)
+	server.Shards = shards
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		err := server.ListenAndServeTLS("serverCert.pem", "serverKey.pem")
+		if err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
+			tb.Error(err)
+		}
+	}()
+	tb.Cleanup(func() { <-done })
+	server.Ready()
+
+	certPool, err := caCertPool("serverCert.pem")
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return server, addr, &tls.Config{
+		CurvePreferences: []tls.CurveID{tls.CurveP256},
+		MinVersion:       tls.VersionTLS12,
+		RootCAs:          certPool,
+	}
+}
+
+func echoTLS(tb testing.TB, addr string, config *tls.Config, msg, buf []byte) {
+	conn, err := tls.Dial("tcp", addr, config)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer conn.Close()
+
+	_, err = conn.Write(msg)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	_, err = io.ReadFull(conn, buf)
+	if err != nil {
+		tb.Fatal(err)
+	}
+}
+
+func TestShardedEchoServerTLS(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	server, addr, config := startShardedServer(t, ctx, 4)
+	t.Cleanup(cancel) // 서버 종료를 기다리기 전에 취소되도록 마지막에 등록
+
+	msg := []byte("hello")
+	buf := make([]byte, len(msg))
+	const conns = 32
+	for i := 0; i < conns; i++ {
+		echoTLS(t, addr, config, msg, buf)
+		if !bytes.Equal(msg, buf) {
+			t.Fatalf("expected %q; actual %q", msg, buf)
+		}
+	}
+
+	counts := server.ShardCounts()
+	if len(counts) != 4 {
+		t.Fatalf("expected 4 shards; actual %v", counts)
+	}
+	var total uint64
+	used := 0
+	for _, c := range counts {
+		total += c
+		if c > 0 {
+			used++
+		}
+	}
+	if total != conns {
+		t.Errorf("expected %d connections; actual %d (%v)", conns, total, counts)
+	}
+	if used < 2 {
+		t.Errorf("expected connections on multiple shards; actual %v", counts)
+	}
+}
+
+func benchmarkEchoTLS(b *testing.B, shards int) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	_, addr, config := startShardedServer(b, ctx, shards)
+	b.Cleanup(cancel)
+
+	msg := []byte("hello")
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		buf := make([]byte, len(msg))
+		for pb.Next() {
+			echoTLS(b, addr, config, msg, buf)
+		}
+	})
+}
+
+func BenchmarkEchoServerTLS(b *testing.B) {
+	benchmarkEchoTLS(b, 1)
+}
+
+func BenchmarkShardedEchoServerTLS(b *testing.B) {
+	benchmarkEchoTLS(b, max(runtime.GOMAXPROCS(0), 2))
+}
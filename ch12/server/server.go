@@ -1,22 +1,25 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"fmt"
 	"log"
-	"net"
 
+	"github.com/huGgW/network-study-with-go/ch04/sockopt"
 	"github.com/huGgW/network-study-with-go/ch12/housework/v1"
 	"google.golang.org/grpc"
 )
 
 var addr, certFn, keyFn string
+var sockOpts sockopt.Options
 
 func init() {
 	flag.StringVar(&addr, "address", "localhost:34443", "listen address")
 	flag.StringVar(&certFn, "cert", "cert.pem", "certificate file")
 	flag.StringVar(&keyFn, "key", "key.pem", "private key file")
+	sockOpts.RegisterFlags(flag.CommandLine)
 }
 
 func main() {
@@ -31,7 +34,7 @@ func main() {
 		log.Fatal(err)
 	}
 
-	listener, err := net.Listen("tcp", addr)
+	listener, err := sockOpts.Listen(context.Background(), "tcp", addr)
 	if err != nil {
 		log.Fatal(err)
 	}